@@ -0,0 +1,176 @@
+// Command mono runs the API and web servers, alone or together in one
+// process, and migrates the database.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/micahco/mono/internal/api"
+	"github.com/micahco/mono/internal/config"
+	"github.com/micahco/mono/internal/janitor"
+	"github.com/micahco/mono/internal/outbox"
+	"github.com/micahco/mono/internal/server"
+	"github.com/micahco/mono/internal/web"
+)
+
+const usage = `Usage: mono <command> [flags]
+
+Commands:
+  api      Run the API server
+  web      Run the web server
+  all      Run the API and web servers in one process
+  migrate  Apply the database migrations
+
+Run "mono <command> -h" for the flags of a command. Flags can also be set
+with the environment variables in their usage or in a -config file.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+
+	var err error
+	switch name {
+	case "api":
+		err = serve(name, args, true, false)
+	case "web":
+		err = serve(name, args, false, true)
+	case "all":
+		err = serve(name, args, true, true)
+	case "migrate":
+		err = migrate(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "mono: unknown command %q\n\n%s", name, usage)
+		os.Exit(2)
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mono %s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+// Run the API server, the web server or both with the shared services and
+// background workers
+func serve(name string, args []string, serveAPI, serveWeb bool) error {
+	var cfg config.Config
+
+	fs := flag.NewFlagSet("mono "+name, flag.ContinueOnError)
+	src := config.NewSource(fs)
+	cfg.Register(src)
+	if serveAPI {
+		cfg.API.Register(src)
+	}
+	if serveWeb {
+		cfg.Web.Register(src)
+	}
+
+	err := src.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{cfg.Validate()}
+	if serveAPI {
+		errs = append(errs, cfg.API.Validate())
+	}
+	if serveWeb {
+		errs = append(errs, cfg.Web.Validate())
+	}
+	if serveAPI && serveWeb && cfg.API.Port == cfg.Web.Port {
+		errs = append(errs, errors.New("api-port and web-port must differ"))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	logger := server.NewLogger(cfg.Dev)
+
+	svc, err := server.NewServices(&cfg, logger)
+	if err != nil {
+		return err
+	}
+	defer svc.Close()
+
+	rt := server.NewRuntime(logger)
+
+	// Purge expired tokens until shutdown
+	if cfg.Janitor > 0 {
+		rt.Go(janitor.New(svc.DB.DB, logger, cfg.Janitor).Run)
+	}
+
+	// Deliver the emails that either server queued until shutdown. The
+	// messages already have a sender, so the outbox mailer needs none.
+	rt.Go(outbox.New(svc.DB.DB, svc.Mailer(""), logger).Run)
+
+	if serveAPI {
+		api.Register(rt, &cfg, svc)
+	}
+	if serveWeb {
+		err := web.Register(rt, &cfg, svc)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rt.Run(context.Background())
+}
+
+// Apply the pending migrations of the database, after rolling back every
+// migration with -drop
+func migrate(args []string) error {
+	var cfg config.DB
+	var drop bool
+
+	fs := flag.NewFlagSet("mono migrate", flag.ContinueOnError)
+	src := config.NewSource(fs)
+	cfg.Register(src)
+	fs.BoolVar(&drop, "drop", false, "Roll back every migration first, which drops the entire schema")
+
+	err := src.Parse(args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	db, err := server.OpenDB(cfg.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := db.Migrator()
+	if err != nil {
+		return err
+	}
+
+	if drop {
+		err := m.Reset()
+		if err != nil {
+			return fmt.Errorf("drop: %w", err)
+		}
+	}
+
+	err = m.Up()
+	if err != nil {
+		return fmt.Errorf("up: %w", err)
+	}
+
+	return nil
+}
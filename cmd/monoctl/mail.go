@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/micahco/mono/ui/emails"
+)
+
+type mailOutput struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+}
+
+func (c *ctl) mailTest(ctx context.Context, args []string) error {
+	var to string
+	fs := c.flags("mail test")
+	fs.StringVar(&to, "to", "", "Recipient of the test email")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	err := validation.Errors{
+		"to": validation.Validate(to, validation.Required, is.Email),
+	}.Filter()
+	if err != nil {
+		return err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+
+	out := mailOutput{To: to, Subject: "Test Email"}
+	err = c.mailer.Send(ctx, out.To, out.Subject, emails.Test(host))
+	if err != nil {
+		return err
+	}
+
+	return c.print(out, []string{"TO", "SUBJECT"}, [][]string{{out.To, out.Subject}})
+}
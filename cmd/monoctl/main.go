@@ -0,0 +1,203 @@
+// Command monoctl administers users, sessions, tokens and SAML identity
+// providers in the database of DATABASE_URL, for the operators of the API
+// and web servers.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/mail"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/micahco/mono/internal/config"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/micahco/mono/internal/server"
+)
+
+// Returned after the usage of a command was printed
+var errUsage = errors.New("usage")
+
+type settings struct {
+	json bool
+	db   config.DB
+	mail config.Mail
+	// Sender of the test email
+	sender string
+}
+
+type ctl struct {
+	db     *data.DB
+	mailer *mailer.Mailer
+	json   bool
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	// Whether the command uses the database or the mailer, which are
+	// only set up for the commands that do
+	db, mail bool
+	run      func(c *ctl, ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{name: "users create", args: "-email EMAIL [-password PASSWORD] [-name NAME] [-locale LOCALE]", summary: "Create a user, with a generated password if none is given", db: true, run: (*ctl).usersCreate},
+	{name: "users reset-password", args: "-email EMAIL [-password PASSWORD]", summary: "Set the password of a user, unlock it and sign out its sessions", db: true, run: (*ctl).usersResetPassword},
+	{name: "users disable", args: "-email EMAIL", summary: "Deactivate a user and sign out its sessions", db: true, run: (*ctl).usersDisable},
+	{name: "sessions list", args: "-email EMAIL", summary: "List the sessions of a user", db: true, run: (*ctl).sessionsList},
+	{name: "sessions revoke", args: "-id ID | -email EMAIL", summary: "Sign out a session, or every session of a user", db: true, run: (*ctl).sessionsRevoke},
+	{name: "tokens purge", summary: "Delete expired verification and authentication tokens", db: true, run: (*ctl).tokensPurge},
+	{name: "saml add", args: "-slug SLUG -name NAME -domain DOMAIN -metadata FILE [-allow-idp-initiated]", summary: "Add the SAML identity provider of an organization", db: true, run: (*ctl).samlAdd},
+	{name: "mail test", args: "-to EMAIL", summary: "Send a test email with the mail settings", mail: true, run: (*ctl).mailTest},
+	{name: "stats", summary: "Print counts of users and tokens", db: true, run: (*ctl).stats},
+}
+
+func main() {
+	var cfg settings
+
+	src := config.NewSource(flag.CommandLine)
+	flag.BoolVar(&cfg.json, "json", false, "Print JSON instead of tables")
+	cfg.db.Register(src)
+	cfg.mail.Register(src)
+	src.String(&cfg.sender, "smtp-sender", "API_SMTP_SENDER", "", "Sender of the test email")
+
+	flag.Usage = usage
+	err := src.Parse(os.Args[1:])
+	if err == nil {
+		err = run(cfg, flag.Args())
+	}
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "monoctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(cfg settings, args []string) error {
+	cmd, args, ok := lookup(args)
+	if !ok {
+		usage()
+		return errUsage
+	}
+
+	c := &ctl{
+		json:   cfg.json,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	if cmd.db {
+		err := cfg.db.Validate()
+		if err != nil {
+			return err
+		}
+
+		db, err := server.OpenDB(cfg.db.DSN)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		c.db = db.DB
+	}
+
+	if cmd.mail {
+		m, err := newMailer(cfg)
+		if err != nil {
+			return err
+		}
+
+		c.mailer = m
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return cmd.run(c, ctx, args)
+}
+
+// Command named by the leading arguments, and the arguments after its name
+func lookup(args []string) (*command, []string, bool) {
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == commands[i].name {
+			return &commands[i], args[len(words):], true
+		}
+	}
+
+	return nil, nil, false
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: monoctl [flags] <command> [command flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %s %s\n    \t%s\n", cmd.name, cmd.args, cmd.summary)
+	}
+	fmt.Fprintf(out, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// Flags of the command, which print its usage to stderr
+func (c *ctl) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("monoctl "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+
+	return fs
+}
+
+// Parse the flags of the command, which takes no other arguments
+func parse(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected argument: %s\n", fs.Arg(0))
+		fs.Usage()
+		return errUsage
+	}
+
+	return nil
+}
+
+// Mailer with the transport and DKIM key of the servers. Only transports
+// that deliver outside of the process can be tested.
+func newMailer(cfg settings) (*mailer.Mailer, error) {
+	if cfg.mail.Transport == "memory" || cfg.mail.Transport == "sink" {
+		return nil, fmt.Errorf("unsupported mail transport: %s", cfg.mail.Transport)
+	}
+
+	err := cfg.mail.Validate(false)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := server.NewMailTransport(cfg.mail, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dkim, err := server.LoadDKIM(cfg.mail)
+	if err != nil {
+		return nil, err
+	}
+
+	sender := &mail.Address{
+		Name:    "Do Not Reply",
+		Address: cfg.sender,
+	}
+
+	return mailer.New(transport, sender, dkim), nil
+}
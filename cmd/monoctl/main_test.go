@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"math/big"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/data/memory"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCtl() (*ctl, *mailer.Memory) {
+	sent := mailer.NewMemory()
+
+	return &ctl{
+		db:     memory.NewMemoryDB().DB,
+		mailer: mailer.New(sent, &mail.Address{Address: "no-reply@example.com"}, nil),
+		json:   true,
+		stderr: io.Discard,
+	}, sent
+}
+
+// Run the command and decode its JSON output into dst
+func (c *ctl) exec(t *testing.T, dst any, args ...string) error {
+	var stdout bytes.Buffer
+	c.stdout = &stdout
+
+	cmd, args, ok := lookup(args)
+	require.True(t, ok)
+
+	err := cmd.run(c, context.Background(), args)
+	if err == nil && dst != nil {
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), dst))
+	}
+
+	return err
+}
+
+func TestLookup(t *testing.T) {
+	cmd, args, ok := lookup([]string{"users", "create", "-email", "a@example.com"})
+	require.True(t, ok)
+	assert.Equal(t, "users create", cmd.name)
+	assert.Equal(t, []string{"-email", "a@example.com"}, args)
+
+	cmd, args, ok = lookup([]string{"stats"})
+	require.True(t, ok)
+	assert.Equal(t, "stats", cmd.name)
+	assert.Empty(t, args)
+
+	_, _, ok = lookup([]string{"users"})
+	assert.False(t, ok)
+
+	_, _, ok = lookup(nil)
+	assert.False(t, ok)
+}
+
+func TestUsers(t *testing.T) {
+	c, _ := newTestCtl()
+	ctx := context.Background()
+
+	var created userOutput
+	err := c.exec(t, &created, "users", "create", "-email", "alice@example.com", "-name", "Alice", "-locale", "de")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", created.Email)
+	assert.Equal(t, "Alice", created.DisplayName)
+	assert.Equal(t, "de", created.Locale)
+	assert.True(t, created.Active)
+
+	// The generated password signs in
+	user, err := c.db.Users.GetWithEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	ok, err := crypto.ComparePasswordAndHash(created.Password, user.PasswordHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	err = c.exec(t, nil, "users", "create", "-email", "alice@example.com")
+	assert.ErrorIs(t, err, data.ErrDuplicateEmail)
+
+	err = c.exec(t, nil, "users", "create", "-email", "bob", "-password", "short")
+	assert.EqualError(t, err, "email: must be a valid email address; password: the length must be between 8 and 72.")
+
+	err = c.exec(t, nil, "users", "create", "-unknown")
+	assert.ErrorIs(t, err, errUsage)
+
+	t.Run("ResetPassword", func(t *testing.T) {
+		user.Locked = true
+		require.NoError(t, c.db.Users.Update(ctx, user))
+		require.NoError(t, c.db.AuthenticationTokens.New(ctx, []byte("session"), time.Now().Add(time.Hour), user.ID))
+
+		var reset userOutput
+		err := c.exec(t, &reset, "users", "reset-password", "-email", "alice@example.com", "-password", "new password")
+		require.NoError(t, err)
+		assert.False(t, reset.Locked)
+		assert.Empty(t, reset.Password)
+
+		user, err = c.db.Users.GetWithEmail(ctx, "alice@example.com")
+		require.NoError(t, err)
+		ok, err := crypto.ComparePasswordAndHash("new password", user.PasswordHash)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		_, err = c.db.AuthenticationTokens.Get(ctx, []byte("session"))
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		err = c.exec(t, nil, "users", "reset-password", "-email", "unknown@example.com")
+		assert.EqualError(t, err, "no user with email unknown@example.com")
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		require.NoError(t, c.db.AuthenticationTokens.New(ctx, []byte("session"), time.Now().Add(time.Hour), user.ID))
+
+		var disabled userOutput
+		err := c.exec(t, &disabled, "users", "disable", "-email", "alice@example.com")
+		require.NoError(t, err)
+		assert.False(t, disabled.Active)
+
+		_, err = c.db.AuthenticationTokens.Get(ctx, []byte("session"))
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+}
+
+func TestSessions(t *testing.T) {
+	c, _ := newTestCtl()
+	ctx := context.Background()
+
+	user, err := c.db.Users.New(ctx, "alice@example.com", []byte("hash"))
+	require.NoError(t, err)
+	for _, hash := range []string{"first", "second", "third"} {
+		require.NoError(t, c.db.AuthenticationTokens.New(ctx, []byte(hash), time.Now().Add(time.Hour), user.ID))
+	}
+
+	var sessions []sessionOutput
+	err = c.exec(t, &sessions, "sessions", "list", "-email", "alice@example.com")
+	require.NoError(t, err)
+	require.Len(t, sessions, 3)
+
+	var revoked revokeOutput
+	err = c.exec(t, &revoked, "sessions", "revoke", "-id", sessions[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked.Revoked)
+
+	err = c.exec(t, nil, "sessions", "revoke", "-id", sessions[0].ID)
+	assert.EqualError(t, err, "no session with id "+sessions[0].ID)
+
+	err = c.exec(t, nil, "sessions", "revoke")
+	assert.Error(t, err)
+
+	err = c.exec(t, &revoked, "sessions", "revoke", "-email", "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 2, revoked.Revoked)
+
+	err = c.exec(t, &sessions, "sessions", "list", "-email", "alice@example.com")
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestTokensPurge(t *testing.T) {
+	c, _ := newTestCtl()
+	ctx := context.Background()
+
+	user, err := c.db.Users.New(ctx, "alice@example.com", []byte("hash"))
+	require.NoError(t, err)
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, c.db.AuthenticationTokens.New(ctx, []byte("expired"), expired, user.ID))
+	require.NoError(t, c.db.AuthenticationTokens.New(ctx, []byte("valid"), time.Now().Add(time.Hour), user.ID))
+	require.NoError(t, c.db.VerificationTokens.New(ctx, []byte("expired"), expired, data.ScopeRegistration, "bob@example.com"))
+
+	var purged purgeOutput
+	err = c.exec(t, &purged, "tokens", "purge")
+	require.NoError(t, err)
+	assert.Equal(t, purgeOutput{VerificationTokens: 1, AuthenticationTokens: 1}, purged)
+
+	_, err = c.db.AuthenticationTokens.Get(ctx, []byte("valid"))
+	assert.NoError(t, err)
+}
+
+func TestMailTest(t *testing.T) {
+	c, sent := newTestCtl()
+
+	var out mailOutput
+	err := c.exec(t, &out, "mail", "test", "-to", "alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", out.To)
+
+	msgs := sent.MessagesTo("alice@example.com")
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Test Email", msgs[0].Subject)
+	assert.Contains(t, msgs[0].Text(), "monoctl")
+}
+
+func TestStats(t *testing.T) {
+	c, _ := newTestCtl()
+	ctx := context.Background()
+
+	_, err := c.db.Users.New(ctx, "alice@example.com", []byte("hash"))
+	require.NoError(t, err)
+
+	var stats data.Stats
+	err = c.exec(t, &stats, "stats")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Users)
+	assert.Equal(t, 1, stats.ActiveUsers)
+
+	// Table output
+	c.json = false
+	var stdout bytes.Buffer
+	c.stdout = &stdout
+	require.NoError(t, c.stats(ctx, nil))
+	assert.Contains(t, stdout.String(), "STATISTIC")
+	assert.Regexp(t, `active users\s+1`, stdout.String())
+}
+
+// Metadata of an identity provider with a self-signed certificate
+func writeIDPMetadata(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	idp := &saml.IdentityProvider{
+		Key:         key,
+		Certificate: cert,
+		MetadataURL: url.URL{Scheme: "https", Host: "idp.example.com", Path: "/metadata"},
+		SSOURL:      url.URL{Scheme: "https", Host: "idp.example.com", Path: "/sso"},
+	}
+	metadata, err := xml.Marshal(idp.Metadata())
+	require.NoError(t, err)
+
+	name := filepath.Join(t.TempDir(), "metadata.xml")
+	require.NoError(t, os.WriteFile(name, metadata, 0o600))
+
+	return name
+}
+
+func TestSAMLAdd(t *testing.T) {
+	c, _ := newTestCtl()
+	ctx := context.Background()
+	metadata := writeIDPMetadata(t)
+
+	var added samlProviderOutput
+	err := c.exec(t, &added, "saml", "add", "-slug", "acme", "-name", "Acme", "-domain", "acme.example", "-metadata", metadata, "-allow-idp-initiated")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", added.Slug)
+	assert.True(t, added.AllowIDPInitiated)
+	assert.False(t, added.LinkByEmail)
+	assert.Equal(t, "/saml/acme/acs", added.ACSPath)
+
+	p, err := c.db.SAML.GetProviderWithDomain(ctx, "ACME.example")
+	require.NoError(t, err)
+	assert.Equal(t, added.ID, p.ID)
+	assert.Equal(t, "Acme", p.Name)
+	assert.Contains(t, p.Metadata, "https://idp.example.com/sso")
+
+	err = c.exec(t, nil, "saml", "add", "-slug", "acme", "-name", "Other", "-domain", "other.example", "-metadata", metadata)
+	assert.EqualError(t, err, "provider acme already exists")
+
+	err = c.exec(t, nil, "saml", "add", "-slug", "other", "-name", "Other", "-domain", "acme.example", "-metadata", metadata)
+	assert.EqualError(t, err, "domain acme.example already has provider acme")
+
+	err = c.exec(t, nil, "saml", "add", "-slug", "Other Org", "-name", "Other", "-domain", "other", "-metadata", metadata)
+	assert.EqualError(t, err, "domain: must be a valid domain; slug: must be lowercase letters, digits and dashes.")
+
+	err = c.exec(t, nil, "saml", "add", "-slug", "other", "-name", "Other", "-domain", "other.example")
+	assert.EqualError(t, err, "metadata: cannot be blank.")
+
+	err = c.exec(t, &added, "saml", "add", "-slug", "partner", "-name", "Partner", "-domain", "partner.example", "-metadata", metadata, "-link-by-email")
+	require.NoError(t, err)
+	assert.True(t, added.LinkByEmail)
+
+	p, err = c.db.SAML.GetProvider(ctx, "partner")
+	require.NoError(t, err)
+	assert.True(t, p.LinkByEmail)
+	assert.False(t, p.AllowIDPInitiated)
+
+	invalid := filepath.Join(t.TempDir(), "invalid.xml")
+	require.NoError(t, os.WriteFile(invalid, []byte("<html></html>"), 0o600))
+	err = c.exec(t, nil, "saml", "add", "-slug", "other", "-name", "Other", "-domain", "other.example", "-metadata", invalid)
+	assert.ErrorContains(t, err, "invalid metadata")
+
+	_, err = c.db.SAML.GetProvider(ctx, "other")
+	assert.ErrorIs(t, err, data.ErrRecordNotFound)
+}
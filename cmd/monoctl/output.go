@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type userOutput struct {
+	ID          uuid.UUID `json:"id"`
+	Email       string    `json:"email"`
+	DisplayName string    `json:"display_name"`
+	Locale      string    `json:"locale"`
+	Active      bool      `json:"active"`
+	Locked      bool      `json:"locked"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Generated password, only printed when none was given
+	Password string `json:"password,omitempty"`
+}
+
+func newUserOutput(u *data.User, password string) userOutput {
+	return userOutput{
+		ID:          u.ID,
+		Email:       u.Email,
+		DisplayName: u.DisplayName,
+		Locale:      u.Locale,
+		Active:      u.Active,
+		Locked:      u.Locked,
+		CreatedAt:   u.CreatedAt,
+		Password:    password,
+	}
+}
+
+func (c *ctl) printUser(u userOutput) error {
+	header := []string{"ID", "EMAIL", "NAME", "LOCALE", "ACTIVE", "LOCKED", "CREATED"}
+	row := []string{u.ID.String(), u.Email, u.DisplayName, u.Locale, yesNo(u.Active), yesNo(u.Locked), formatTime(u.CreatedAt)}
+	if u.Password != "" {
+		header = append(header, "PASSWORD")
+		row = append(row, u.Password)
+	}
+
+	return c.print(u, header, [][]string{row})
+}
+
+// Print v as JSON with -json, and otherwise the rows as a table
+func (c *ctl) print(v any, header []string, rows [][]string) error {
+	if c.json {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.Local().Format(time.DateTime)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
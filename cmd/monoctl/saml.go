@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/crewjam/saml/samlsp"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+// Slugs are a path segment of the provider's URLs
+var samlSlug = validation.Match(regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)).Error("must be lowercase letters, digits and dashes")
+
+type samlProviderOutput struct {
+	ID                uuid.UUID `json:"id"`
+	Slug              string    `json:"slug"`
+	Name              string    `json:"name"`
+	Domain            string    `json:"domain"`
+	AllowIDPInitiated bool      `json:"allow_idp_initiated"`
+	LinkByEmail       bool      `json:"link_by_email"`
+	CreatedAt         time.Time `json:"created_at"`
+	// Paths of the service provider, relative to the web server's URL,
+	// that the identity provider is configured with
+	MetadataPath string `json:"metadata_path"`
+	ACSPath      string `json:"acs_path"`
+}
+
+func (c *ctl) samlAdd(ctx context.Context, args []string) error {
+	var slug, name, domain, metadataFile string
+	var allowIDPInitiated, linkByEmail bool
+	fs := c.flags("saml add")
+	fs.StringVar(&slug, "slug", "", "Identifier of the provider in its URLs")
+	fs.StringVar(&name, "name", "", "Name of the organization")
+	fs.StringVar(&domain, "domain", "", "Email domain of the organization's users")
+	fs.StringVar(&metadataFile, "metadata", "", "File of the identity provider's metadata XML")
+	fs.BoolVar(&allowIDPInitiated, "allow-idp-initiated", false, "Accept logins started by the identity provider")
+	fs.BoolVar(&linkByEmail, "link-by-email", false, "Link existing users with the same email to the provider")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	err := validation.Errors{
+		"slug":     validation.Validate(slug, validation.Required, samlSlug),
+		"name":     validation.Validate(name, validation.Required),
+		"domain":   validation.Validate(domain, validation.Required, is.Domain),
+		"metadata": validation.Validate(metadataFile, validation.Required),
+	}.Filter()
+	if err != nil {
+		return err
+	}
+
+	metadata, err := os.ReadFile(metadataFile)
+	if err != nil {
+		return err
+	}
+
+	_, err = samlsp.ParseMetadata(metadata)
+	if err != nil {
+		return fmt.Errorf("invalid metadata: %w", err)
+	}
+
+	p := &data.SAMLProvider{
+		Slug:              slug,
+		Name:              name,
+		Domain:            domain,
+		Metadata:          string(metadata),
+		AllowIDPInitiated: allowIDPInitiated,
+		LinkByEmail:       linkByEmail,
+	}
+
+	err = c.db.WithTx(ctx, func(tx *data.DB) error {
+		_, err := tx.SAML.GetProvider(ctx, slug)
+		switch {
+		case err == nil:
+			return fmt.Errorf("provider %s already exists", slug)
+		case !errors.Is(err, data.ErrRecordNotFound):
+			return err
+		}
+
+		other, err := tx.SAML.GetProviderWithDomain(ctx, domain)
+		switch {
+		case err == nil:
+			return fmt.Errorf("domain %s already has provider %s", domain, other.Slug)
+		case !errors.Is(err, data.ErrRecordNotFound):
+			return err
+		}
+
+		return tx.SAML.NewProvider(ctx, p)
+	})
+	if err != nil {
+		return err
+	}
+
+	out := samlProviderOutput{
+		ID:                p.ID,
+		Slug:              p.Slug,
+		Name:              p.Name,
+		Domain:            p.Domain,
+		AllowIDPInitiated: p.AllowIDPInitiated,
+		LinkByEmail:       p.LinkByEmail,
+		CreatedAt:         p.CreatedAt,
+		MetadataPath:      "/saml/" + p.Slug + "/metadata",
+		ACSPath:           "/saml/" + p.Slug + "/acs",
+	}
+
+	header := []string{"ID", "SLUG", "NAME", "DOMAIN", "IDP INITIATED", "LINK BY EMAIL", "CREATED", "METADATA", "ACS"}
+	row := []string{out.ID.String(), out.Slug, out.Name, out.Domain, yesNo(out.AllowIDPInitiated), yesNo(out.LinkByEmail), formatTime(out.CreatedAt), out.MetadataPath, out.ACSPath}
+
+	return c.print(out, header, [][]string{row})
+}
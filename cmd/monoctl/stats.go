@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strconv"
+)
+
+func (c *ctl) stats(ctx context.Context, args []string) error {
+	fs := c.flags("stats")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	stats, err := c.db.Stats.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{
+		{"users", strconv.Itoa(stats.Users)},
+		{"active users", strconv.Itoa(stats.ActiveUsers)},
+		{"locked users", strconv.Itoa(stats.LockedUsers)},
+		{"authentication tokens", strconv.Itoa(stats.AuthenticationTokens)},
+		{"expired authentication tokens", strconv.Itoa(stats.ExpiredAuthenticationTokens)},
+	}
+
+	scopes := make([]string, 0, len(stats.VerificationTokens))
+	for scope := range stats.VerificationTokens {
+		scopes = append(scopes, scope)
+	}
+	slices.Sort(scopes)
+	for _, scope := range scopes {
+		rows = append(rows, []string{scope + " verification tokens", strconv.Itoa(stats.VerificationTokens[scope])})
+	}
+	rows = append(rows, []string{"expired verification tokens", strconv.Itoa(stats.ExpiredVerificationTokens)})
+
+	return c.print(stats, []string{"STATISTIC", "COUNT"}, rows)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+)
+
+// Expired tokens deleted by each query
+const purgeBatchSize = 1000
+
+type sessionOutput struct {
+	// Encoded hash of the token, which is not a credential
+	ID              string    `json:"id"`
+	Expiry          time.Time `json:"expiry"`
+	AuthenticatedAt time.Time `json:"authenticated_at"`
+}
+
+type revokeOutput struct {
+	Revoked int `json:"revoked"`
+}
+
+type purgeOutput struct {
+	VerificationTokens   int `json:"verification_tokens"`
+	AuthenticationTokens int `json:"authentication_tokens"`
+}
+
+func (c *ctl) sessionsList(ctx context.Context, args []string) error {
+	var email string
+	fs := c.flags("sessions list")
+	fs.StringVar(&email, "email", "", "Email of the user")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	err := validation.Errors{
+		"email": validation.Validate(email, validation.Required),
+	}.Filter()
+	if err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	tokens, err := c.db.AuthenticationTokens.ListForUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	sessions := []sessionOutput{}
+	var rows [][]string
+	for _, at := range tokens {
+		s := sessionOutput{
+			ID:              crypto.EncodeTokenHash(at.Hash),
+			Expiry:          at.Expiry,
+			AuthenticatedAt: at.AuthenticatedAt,
+		}
+		sessions = append(sessions, s)
+		rows = append(rows, []string{s.ID, formatTime(s.AuthenticatedAt), formatTime(s.Expiry)})
+	}
+
+	return c.print(sessions, []string{"ID", "AUTHENTICATED", "EXPIRES"}, rows)
+}
+
+func (c *ctl) sessionsRevoke(ctx context.Context, args []string) error {
+	var id, email string
+	fs := c.flags("sessions revoke")
+	fs.StringVar(&id, "id", "", "ID of the session, as listed by sessions list")
+	fs.StringVar(&email, "email", "", "Email of the user to sign out of every session")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	var out revokeOutput
+	switch {
+	case id != "" && email == "":
+		hash, err := crypto.DecodeTokenHash(id)
+		if err != nil {
+			return fmt.Errorf("invalid session id: %s", id)
+		}
+
+		err = c.db.AuthenticationTokens.Delete(ctx, hash)
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return fmt.Errorf("no session with id %s", id)
+		}
+		if err != nil {
+			return err
+		}
+
+		out.Revoked = 1
+	case email != "" && id == "":
+		user, err := c.user(ctx, email)
+		if err != nil {
+			return err
+		}
+
+		tokens, err := c.db.AuthenticationTokens.ListForUser(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		err = c.db.AuthenticationTokens.Purge(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		out.Revoked = len(tokens)
+	default:
+		return errors.New("either id or email is required")
+	}
+
+	return c.print(out, []string{"REVOKED"}, [][]string{{strconv.Itoa(out.Revoked)}})
+}
+
+func (c *ctl) tokensPurge(ctx context.Context, args []string) error {
+	fs := c.flags("tokens purge")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	var out purgeOutput
+	var err error
+
+	out.VerificationTokens, err = deleteAll(ctx, c.db.VerificationTokens.DeleteExpired)
+	if err != nil {
+		return err
+	}
+
+	out.AuthenticationTokens, err = deleteAll(ctx, c.db.AuthenticationTokens.DeleteExpired)
+	if err != nil {
+		return err
+	}
+
+	return c.print(out, []string{"TOKENS", "DELETED"}, [][]string{
+		{"verification", strconv.Itoa(out.VerificationTokens)},
+		{"authentication", strconv.Itoa(out.AuthenticationTokens)},
+	})
+}
+
+// Delete expired rows one batch at a time until none are left
+func deleteAll(ctx context.Context, deleteExpired func(ctx context.Context, limit int) (int, error)) (int, error) {
+	var total int
+	for {
+		n, err := deleteExpired(ctx, purgeBatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		if n < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
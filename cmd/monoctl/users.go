@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/i18n"
+)
+
+var passwordLength = validation.Length(8, 72)
+
+func (c *ctl) usersCreate(ctx context.Context, args []string) error {
+	var email, password, name, locale string
+	fs := c.flags("users create")
+	fs.StringVar(&email, "email", "", "Email of the user")
+	fs.StringVar(&password, "password", "", "Password of the user (generated if empty)")
+	fs.StringVar(&name, "name", "", "Display name of the user")
+	fs.StringVar(&locale, "locale", i18n.DefaultLocale, "Language of the emails to the user")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	err := validation.Errors{
+		"email":    validation.Validate(email, validation.Required, is.Email),
+		"password": validation.Validate(password, passwordLength),
+		"locale":   validation.Validate(locale, validation.Required, validation.In(locales()...)),
+	}.Filter()
+	if err != nil {
+		return err
+	}
+
+	password, generated, err := passwordOrGenerate(password)
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := crypto.PasswordHash(password)
+	if err != nil {
+		return err
+	}
+
+	var user *data.User
+	err = c.db.WithTx(ctx, func(tx *data.DB) error {
+		// Registration links sent to the email are no longer needed
+		err := tx.VerificationTokens.Purge(ctx, email)
+		if err != nil {
+			return err
+		}
+
+		user, err = tx.Users.New(ctx, email, passwordHash)
+		if err != nil {
+			return err
+		}
+
+		user.DisplayName = name
+		user.Locale = locale
+		return tx.Users.Update(ctx, user)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printUser(newUserOutput(user, generated))
+}
+
+func (c *ctl) usersResetPassword(ctx context.Context, args []string) error {
+	var email, password string
+	fs := c.flags("users reset-password")
+	fs.StringVar(&email, "email", "", "Email of the user")
+	fs.StringVar(&password, "password", "", "New password of the user (generated if empty)")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	err := validation.Errors{
+		"email":    validation.Validate(email, validation.Required),
+		"password": validation.Validate(password, passwordLength),
+	}.Filter()
+	if err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	password, generated, err := passwordOrGenerate(password)
+	if err != nil {
+		return err
+	}
+
+	user.PasswordHash, err = crypto.PasswordHash(password)
+	if err != nil {
+		return err
+	}
+	user.Locked = false
+
+	err = c.db.WithTx(ctx, func(tx *data.DB) error {
+		err := tx.Users.Update(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		// Password reset links that were sent are no longer needed
+		err = tx.VerificationTokens.Purge(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+
+		return tx.AuthenticationTokens.Purge(ctx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printUser(newUserOutput(user, generated))
+}
+
+func (c *ctl) usersDisable(ctx context.Context, args []string) error {
+	var email string
+	fs := c.flags("users disable")
+	fs.StringVar(&email, "email", "", "Email of the user")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+
+	err := validation.Errors{
+		"email": validation.Validate(email, validation.Required),
+	}.Filter()
+	if err != nil {
+		return err
+	}
+
+	user, err := c.user(ctx, email)
+	if err != nil {
+		return err
+	}
+
+	user.Active = false
+
+	err = c.db.WithTx(ctx, func(tx *data.DB) error {
+		err := tx.Users.Update(ctx, user)
+		if err != nil {
+			return err
+		}
+
+		return tx.AuthenticationTokens.Purge(ctx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.printUser(newUserOutput(user, ""))
+}
+
+func (c *ctl) user(ctx context.Context, email string) (*data.User, error) {
+	user, err := c.db.Users.GetWithEmail(ctx, email)
+	if errors.Is(err, data.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no user with email %s", email)
+	}
+
+	return user, err
+}
+
+// The password, or a random one if it's empty, which is also returned as
+// generated so that it can be printed
+func passwordOrGenerate(password string) (string, string, error) {
+	if password != "" {
+		return password, "", nil
+	}
+
+	generated, err := crypto.GeneratePlaintextToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	return generated, generated, nil
+}
+
+func locales() []any {
+	var locales []any
+	for _, locale := range i18n.Locales() {
+		locales = append(locales, locale)
+	}
+
+	return locales
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+// Operator endpoints, authenticated by the admin bearer token
+
+// List emails that the outbox gave up on delivering
+func (app *application) adminOutboxDeadGet(w http.ResponseWriter, r *http.Request) error {
+	msgs, err := app.db.Outbox.GetDead(r.Context())
+	if err != nil {
+		return err
+	}
+
+	res := response{"messages": msgs}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
+
+// Queue a dead email for delivery again
+func (app *application) adminOutboxRetryPost(w http.ResponseWriter, r *http.Request) error {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		return app.writeProblem(w, r, notFoundProblem())
+	}
+
+	err = app.db.Outbox.Retry(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, notFoundProblem())
+		default:
+			return err
+		}
+	}
+
+	res := response{"message": "the email was queued for delivery"}
+
+	return app.writeJSON(w, res, http.StatusAccepted)
+}
+
+// Show the bounces recorded for an email address
+func (app *application) adminSuppressionGet(w http.ResponseWriter, r *http.Request) error {
+	s, err := app.db.Suppressions.Get(r.Context(), chi.URLParam(r, "email"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, notFoundProblem())
+		default:
+			return err
+		}
+	}
+
+	res := response{"suppression": s}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
+
+// Clear the bounces of an email address so that it receives mail again
+func (app *application) adminSuppressionDelete(w http.ResponseWriter, r *http.Request) error {
+	err := app.db.Suppressions.Delete(r.Context(), chi.URLParam(r, "email"))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, notFoundProblem())
+		default:
+			return err
+		}
+	}
+
+	res := response{"message": "the suppression was cleared"}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
@@ -0,0 +1,64 @@
+// Package api is the JSON API server of mono, at /v1.
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/a-h/templ"
+	"github.com/micahco/mono/internal/authn"
+	"github.com/micahco/mono/internal/bounce"
+	"github.com/micahco/mono/internal/config"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/geoip"
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/micahco/mono/internal/outbox"
+	"github.com/micahco/mono/internal/server"
+)
+
+type application struct {
+	config  config.Config
+	db      data.DB
+	logger  *slog.Logger
+	mailer  *mailer.Mailer
+	sink    *mailer.Sink
+	bounces *bounce.Processor
+	locator *geoip.Locator
+	authn   authn.Authenticator
+	baseURL *url.URL
+}
+
+// Serve the API with the runtime, and record the bounces that are
+// delivered to the maildir in the background
+func Register(rt *server.Runtime, cfg *config.Config, svc *server.Services) {
+	app := &application{
+		config:  *cfg,
+		db:      *svc.DB.DB,
+		logger:  svc.Logger,
+		mailer:  svc.Mailer(cfg.API.Sender),
+		sink:    svc.Sink,
+		bounces: bounce.New(svc.DB.DB, svc.Logger, cfg.API.Bounce.Dir, cfg.API.Bounce.Interval),
+		locator: svc.Locator,
+		authn:   svc.Authn,
+	}
+
+	rt.Serve("api", fmt.Sprintf(":%d", cfg.API.Port), app.routes())
+
+	if cfg.API.Bounce.Dir != "" {
+		rt.Go(app.bounces.Run)
+	}
+}
+
+// Queue an email in the outbox, translated into the locale. Pass the DB
+// of a transaction to send it only if the transaction commits.
+func (app *application) sendMail(ctx context.Context, db *data.DB, locale, recipient, subject string, component templ.Component) error {
+	msg, err := app.mailer.Render(i18n.WithLocale(ctx, locale), recipient, subject, component)
+	if err != nil {
+		return err
+	}
+
+	return outbox.Enqueue(ctx, db, msg)
+}
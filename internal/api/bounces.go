@@ -0,0 +1,37 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/micahco/mono/internal/bounce"
+)
+
+// Largest report accepted by the bounce webhook
+const maxBounceSize = 10 << 20
+
+// Bounce webhook for mail servers and providers, authenticated by the
+// bounce bearer token
+
+// Record the bounces of a raw delivery status notification or abuse
+// report
+func (app *application) bouncesPost(w http.ResponseWriter, r *http.Request) error {
+	body := http.MaxBytesReader(w, r.Body, maxBounceSize)
+
+	n, err := app.bounces.Process(r.Context(), body)
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesError):
+			return app.writeProblem(w, r, statusProblem(http.StatusRequestEntityTooLarge, ""))
+		case errors.Is(err, bounce.ErrNotReport):
+			return app.writeProblem(w, r, statusProblem(http.StatusUnprocessableEntity, "body must be a delivery status notification or abuse report"))
+		default:
+			return err
+		}
+	}
+
+	res := response{"bounces": n}
+
+	return app.writeJSON(w, res, http.StatusAccepted)
+}
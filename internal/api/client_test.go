@@ -0,0 +1,319 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/authn"
+	"github.com/micahco/mono/internal/config"
+	"github.com/micahco/mono/internal/data/memory"
+	"github.com/micahco/mono/internal/geoip"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/micahco/mono/internal/outbox"
+	"github.com/micahco/mono/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPassword = "pa55word1234"
+
+// API server of the real routes with an in-memory database and mailer
+type testServer struct {
+	*httptest.Server
+	app    *application
+	mail   *mailer.Memory
+	outbox *outbox.Worker
+}
+
+func newTestServer(t *testing.T, wrap func(http.Handler) http.Handler) *testServer {
+	db := memory.NewMemoryDB()
+	sent := mailer.NewMemory()
+	m := mailer.New(sent, &mail.Address{Address: "no-reply@example.com"}, nil)
+	logger := slog.New(slog.DiscardHandler)
+
+	locator, err := geoip.Open("")
+	require.NoError(t, err)
+
+	app := &application{
+		db:      *db.DB,
+		logger:  logger,
+		mailer:  m,
+		locator: locator,
+		authn:   &authn.Local{Users: db.Users},
+	}
+	app.config.API.BodyMaxBytes = config.DefaultBodyMaxBytes
+
+	h := app.routes()
+	if wrap != nil {
+		h = wrap(h)
+	}
+
+	ts := &testServer{
+		Server: httptest.NewServer(h),
+		app:    app,
+		mail:   sent,
+		outbox: outbox.New(db.DB, m, logger),
+	}
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func (ts *testServer) client(t *testing.T, opts ...client.Option) *client.Client {
+	c, err := client.New(ts.URL, opts...)
+	require.NoError(t, err)
+
+	return c
+}
+
+// Token of the last email to the recipient, which is its last link
+func (ts *testServer) mailedToken(t *testing.T, recipient string) string {
+	require.NoError(t, ts.outbox.Deliver(context.Background()))
+
+	msgs := ts.mail.MessagesTo(recipient)
+	require.NotEmpty(t, msgs, "no email to %s", recipient)
+
+	links := msgs[len(msgs)-1].Links()
+	require.NotEmpty(t, links)
+
+	return links[len(links)-1]
+}
+
+// Register a user with the email and testPassword
+func (ts *testServer) register(t *testing.T, c *client.Client, email string) *client.User {
+	ctx := context.Background()
+
+	_, err := c.RequestRegistration(ctx, email)
+	require.NoError(t, err)
+
+	user, err := c.Register(ctx, client.NewUser{
+		Email:    email,
+		Password: testPassword,
+		Token:    ts.mailedToken(t, email),
+	})
+	require.NoError(t, err)
+
+	return user
+}
+
+func TestClient(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ctx := context.Background()
+	c := ts.client(t)
+
+	t.Run("Healthcheck", func(t *testing.T) {
+		health, err := c.Healthcheck(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "available", health.Status)
+	})
+
+	t.Run("OpenAPI", func(t *testing.T) {
+		spec, err := c.OpenAPI(ctx)
+		require.NoError(t, err)
+		assert.True(t, json.Valid(spec))
+	})
+
+	t.Run("Register", func(t *testing.T) {
+		_, err := c.RequestRegistration(ctx, "alice@example.com")
+		require.NoError(t, err)
+		token := ts.mailedToken(t, "alice@example.com")
+
+		user, err := c.Register(ctx, client.NewUser{Email: "alice@example.com", Password: testPassword, Token: token, Locale: "en"})
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", user.Email)
+		assert.Equal(t, "en", user.Locale)
+
+		// Tokens can only be used once
+		_, err = c.Register(ctx, client.NewUser{Email: "alice@example.com", Password: testPassword, Token: token})
+		assert.ErrorIs(t, err, client.ErrUnauthorized)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		_, err := c.RequestRegistration(ctx, "alice")
+		assert.ErrorIs(t, err, client.ErrValidation)
+
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusUnprocessableEntity, apiErr.StatusCode)
+		assert.NotEmpty(t, apiErr.RequestID)
+		require.Len(t, apiErr.Fields["email"], 1)
+		assert.Equal(t, "invalid_email", apiErr.Fields["email"][0].Code)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		ts.register(t, c, "bob@example.com")
+
+		bob := ts.client(t)
+		_, err := bob.Me(ctx)
+		assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+		_, err = bob.Login(ctx, "bob@example.com", "wrong password")
+		assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+		token, err := bob.Login(ctx, "bob@example.com", testPassword)
+		require.NoError(t, err)
+		assert.Equal(t, token, bob.Token())
+
+		user, err := bob.Me(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "bob@example.com", user.Email)
+
+		at, err := bob.Reauthenticate(ctx, testPassword)
+		require.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), at.AuthenticatedAt, time.Minute)
+
+		locale := "de"
+		user, err = bob.UpdateMe(ctx, client.UserUpdate{Locale: &locale})
+		require.NoError(t, err)
+		assert.Equal(t, "de", user.Locale)
+
+		// The new device email signs out the session
+		session := ts.mailedToken(t, "bob@example.com")
+		_, err = bob.RevokeSession(ctx, session)
+		require.NoError(t, err)
+
+		_, err = bob.Me(ctx)
+		assert.ErrorIs(t, err, client.ErrUnauthorized)
+
+		_, err = bob.RevokeSession(ctx, session)
+		assert.ErrorIs(t, err, client.ErrNotFound)
+	})
+
+	t.Run("ResetPassword", func(t *testing.T) {
+		ts.register(t, c, "carol@example.com")
+
+		_, err := c.RequestPasswordReset(ctx, "carol@example.com")
+		require.NoError(t, err)
+
+		_, err = c.ResetPassword(ctx, "new password", ts.mailedToken(t, "carol@example.com"))
+		require.NoError(t, err)
+
+		_, err = c.Login(ctx, "carol@example.com", "new password")
+		assert.NoError(t, err)
+	})
+
+	t.Run("ChangeEmail", func(t *testing.T) {
+		ts.register(t, c, "dave@example.com")
+
+		dave := ts.client(t)
+		_, err := dave.Login(ctx, "dave@example.com", testPassword)
+		require.NoError(t, err)
+
+		_, err = dave.RequestEmailChange(ctx, "david@example.com")
+		require.NoError(t, err)
+
+		email := "david@example.com"
+		token := ts.mailedToken(t, email)
+		user, err := dave.UpdateMe(ctx, client.UserUpdate{Email: &email, Token: &token})
+		require.NoError(t, err)
+		assert.Equal(t, "david@example.com", user.Email)
+
+		// The previous address undoes the change and locks the account
+		_, err = c.RevertEmail(ctx, ts.mailedToken(t, "dave@example.com"))
+		require.NoError(t, err)
+
+		_, err = c.Login(ctx, "dave@example.com", testPassword)
+		assert.ErrorIs(t, err, client.ErrForbidden)
+	})
+}
+
+func TestClientRefresh(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ctx := context.Background()
+
+	ts.register(t, ts.client(t), "alice@example.com")
+
+	c := ts.client(t, client.WithCredentials("alice@example.com", testPassword))
+
+	// Signs in before the first request
+	user, err := c.Me(ctx)
+	require.NoError(t, err)
+	token := c.Token()
+	require.NotEmpty(t, token)
+
+	// Signs in again once the token is revoked
+	u, err := ts.app.db.Users.GetWithEmail(ctx, user.Email)
+	require.NoError(t, err)
+	require.NoError(t, ts.app.db.AuthenticationTokens.Purge(ctx, u.ID))
+
+	_, err = c.Me(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, token, c.Token())
+
+	// Credentials that are wrong aren't retried
+	c = ts.client(t, client.WithCredentials("alice@example.com", "wrong password"))
+	_, err = c.Me(ctx)
+	assert.ErrorIs(t, err, client.ErrUnauthorized)
+}
+
+// Fails the first requests of the path with a 503
+func failFirst(path string, failures int32, requests *atomic.Int32) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == path && requests.Add(1) <= failures {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestClientRetries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("idempotent", func(t *testing.T) {
+		var requests atomic.Int32
+		ts := newTestServer(t, failFirst("/v1/healthcheck", 2, &requests))
+		c := ts.client(t, client.WithRetries(2, time.Millisecond))
+
+		health, err := c.Healthcheck(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "available", health.Status)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("exhausted", func(t *testing.T) {
+		var requests atomic.Int32
+		ts := newTestServer(t, failFirst("/v1/healthcheck", 5, &requests))
+		c := ts.client(t, client.WithRetries(2, time.Millisecond))
+
+		_, err := c.Healthcheck(ctx)
+		var apiErr *client.Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+		assert.Equal(t, int32(3), requests.Load())
+	})
+
+	t.Run("not idempotent", func(t *testing.T) {
+		var requests atomic.Int32
+		ts := newTestServer(t, failFirst("/v1/tokens/verification/registration", 1, &requests))
+		c := ts.client(t, client.WithRetries(2, time.Millisecond))
+
+		_, err := c.RequestRegistration(ctx, "alice@example.com")
+		require.Error(t, err)
+		assert.Equal(t, int32(1), requests.Load())
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		var requests atomic.Int32
+		ts := newTestServer(t, failFirst("/v1/healthcheck", 5, &requests))
+		c := ts.client(t, client.WithRetries(5, time.Hour))
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		_, err := c.Healthcheck(ctx)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		assert.Equal(t, int32(1), requests.Load())
+	})
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"context"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+type contextKey string
+
+const (
+	userContextKey                = contextKey("user")
+	authenticationTokenContextKey = contextKey("authenticationToken")
+)
+
+func (app *application) contextSetUser(ctx context.Context, user *data.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+func (app *application) contextGetUser(ctx context.Context) *data.User {
+	user, ok := ctx.Value(userContextKey).(*data.User)
+	if !ok {
+		panic("missing user value in request context")
+	}
+
+	return user
+}
+
+func (app *application) contextSetAuthenticationToken(ctx context.Context, tokenHash []byte) context.Context {
+	return context.WithValue(ctx, authenticationTokenContextKey, tokenHash)
+}
+
+func (app *application) contextGetAuthenticationToken(ctx context.Context) []byte {
+	tokenHash, ok := ctx.Value(authenticationTokenContextKey).([]byte)
+	if !ok {
+		panic("missing authentication token value in request context")
+	}
+
+	return tokenHash
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/micahco/mono/internal/fielderr"
+)
+
+type response map[string]any
+
+func (app *application) writeJSON(w http.ResponseWriter, res response, statusCode int) error {
+	js, err := json.MarshalIndent(res, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	js = append(js, '\n')
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(js)
+
+	return nil
+}
+
+// Request body that couldn't be decoded
+type bodyError struct {
+	status  int
+	message string
+	fields  fielderr.Errors
+}
+
+func newBodyError(field, code, message string, params map[string]any) error {
+	fields := fielderr.Errors{}
+	fields.Add(field, code, message, params)
+
+	return &bodyError{http.StatusBadRequest, message, fields}
+}
+
+func (e *bodyError) Error() string {
+	return e.message
+}
+
+// Decode the body, which must be a single JSON value no larger than the
+// body limit, into dst. Fields that dst doesn't have are rejected.
+func (app *application) readJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	return app.decodeJSON(w, r, dst, true)
+}
+
+// Like readJSON, but ignores fields that dst doesn't have. SCIM clients
+// send attributes of extensions that aren't modelled.
+func (app *application) readSCIMJSON(w http.ResponseWriter, r *http.Request, dst any) error {
+	return app.decodeJSON(w, r, dst, false)
+}
+
+func (app *application) decodeJSON(w http.ResponseWriter, r *http.Request, dst any, strict bool) error {
+	maxBytes := app.config.API.BodyMaxBytes
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+
+	dec := json.NewDecoder(r.Body)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+
+	err := dec.Decode(dst)
+	if err != nil {
+		var syntaxError *json.SyntaxError
+		var unmarshalTypeError *json.UnmarshalTypeError
+		var invalidUnmarshalError *json.InvalidUnmarshalError
+		var maxBytesError *http.MaxBytesError
+		switch {
+		case errors.As(err, &syntaxError):
+			msg := fmt.Sprintf("body contains badly-formed JSON (at character %d)", syntaxError.Offset)
+			return newBodyError(fielderr.Body, fielderr.CodeInvalidJSON, msg, map[string]any{"offset": syntaxError.Offset})
+
+		case errors.Is(err, io.ErrUnexpectedEOF):
+			return newBodyError(fielderr.Body, fielderr.CodeInvalidJSON, "body contains badly-formed JSON", nil)
+
+		case errors.As(err, &unmarshalTypeError):
+			params := map[string]any{"expected": jsonType(unmarshalTypeError.Type)}
+			if unmarshalTypeError.Field != "" {
+				msg := fmt.Sprintf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+				return newBodyError(unmarshalTypeError.Field, fielderr.CodeInvalidType, msg, params)
+			}
+			msg := fmt.Sprintf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+			return newBodyError(fielderr.Body, fielderr.CodeInvalidType, msg, params)
+
+		case errors.Is(err, io.EOF):
+			return newBodyError(fielderr.Body, fielderr.CodeEmptyBody, "body must not be empty", nil)
+
+		// The decoder has no error type for unknown fields
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field, uerr := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+			if uerr != nil {
+				return err
+			}
+			msg := fmt.Sprintf("body contains unknown field %q", field)
+			return newBodyError(field, fielderr.CodeUnknownField, msg, nil)
+
+		case errors.As(err, &maxBytesError):
+			return bodyTooLarge(maxBytesError.Limit)
+
+		case errors.As(err, &invalidUnmarshalError):
+			panic(err)
+
+		default:
+			return err
+		}
+	}
+
+	// Anything but whitespace after the value is another value
+	err = dec.Decode(&struct{}{})
+	if !errors.Is(err, io.EOF) {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			return bodyTooLarge(maxBytesError.Limit)
+		}
+
+		return newBodyError(fielderr.Body, fielderr.CodeMultipleValues, "body must only contain a single JSON value", nil)
+	}
+
+	return nil
+}
+
+func bodyTooLarge(limit int64) error {
+	msg := fmt.Sprintf("body must not be larger than %d bytes", limit)
+
+	fields := fielderr.Errors{}
+	fields.Add(fielderr.Body, fielderr.CodeTooLarge, msg, map[string]any{"max": limit})
+
+	return &bodyError{http.StatusRequestEntityTooLarge, msg, fields}
+}
+
+// Name of the JSON type that decodes into the Go type
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	default:
+		return "object"
+	}
+}
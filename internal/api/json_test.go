@@ -0,0 +1,135 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micahco/mono/internal/fielderr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMaxBodyBytes = 128
+
+type testInput struct {
+	Email    string   `json:"email"`
+	Password string   `json:"password"`
+	Active   *bool    `json:"active"`
+	Count    int      `json:"count"`
+	Tags     []string `json:"tags"`
+}
+
+func newTestApplication() *application {
+	app := &application{}
+	app.config.API.BodyMaxBytes = testMaxBodyBytes
+
+	return app
+}
+
+func readTestJSON(app *application, body io.Reader, strict bool) error {
+	r := httptest.NewRequest(http.MethodPost, "/", body)
+	w := httptest.NewRecorder()
+
+	var input testInput
+	if strict {
+		return app.readJSON(w, r, &input)
+	}
+
+	return app.readSCIMJSON(w, r, &input)
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestReadJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+		code   string
+	}{
+		{"valid", `{"email": "alice@example.com", "password": "pa55word"}`, 0, "", ""},
+		{"trailing whitespace", "{\"count\": 1}\n\t ", 0, "", ""},
+		{"syntax", `{"email": "alice@example.com",}`, http.StatusBadRequest, fielderr.Body, fielderr.CodeInvalidJSON},
+		{"unexpected EOF", `{"email": "alice@example.com"`, http.StatusBadRequest, fielderr.Body, fielderr.CodeInvalidJSON},
+		{"field type", `{"count": "one"}`, http.StatusBadRequest, "count", fielderr.CodeInvalidType},
+		{"body type", `["alice@example.com"]`, http.StatusBadRequest, fielderr.Body, fielderr.CodeInvalidType},
+		{"empty", ``, http.StatusBadRequest, fielderr.Body, fielderr.CodeEmptyBody},
+		{"unknown field", `{"email": "alice@example.com", "pasword": "pa55word"}`, http.StatusBadRequest, "pasword", fielderr.CodeUnknownField},
+		{"too large", `{"email": "` + strings.Repeat("a", testMaxBodyBytes) + `"}`, http.StatusRequestEntityTooLarge, fielderr.Body, fielderr.CodeTooLarge},
+		{"too large after value", `{}` + strings.Repeat(" ", testMaxBodyBytes), http.StatusRequestEntityTooLarge, fielderr.Body, fielderr.CodeTooLarge},
+		{"multiple values", `{"count": 1}{"count": 2}`, http.StatusBadRequest, fielderr.Body, fielderr.CodeMultipleValues},
+		{"trailing garbage", `{"count": 1}}`, http.StatusBadRequest, fielderr.Body, fielderr.CodeMultipleValues},
+	}
+
+	app := newTestApplication()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := readTestJSON(app, strings.NewReader(tt.body), true)
+			if tt.status == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var bodyErr *bodyError
+			require.ErrorAs(t, err, &bodyErr)
+			assert.Equal(t, tt.status, bodyErr.status)
+			require.Len(t, bodyErr.fields[tt.field], 1)
+			assert.Equal(t, tt.code, bodyErr.fields[tt.field][0].Code)
+			assert.Equal(t, bodyErr.message, bodyErr.fields[tt.field][0].Message)
+		})
+	}
+
+	t.Run("unknown field of SCIM", func(t *testing.T) {
+		err := readTestJSON(app, strings.NewReader(`{"email": "alice@example.com", "name": {}}`), false)
+		assert.NoError(t, err)
+	})
+
+	t.Run("read error", func(t *testing.T) {
+		err := readTestJSON(app, errReader{}, true)
+		require.Error(t, err)
+
+		var bodyErr *bodyError
+		assert.False(t, errors.As(err, &bodyErr))
+	})
+}
+
+func FuzzReadJSON(f *testing.F) {
+	f.Add(`{"email": "alice@example.com", "password": "pa55word", "active": true, "count": 1, "tags": ["a"]}`, true)
+	f.Add(`{"email": "alice@example.com",}`, true)
+	f.Add(`{"email": "alice@example.com"`, true)
+	f.Add(`{"count": "one"}`, true)
+	f.Add(`[]`, true)
+	f.Add(``, true)
+	f.Add(`{"pasword": ""}`, true)
+	f.Add(`{"pasword": ""}`, false)
+	f.Add(`{"tags": ["`+strings.Repeat("a", testMaxBodyBytes)+`"]}`, true)
+	f.Add(`{} {}`, false)
+	f.Add(`null`, true)
+
+	app := newTestApplication()
+	f.Fuzz(func(t *testing.T, body string, strict bool) {
+		err := readTestJSON(app, strings.NewReader(body), strict)
+		if err == nil {
+			return
+		}
+
+		// Every failure to decode the body is described to the client
+		var bodyErr *bodyError
+		require.ErrorAs(t, err, &bodyErr)
+		assert.Contains(t, []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge}, bodyErr.status)
+		assert.NotEmpty(t, bodyErr.message)
+		assert.Len(t, bodyErr.fields, 1)
+		if bodyErr.status == http.StatusRequestEntityTooLarge {
+			assert.Greater(t, len(body), testMaxBodyBytes)
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/middleware"
+)
+
+type handlerWithError func(w http.ResponseWriter, r *http.Request) error
+
+func (app *application) handle(h handlerWithError) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h(w, r); err != nil {
+			var validationError validation.Errors
+			var bodyErr *bodyError
+			switch {
+			case errors.As(err, &bodyErr):
+				app.problemResponse(w, r, invalidBodyProblem(bodyErr.status, bodyErr.message, bodyErr.fields))
+			case errors.As(err, &validationError):
+				app.problemResponse(w, r, validationProblem(validationError.Error(), fieldErrors(validationError)))
+			default:
+				app.serverError(w, r, "handled unexpected error", err)
+			}
+		}
+	}
+}
+
+// Writes to response writer with error message and status code. Mimics http.Error()
+func (app *application) errorResponse(w http.ResponseWriter, r *http.Request, errorMessage string, statusCode int) {
+	app.problemResponse(w, r, problemForStatus(statusCode, errorMessage))
+}
+
+// Writes the problem to the response writer
+func (app *application) problemResponse(w http.ResponseWriter, r *http.Request, p *problem) {
+	err := app.writeProblem(w, r, p)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// Logs error and responds with generic internal server error message.
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, logMsg string, err error) {
+	app.logger.Error(
+		logMsg,
+		slog.Any("err", err),
+		slog.String("type", fmt.Sprintf("%T", err)),
+		slog.String("request_id", middleware.GetRequestID(r.Context())),
+	)
+
+	app.errorResponse(w, r, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func (app *application) recovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if err := recover(); err != nil {
+				w.Header().Set("Connection", "close")
+
+				app.serverError(w, r, "recovered from panic", fmt.Errorf("%s", err))
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (app *application) authenticate(next http.Handler) http.Handler {
+	invalidAuthenticationTokenMessage := "invalid or expired authentication token"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Add the "Vary: Authorization" header to the response. This indicates to any
+		// caches that the response may vary based on the value of the Authorization
+		// header in the request.
+		w.Header().Add("Vary", "Authorization")
+
+		authorizationHeader := r.Header.Get("Authorization")
+		if authorizationHeader == "" {
+			// Create new context with request context setting user anon
+			ctx := app.contextSetUser(r.Context(), data.AnonymousUser)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		headerParts := strings.Split(authorizationHeader, " ")
+		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.errorResponse(w, r, invalidAuthenticationTokenMessage, http.StatusUnauthorized)
+			return
+		}
+
+		plaintextToken := headerParts[1]
+		tokenHash := crypto.TokenHash(plaintextToken)
+
+		user, err := app.db.Users.GetWithAuthenticationToken(r.Context(), tokenHash)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound),
+				errors.Is(err, data.ErrExpiredToken):
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				app.errorResponse(w, r, invalidAuthenticationTokenMessage, http.StatusUnauthorized)
+			default:
+				app.serverError(w, r, "unable to get authentication token", err)
+			}
+			return
+		}
+
+		if user.Locked || !user.Active {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			app.errorResponse(w, r, invalidAuthenticationTokenMessage, http.StatusUnauthorized)
+			return
+		}
+
+		// Add authenticated user and their token to this request's context
+		ctx := app.contextSetUser(r.Context(), user)
+		ctx = app.contextSetAuthenticationToken(ctx, tokenHash)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Requires the bearer token of the realm. The hashes of the tokens are
+// compared, so the comparison takes the same time for any token.
+func (app *application) requireBearerToken(token, realm string) func(next http.Handler) http.Handler {
+	expected := sha256.Sum256([]byte(token))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Authorization")
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			actual := sha256.Sum256([]byte(token))
+			if !ok || subtle.ConstantTimeCompare(expected[:], actual[:]) != 1 {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", realm))
+
+				message := fmt.Sprintf("invalid %s token", realm)
+				// SCIM clients expect errors of the SCIM schema
+				if realm == scimRealm {
+					app.writeSCIMError(w, http.StatusUnauthorized, "", message)
+					return
+				}
+				app.errorResponse(w, r, message, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (app *application) requireAuthentication(next http.Handler) http.Handler {
+	authenticationRequiredMessage := "invalid or expired authentication token"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := app.contextGetUser(r.Context())
+
+		if user.IsAnonymous() {
+			app.errorResponse(w, r, authenticationRequiredMessage, http.StatusUnauthorized)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Requires the authenticated user to have entered their password recently.
+// Must be used after requireAuthentication.
+func (app *application) requireRecentAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokenHash := app.contextGetAuthenticationToken(r.Context())
+
+		at, err := app.db.AuthenticationTokens.Get(r.Context(), tokenHash)
+		if err != nil {
+			app.serverError(w, r, "unable to get authentication token", err)
+			return
+		}
+
+		if !at.IsFresh() {
+			app.errorResponse(w, r, reauthenticationRequiredMessage, http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
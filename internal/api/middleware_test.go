@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireBearerToken(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name          string
+		realm         string
+		authorization string
+		status        int
+		contentType   string
+	}{
+		{"valid", "admin", "Bearer secret", http.StatusNoContent, ""},
+		{"wrong token", "admin", "Bearer wrong", http.StatusUnauthorized, "application/problem+json"},
+		{"prefix of token", "admin", "Bearer secre", http.StatusUnauthorized, "application/problem+json"},
+		{"not bearer", "admin", "Basic secret", http.StatusUnauthorized, "application/problem+json"},
+		{"missing", "bounce", "", http.StatusUnauthorized, "application/problem+json"},
+		{"scim error", scimRealm, "Bearer wrong", http.StatusUnauthorized, scimContentType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := ts.app.requireBearerToken("secret", tt.realm)(ok)
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, "Authorization", w.Header().Get("Vary"))
+			if tt.status == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="`+tt.realm+`"`, w.Header().Get("WWW-Authenticate"))
+				assert.Contains(t, w.Header().Get("Content-Type"), tt.contentType)
+			}
+		})
+	}
+}
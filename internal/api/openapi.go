@@ -0,0 +1,450 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/fielderr"
+	"github.com/micahco/mono/ui"
+)
+
+// Bodies of successful responses. Handlers respond with maps of the same
+// shape; these only describe them.
+type (
+	healthcheckResponse struct {
+		Status     string            `json:"status"`
+		SystemInfo map[string]string `json:"system_info"`
+	}
+	messageResponse struct {
+		Message string `json:"message"`
+	}
+	userResponse struct {
+		User *data.User `json:"user"`
+	}
+	newAuthenticationTokenResponse struct {
+		// Bearer token of the Authorization header
+		AuthenticationToken string `json:"authentication_token"`
+	}
+	authenticationTokenResponse struct {
+		AuthenticationToken *data.AuthenticationToken `json:"authentication_token"`
+	}
+)
+
+type apiOperation struct {
+	method  string
+	path    string
+	summary string
+	// Whether the operation requires an authentication token
+	auth bool
+	// Body of the request, if any
+	input  any
+	status int
+	// Body of the successful response, and its media type if it isn't JSON
+	output      any
+	contentType string
+	// Statuses of problems that the operation responds with, besides
+	// those of any operation
+	problems []int
+}
+
+// Every /v1 route. A test fails if this drifts from the router.
+var apiOperations = []apiOperation{
+	{
+		method:  http.MethodGet,
+		path:    "/v1/healthcheck",
+		summary: "Report the status of the API",
+		status:  http.StatusOK,
+		output:  healthcheckResponse{},
+	},
+	{
+		method:  http.MethodGet,
+		path:    "/v1/openapi.json",
+		summary: "Get this specification",
+		status:  http.StatusOK,
+		output:  map[string]any{},
+	},
+	{
+		method:      http.MethodGet,
+		path:        "/v1/docs",
+		summary:     "Browse this specification",
+		status:      http.StatusOK,
+		output:      "",
+		contentType: "text/html",
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/v1/tokens/authentication",
+		summary:  "Exchange credentials for an authentication token",
+		input:    credentialsInput{},
+		status:   http.StatusCreated,
+		output:   newAuthenticationTokenResponse{},
+		problems: []int{http.StatusUnauthorized, http.StatusForbidden},
+	},
+	{
+		method:   http.MethodPut,
+		path:     "/v1/tokens/authentication",
+		summary:  "Confirm the password to allow sensitive changes with the token",
+		auth:     true,
+		input:    passwordInput{},
+		status:   http.StatusOK,
+		output:   authenticationTokenResponse{},
+		problems: []int{http.StatusUnauthorized},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/v1/tokens/authentication/revoke",
+		summary:  "Sign out the session of a new device email",
+		input:    sessionInput{},
+		status:   http.StatusOK,
+		output:   messageResponse{},
+		problems: []int{http.StatusUnauthorized, http.StatusNotFound},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/v1/tokens/verification/registration",
+		summary:  "Mail a token to register the email address",
+		input:    verificationInput{},
+		status:   http.StatusOK,
+		output:   messageResponse{},
+		problems: []int{http.StatusTooManyRequests},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/v1/tokens/verification/password-reset",
+		summary:  "Mail a token to reset the password of the email address",
+		input:    verificationInput{},
+		status:   http.StatusOK,
+		output:   messageResponse{},
+		problems: []int{http.StatusTooManyRequests},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/v1/tokens/verification/email-change",
+		summary:  "Mail a token to change the email of the user to the email address",
+		auth:     true,
+		input:    verificationInput{},
+		status:   http.StatusOK,
+		output:   messageResponse{},
+		problems: []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests},
+	},
+	{
+		method:   http.MethodPost,
+		path:     "/v1/users",
+		summary:  "Create a user with a registration token",
+		input:    newUserInput{},
+		status:   http.StatusCreated,
+		output:   userResponse{},
+		problems: []int{http.StatusUnauthorized},
+	},
+	{
+		method:   http.MethodPut,
+		path:     "/v1/users/password",
+		summary:  "Reset the password with a password reset token",
+		input:    passwordResetInput{},
+		status:   http.StatusOK,
+		output:   messageResponse{},
+		problems: []int{http.StatusUnauthorized},
+	},
+	{
+		method:   http.MethodPut,
+		path:     "/v1/users/email/revert",
+		summary:  "Restore the previous email address and lock the account",
+		input:    emailRevertInput{},
+		status:   http.StatusOK,
+		output:   messageResponse{},
+		problems: []int{http.StatusUnauthorized, http.StatusConflict},
+	},
+	{
+		method:   http.MethodGet,
+		path:     "/v1/users/me",
+		summary:  "Get the authenticated user",
+		auth:     true,
+		status:   http.StatusOK,
+		output:   userResponse{},
+		problems: []int{http.StatusUnauthorized},
+	},
+	{
+		method:   http.MethodPut,
+		path:     "/v1/users/me",
+		summary:  "Update the authenticated user",
+		auth:     true,
+		input:    userUpdateInput{},
+		status:   http.StatusCreated,
+		output:   userResponse{},
+		problems: []int{http.StatusUnauthorized, http.StatusForbidden},
+	},
+}
+
+// Statuses of problems that operations with a body respond with
+var bodyProblems = []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity}
+
+// The OpenAPI document, which only changes with the binary
+var openAPIDocument = sync.OnceValues(func() ([]byte, error) {
+	return json.MarshalIndent(openAPISpec(), "", "\t")
+})
+
+func (app *application) openAPIGet(w http.ResponseWriter, r *http.Request) error {
+	js, err := openAPIDocument()
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+
+	return nil
+}
+
+// Viewer of the OpenAPI document that works offline
+func (app *application) docsGet(w http.ResponseWriter, r *http.Request) error {
+	http.ServeFileFS(w, r, ui.DocsFiles, "docs/index.html")
+
+	return nil
+}
+
+func openAPISpec() map[string]any {
+	g := newSchemaGenerator()
+
+	problemSchema := g.schema(reflect.TypeFor[problem]())
+
+	paths := map[string]map[string]any{}
+	for _, op := range apiOperations {
+		contentType := op.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+
+		responses := map[string]any{
+			strconv.Itoa(op.status): map[string]any{
+				"description": http.StatusText(op.status),
+				"content": map[string]any{
+					contentType: map[string]any{"schema": g.schema(reflect.TypeOf(op.output))},
+				},
+			},
+			"default": map[string]any{"$ref": "#/components/responses/Problem"},
+		}
+
+		problems := slices.Clone(op.problems)
+		if op.input != nil {
+			problems = append(problems, bodyProblems...)
+		}
+		for _, status := range problems {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": http.StatusText(status),
+				"content":     problemContent(problemSchema),
+			}
+		}
+
+		operation := map[string]any{
+			"operationId": operationID(op.method, op.path),
+			"summary":     op.summary,
+			"tags":        []string{operationTag(op.path)},
+			"responses":   responses,
+		}
+		if op.auth {
+			operation["security"] = []map[string][]string{{"bearerAuth": {}}}
+		}
+		if op.input != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": g.input(reflect.TypeOf(op.input))},
+				},
+			}
+		}
+
+		if paths[op.path] == nil {
+			paths[op.path] = map[string]any{}
+		}
+		paths[op.path][strings.ToLower(op.method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":   "Mono API",
+			"version": "1",
+			"description": "Errors are problem details (RFC 9457) for clients that accept application/problem+json. " +
+				"Every response has an X-Request-Id header, which problems repeat as request_id.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": g.schemas,
+			"responses": map[string]any{
+				"Problem": map[string]any{
+					"description": "Problem",
+					"content":     problemContent(problemSchema),
+				},
+			},
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "Authentication token from POST /v1/tokens/authentication",
+				},
+			},
+		},
+	}
+}
+
+func problemContent(schema map[string]any) map[string]any {
+	return map[string]any{
+		problemContentType: map[string]any{"schema": schema},
+	}
+}
+
+// Tag of the operations of the first path segment after the version, e.g.
+// "users"
+func operationTag(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/v1/"), "/")
+
+	return strings.TrimSuffix(segment, ".json")
+}
+
+// Name of the operation, e.g. "putUsersEmailRevert"
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(strings.TrimPrefix(path, "/v1"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.'
+	}) {
+		b.WriteString(exportName(part))
+	}
+
+	return b.String()
+}
+
+// Generates JSON schemas of Go types the way encoding/json encodes them.
+// Named structs become components, which the schemas reference.
+type schemaGenerator struct {
+	schemas map[string]any
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{schemas: map[string]any{}}
+}
+
+// Names of components that would otherwise collide
+var schemaNames = map[reflect.Type]string{
+	reflect.TypeFor[fielderr.Error](): "FieldError",
+}
+
+func schemaName(t reflect.Type) string {
+	if name, ok := schemaNames[t]; ok {
+		return name
+	}
+
+	return exportName(t.Name())
+}
+
+// Schema of a request body. Fields that it doesn't have are rejected.
+func (g *schemaGenerator) input(t reflect.Type) map[string]any {
+	name := schemaName(t)
+	if _, ok := g.schemas[name]; !ok {
+		s := g.object(t)
+		s["additionalProperties"] = false
+		g.schemas[name] = s
+	}
+
+	return ref(name)
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	if t == reflect.TypeFor[time.Time]() {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schema(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+
+		name := schemaName(t)
+		if _, ok := g.schemas[name]; !ok {
+			// Reserve the name for recursive types
+			g.schemas[name] = nil
+			g.schemas[name] = g.object(t)
+		}
+		return ref(name)
+	default:
+		// Any value
+		return map[string]any{}
+	}
+}
+
+// Schema of the exported fields of the struct. Fields that are neither
+// pointers nor omitted when empty are required.
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = g.schema(f.Type)
+		if f.Type.Kind() != reflect.Pointer && !slices.Contains(strings.Split(opts, ","), "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// Name with an upper case first letter
+func exportName(name string) string {
+	if name == "" {
+		return name
+	}
+
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+
+	return string(r)
+}
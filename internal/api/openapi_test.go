@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIRoutes(t *testing.T) {
+	routes := map[string]bool{}
+	err := chi.Walk(newTestApplication().routes().(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/v1/") {
+			routes[method+" "+strings.TrimSuffix(route, "/")] = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	spec := map[string]bool{}
+	for _, op := range apiOperations {
+		spec[op.method+" "+op.path] = true
+	}
+
+	for route := range routes {
+		assert.True(t, spec[route], "route %s is missing from the specification", route)
+	}
+	for op := range spec {
+		assert.True(t, routes[op], "operation %s has no route", op)
+	}
+}
+
+func TestOpenAPIGet(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	newTestApplication().routes().ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var spec map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.1.0", spec["openapi"])
+
+	// Every reference is to a component
+	var refs []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				if ref, ok := child.(string); ok && k == "$ref" {
+					refs = append(refs, ref)
+				}
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(spec)
+	require.NotEmpty(t, refs)
+
+	for _, ref := range refs {
+		var v any = spec
+		for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, ok := v.(map[string]any)
+			require.True(t, ok, "unresolved reference %s", ref)
+			v = m[key]
+		}
+		assert.NotNil(t, v, "unresolved reference %s", ref)
+	}
+
+	// Request bodies are the input structs of the handlers
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	newUser := schemas["NewUserInput"].(map[string]any)
+	assert.ElementsMatch(t, []any{"email", "password", "token"}, newUser["required"])
+	assert.Contains(t, newUser["properties"], "locale")
+	assert.Equal(t, false, newUser["additionalProperties"])
+}
+
+func TestDocsGet(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/v1/docs", nil)
+	w := httptest.NewRecorder()
+	newTestApplication().routes().ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), "openapi.json")
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/micahco/mono/internal/fielderr"
+	"github.com/micahco/mono/internal/middleware"
+)
+
+// Types of problems. They are stable, so clients may switch on them.
+// Problems that are described by their status alone are about:blank.
+const (
+	problemTypeBlank        = "about:blank"
+	problemTypeValidation   = "urn:mono:problem:validation"
+	problemTypeInvalidBody  = "urn:mono:problem:invalid-body"
+	problemTypeUnauthorized = "urn:mono:problem:unauthorized"
+	problemTypeExpiredToken = "urn:mono:problem:expired-token"
+	problemTypeForbidden    = "urn:mono:problem:forbidden"
+	problemTypeNotFound     = "urn:mono:problem:not-found"
+	problemTypeConflict     = "urn:mono:problem:conflict"
+	problemTypeRateLimit    = "urn:mono:problem:rate-limit"
+)
+
+const problemContentType = "application/problem+json"
+
+// When the legacy error shape was deprecated, sent in the Deprecation
+// header (RFC 9745) of legacy responses
+var legacyErrorsDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Problem details (RFC 9457) of an error response
+type problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id"`
+	// Errors of each field, for validation problems
+	Fields fielderr.Errors `json:"fields,omitempty"`
+}
+
+func newProblem(typ string, status int, title, detail string) *problem {
+	return &problem{
+		Type:   typ,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Problem that is described by its status, and the detail if given
+func statusProblem(status int, detail string) *problem {
+	if detail == http.StatusText(status) {
+		detail = ""
+	}
+
+	return newProblem(problemTypeBlank, status, http.StatusText(status), detail)
+}
+
+// Fields of the request that failed validation
+func validationProblem(detail string, fields fielderr.Errors) *problem {
+	p := newProblem(problemTypeValidation, http.StatusUnprocessableEntity, "Invalid request", detail)
+	p.Fields = fields
+
+	return p
+}
+
+// Request body that couldn't be decoded, with a status of 400 or 413
+func invalidBodyProblem(status int, detail string, fields fielderr.Errors) *problem {
+	p := newProblem(problemTypeInvalidBody, status, "Invalid request body", detail)
+	p.Fields = fields
+
+	return p
+}
+
+// Missing or wrong credentials
+func unauthorizedProblem(detail string) *problem {
+	return newProblem(problemTypeUnauthorized, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized), detail)
+}
+
+// Token that was valid but has expired
+func expiredTokenProblem(detail string) *problem {
+	return newProblem(problemTypeExpiredToken, http.StatusUnauthorized, "Expired token", detail)
+}
+
+// Authenticated user that isn't allowed to make the request
+func forbiddenProblem(detail string) *problem {
+	return newProblem(problemTypeForbidden, http.StatusForbidden, http.StatusText(http.StatusForbidden), detail)
+}
+
+func notFoundProblem() *problem {
+	return newProblem(problemTypeNotFound, http.StatusNotFound, http.StatusText(http.StatusNotFound), "")
+}
+
+// Request that conflicts with the current state of a resource
+func conflictProblem(detail string) *problem {
+	return newProblem(problemTypeConflict, http.StatusConflict, http.StatusText(http.StatusConflict), detail)
+}
+
+func rateLimitProblem(detail string) *problem {
+	return newProblem(problemTypeRateLimit, http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests), detail)
+}
+
+// Typed problem of the status, for errors that are only known by their
+// status and message
+func problemForStatus(status int, detail string) *problem {
+	if detail == http.StatusText(status) {
+		detail = ""
+	}
+
+	switch status {
+	case http.StatusUnauthorized:
+		return unauthorizedProblem(detail)
+	case http.StatusForbidden:
+		return forbiddenProblem(detail)
+	case http.StatusNotFound:
+		return notFoundProblem()
+	case http.StatusConflict:
+		return conflictProblem(detail)
+	case http.StatusTooManyRequests:
+		return rateLimitProblem(detail)
+	default:
+		return statusProblem(status, detail)
+	}
+}
+
+// Respond with the problem, as problem+json if the client accepts it and
+// in the legacy {"error": ...} shape otherwise. Legacy responses are
+// deprecated; once the legacy shape is disabled, every client gets
+// problem+json.
+func (app *application) writeProblem(w http.ResponseWriter, r *http.Request, p *problem) error {
+	p.Instance = r.URL.Path
+	p.RequestID = middleware.GetRequestID(r.Context())
+
+	w.Header().Add("Vary", "Accept")
+
+	if !app.config.API.LegacyErrors || acceptsProblem(r) {
+		js, err := json.MarshalIndent(p, "", "\t")
+		if err != nil {
+			return err
+		}
+
+		js = append(js, '\n')
+
+		w.Header().Set("Content-Type", problemContentType)
+		w.WriteHeader(p.Status)
+		w.Write(js)
+
+		return nil
+	}
+
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+
+	res := response{
+		"error":      message,
+		"request_id": p.RequestID,
+	}
+	if p.Fields != nil {
+		res["fields"] = p.Fields
+	}
+
+	w.Header().Set("Deprecation", fmt.Sprintf("@%d", legacyErrorsDeprecatedAt.Unix()))
+
+	return app.writeJSON(w, res, p.Status)
+}
+
+// Whether the Accept header of the request names problem+json
+func acceptsProblem(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err == nil && mediaType == problemContentType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/micahco/mono/internal/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Response of the request to the test server, with its body decoded
+func doJSON(t *testing.T, ts *testServer, method, path, body string, header http.Header) (*http.Response, map[string]any) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header = header
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var v map[string]any
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&v))
+
+	return res, v
+}
+
+func TestWriteProblem(t *testing.T) {
+	const requestID = "test-request-id"
+
+	tests := []struct {
+		name         string
+		legacyErrors bool
+		accept       string
+		problem      bool
+	}{
+		{"problem", false, "", true},
+		{"problem accepted", true, "application/json, application/problem+json;q=0.9", true},
+		{"legacy", true, "", false},
+		{"legacy json", true, "application/json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestServer(t, nil)
+			ts.app.config.API.LegacyErrors = tt.legacyErrors
+
+			header := http.Header{}
+			header.Set(middleware.RequestIDHeader, requestID)
+			if tt.accept != "" {
+				header.Set("Accept", tt.accept)
+			}
+
+			// Unauthenticated
+			res, body := doJSON(t, ts, http.MethodGet, "/v1/users/me", "", header)
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+			assert.Contains(t, res.Header.Values("Vary"), "Accept")
+			assert.Equal(t, requestID, body["request_id"])
+
+			if tt.problem {
+				assert.Equal(t, problemContentType, res.Header.Get("Content-Type"))
+				assert.Empty(t, res.Header.Get("Deprecation"))
+				assert.Equal(t, map[string]any{
+					"type":       problemTypeUnauthorized,
+					"title":      "Unauthorized",
+					"status":     float64(http.StatusUnauthorized),
+					"detail":     "invalid or expired authentication token",
+					"instance":   "/v1/users/me",
+					"request_id": requestID,
+				}, body)
+			} else {
+				assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+				assert.Equal(t, "@1792368000", res.Header.Get("Deprecation"))
+				assert.Equal(t, map[string]any{
+					"error":      "invalid or expired authentication token",
+					"request_id": requestID,
+				}, body)
+			}
+
+			// Validation errors keep their fields in both shapes
+			res, body = doJSON(t, ts, http.MethodPost, "/v1/tokens/verification/registration", `{"email": "invalid"}`, header)
+			assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+			assert.Contains(t, body, "fields")
+			if tt.problem {
+				assert.Equal(t, problemTypeValidation, body["type"])
+				assert.Equal(t, "/v1/tokens/verification/registration", body["instance"])
+			} else {
+				assert.NotContains(t, body, "type")
+				assert.NotEmpty(t, body["error"])
+			}
+		})
+	}
+}
+
+func TestProblemForStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		detail string
+		typ    string
+		want   string
+	}{
+		{http.StatusUnauthorized, "bad token", problemTypeUnauthorized, "bad token"},
+		{http.StatusForbidden, "", problemTypeForbidden, ""},
+		{http.StatusNotFound, "no such user", problemTypeNotFound, ""},
+		{http.StatusConflict, "email taken", problemTypeConflict, "email taken"},
+		{http.StatusTooManyRequests, "Too Many Requests", problemTypeRateLimit, ""},
+		{http.StatusMethodNotAllowed, "Method Not Allowed", problemTypeBlank, ""},
+		{http.StatusInternalServerError, "", problemTypeBlank, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			p := problemForStatus(tt.status, tt.detail)
+			assert.Equal(t, tt.typ, p.Type)
+			assert.Equal(t, tt.status, p.Status)
+			assert.Equal(t, tt.want, p.Detail)
+			assert.NotEmpty(t, p.Title)
+		})
+	}
+}
+
+func TestAcceptsProblem(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/json", false},
+		{"application/problem+json", true},
+		{"application/json, application/problem+json", true},
+		{"application/problem+json; charset=utf-8", true},
+		{"invalid;;", false},
+	}
+
+	for _, tt := range tests {
+		r, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		r.Header.Set("Accept", tt.accept)
+
+		assert.Equal(t, tt.want, acceptsProblem(r), tt.accept)
+	}
+}
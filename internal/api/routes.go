@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/micahco/mono/internal/devmail"
+	"github.com/micahco/mono/internal/middleware"
+)
+
+// App router
+func (app *application) routes() http.Handler {
+	r := chi.NewRouter()
+
+	// Middleware
+	r.Use(middleware.RequestID)
+	r.Use(middleware.RealIP(app.config.TrustedProxies))
+	r.Use(middleware.StripSlashes)
+	r.Use(middleware.Metrics)
+	r.Use(app.recovery)
+	r.Use(middleware.EnableCORS(app.config.API.TrustedOrigins))
+	if app.config.API.Limiter.Enabled {
+		r.Use(middleware.RateLimit(app.config.API.Limiter.RPS, app.errorResponse))
+	}
+	r.NotFound(app.handle(app.notFound))
+	r.MethodNotAllowed(app.handle(app.methodNotAllowed))
+
+	// Metrics
+	r.Mount("/debug", middleware.Profiler())
+
+	// Email previews and captured mail
+	if app.config.Dev {
+		r.Mount(devmail.Path, devmail.New(app.mailer, app.sink))
+	}
+
+	// API
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(app.authenticate)
+
+		r.Get("/healthcheck", app.handle(app.healthcheck))
+		r.Get("/openapi.json", app.handle(app.openAPIGet))
+		r.Get("/docs", app.handle(app.docsGet))
+
+		r.Route("/tokens", func(r chi.Router) {
+			r.Post("/authentication", app.handle(app.tokensAuthenticationPost))
+			r.With(app.requireAuthentication).Put("/authentication", app.handle(app.tokensAuthenticationPut))
+			r.Post("/authentication/revoke", app.handle(app.tokensAuthenticationRevokePost))
+
+			r.Route("/verification", func(r chi.Router) {
+				r.Post("/registration", app.handle(app.tokensVerificaitonRegistrationPost))
+				r.Post("/password-reset", app.handle(app.tokensVerificaitonPasswordResetPost))
+
+				r.Route("/email-change", func(r chi.Router) {
+					r.Use(app.requireAuthentication)
+					r.Use(app.requireRecentAuthentication)
+
+					r.Post("/", app.handle(app.tokensVerificaitonEmailChangePost))
+				})
+			})
+		})
+
+		r.Route("/users", func(r chi.Router) {
+			r.Post("/", app.handle(app.usersPost))
+			r.Put("/password", app.handle(app.usersPasswordPut))
+			r.Put("/email/revert", app.handle(app.usersEmailRevertPut))
+
+			r.Route("/me", func(r chi.Router) {
+				r.Use(app.requireAuthentication)
+
+				r.Get("/", app.handle(app.usersMeGet))
+				r.With(app.requireRecentAuthentication).Put("/", app.handle(app.usersMePut))
+			})
+		})
+	})
+
+	// SCIM provisioning for identity providers
+	if app.config.API.SCIMToken != "" {
+		r.Route("/scim/v2", func(r chi.Router) {
+			r.Use(app.requireBearerToken(app.config.API.SCIMToken, scimRealm))
+
+			r.Get("/ServiceProviderConfig", app.handle(app.scimServiceProviderConfigGet))
+
+			r.Route("/Users", func(r chi.Router) {
+				r.Get("/", app.handle(app.scimUsersGet))
+				r.Post("/", app.handle(app.scimUsersPost))
+				r.Get("/{id}", app.handle(app.scimUserGet))
+				r.Put("/{id}", app.handle(app.scimUserPut))
+				r.Patch("/{id}", app.handle(app.scimUserPatch))
+				r.Delete("/{id}", app.handle(app.scimUserDelete))
+			})
+		})
+	}
+
+	// Delivery status notifications and abuse reports
+	if app.config.API.Bounce.Token != "" {
+		r.With(app.requireBearerToken(app.config.API.Bounce.Token, "bounce")).Post("/bounces", app.handle(app.bouncesPost))
+	}
+
+	// Operator endpoints
+	if app.config.API.AdminToken != "" {
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.requireBearerToken(app.config.API.AdminToken, "admin"))
+
+			r.Get("/outbox/dead", app.handle(app.adminOutboxDeadGet))
+			r.Post("/outbox/{id}/retry", app.handle(app.adminOutboxRetryPost))
+
+			r.Get("/suppressions/{email}", app.handle(app.adminSuppressionGet))
+			r.Delete("/suppressions/{email}", app.handle(app.adminSuppressionDelete))
+		})
+	}
+
+	return r
+}
+
+func (app *application) notFound(w http.ResponseWriter, r *http.Request) error {
+	return app.writeProblem(w, r, notFoundProblem())
+}
+
+func (app *application) methodNotAllowed(w http.ResponseWriter, r *http.Request) error {
+	return app.writeProblem(w, r, statusProblem(http.StatusMethodNotAllowed, ""))
+}
+
+func (app *application) healthcheck(w http.ResponseWriter, r *http.Request) error {
+	env := "production"
+	if app.config.Dev {
+		env = "development"
+	}
+
+	res := response{
+		"status": "available",
+		"system_info": map[string]string{
+			"environment": env,
+		},
+	}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
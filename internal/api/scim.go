@@ -0,0 +1,658 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-chi/chi/v5"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+)
+
+// SCIM 2.0 (RFC 7643, RFC 7644) provisioning of users by an identity provider
+
+const (
+	scimUserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimPatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	scimServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimContentType                 = "application/scim+json"
+	scimMaxResults                  = 100
+	// Realm of the provisioning client's bearer token
+	scimRealm = "provisioning"
+)
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	Location     string    `json:"location"`
+	Version      string    `json:"version"`
+}
+
+type scimUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Active      *bool       `json:"active,omitempty"`
+	Emails      []scimEmail `json:"emails,omitempty"`
+	Password    string      `json:"password,omitempty"`
+	Meta        *scimMeta   `json:"meta,omitempty"`
+}
+
+type scimListResponse struct {
+	Schemas      []string   `json:"schemas"`
+	TotalResults int        `json:"totalResults"`
+	StartIndex   int        `json:"startIndex"`
+	ItemsPerPage int        `json:"itemsPerPage"`
+	Resources    []scimUser `json:"Resources"`
+}
+
+type scimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Error with a SCIM detail error keyword, returned to the client as a
+// 400 Bad Request.
+type scimBadRequest struct {
+	scimType string
+	detail   string
+}
+
+func (e *scimBadRequest) Error() string {
+	return e.detail
+}
+
+func newSCIMUser(u *data.User) scimUser {
+	location := "/scim/v2/Users/" + u.ID.String()
+
+	return scimUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          u.ID.String(),
+		ExternalID:  u.ExternalID,
+		UserName:    u.Email,
+		DisplayName: u.DisplayName,
+		Active:      &u.Active,
+		Emails:      []scimEmail{{Value: u.Email, Type: "work", Primary: true}},
+		Meta: &scimMeta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			Location:     location,
+			Version:      fmt.Sprintf("W/\"%d\"", u.Version),
+		},
+	}
+}
+
+// The email of a SCIM user is its userName, unless the userName is not an
+// email address and a primary email is provided.
+func (s *scimUser) email() string {
+	if is.Email.Validate(s.UserName) == nil {
+		return s.UserName
+	}
+
+	for _, e := range s.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(s.Emails) > 0 {
+		return s.Emails[0].Value
+	}
+
+	return s.UserName
+}
+
+func (app *application) writeSCIM(w http.ResponseWriter, v any, statusCode int) error {
+	js, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	js = append(js, '\n')
+
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(statusCode)
+	w.Write(js)
+
+	return nil
+}
+
+func (app *application) writeSCIMError(w http.ResponseWriter, statusCode int, scimType, detail string) error {
+	res := response{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(statusCode),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		res["scimType"] = scimType
+	}
+
+	return app.writeSCIM(w, res, statusCode)
+}
+
+func (app *application) scimServiceProviderConfigGet(w http.ResponseWriter, r *http.Request) error {
+	res := response{
+		"schemas":        []string{scimServiceProviderConfigSchema},
+		"patch":          response{"supported": true},
+		"bulk":           response{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         response{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": response{"supported": true},
+		"sort":           response{"supported": false},
+		"etag":           response{"supported": false},
+		"authenticationSchemes": []response{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with the provisioning token",
+		}},
+	}
+
+	return app.writeSCIM(w, res, http.StatusOK)
+}
+
+func (app *application) scimUsersGet(w http.ResponseWriter, r *http.Request) error {
+	filter, err := parseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		return app.writeSCIMError(w, http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+
+	startIndex := 1
+	if v := r.URL.Query().Get("startIndex"); v != "" {
+		startIndex, err = strconv.Atoi(v)
+		if err != nil {
+			return app.writeSCIMError(w, http.StatusBadRequest, "invalidValue", "startIndex must be an integer")
+		}
+		// Values less than 1 are interpreted as 1
+		startIndex = max(startIndex, 1)
+	}
+
+	count := scimMaxResults
+	if v := r.URL.Query().Get("count"); v != "" {
+		count, err = strconv.Atoi(v)
+		if err != nil {
+			return app.writeSCIMError(w, http.StatusBadRequest, "invalidValue", "count must be an integer")
+		}
+		count = min(max(count, 0), scimMaxResults)
+	}
+
+	users, total, err := app.db.Users.List(r.Context(), filter, startIndex-1, count)
+	if err != nil {
+		return err
+	}
+
+	res := scimListResponse{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(users),
+		Resources:    make([]scimUser, 0, len(users)),
+	}
+	for _, u := range users {
+		res.Resources = append(res.Resources, newSCIMUser(u))
+	}
+
+	return app.writeSCIM(w, res, http.StatusOK)
+}
+
+func (app *application) scimUsersPost(w http.ResponseWriter, r *http.Request) error {
+	var input scimUser
+
+	err := app.readSCIMJSON(w, r, &input)
+	if err != nil {
+		return app.writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+	}
+
+	email := input.email()
+	err = validation.Validate(email, validation.Required, is.Email)
+	if err != nil {
+		return app.writeSCIMError(w, http.StatusBadRequest, "invalidValue", "userName: "+err.Error())
+	}
+
+	if input.ExternalID != "" {
+		_, total, err := app.db.Users.List(r.Context(), data.UserFilter{ExternalID: input.ExternalID}, 0, 1)
+		if err != nil {
+			return err
+		}
+		if total > 0 {
+			return app.writeSCIMError(w, http.StatusConflict, "uniqueness", "externalId is already in use")
+		}
+	}
+
+	passwordHash, err := scimPasswordHash(input.Password)
+	if err != nil {
+		return err
+	}
+
+	var user *data.User
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		var err error
+		user, err = tx.Users.New(r.Context(), email, passwordHash)
+		if err != nil {
+			return err
+		}
+
+		user.DisplayName = input.DisplayName
+		user.ExternalID = input.ExternalID
+		user.Active = input.Active == nil || *input.Active
+
+		return tx.Users.Update(r.Context(), user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			return app.writeSCIMError(w, http.StatusConflict, "uniqueness", "userName is already in use")
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			return app.writeSCIMError(w, http.StatusConflict, "uniqueness", "externalId is already in use")
+		default:
+			return err
+		}
+	}
+
+	res := newSCIMUser(user)
+	w.Header().Set("Location", res.Meta.Location)
+
+	return app.writeSCIM(w, res, http.StatusCreated)
+}
+
+func (app *application) scimUserGet(w http.ResponseWriter, r *http.Request) error {
+	user, err := app.scimGetUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeSCIMError(w, http.StatusNotFound, "", "user not found")
+		default:
+			return err
+		}
+	}
+
+	return app.writeSCIM(w, newSCIMUser(user), http.StatusOK)
+}
+
+// Replace every attribute of the user
+func (app *application) scimUserPut(w http.ResponseWriter, r *http.Request) error {
+	user, err := app.scimGetUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeSCIMError(w, http.StatusNotFound, "", "user not found")
+		default:
+			return err
+		}
+	}
+
+	var input scimUser
+
+	err = app.readSCIMJSON(w, r, &input)
+	if err != nil {
+		return app.writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+	}
+
+	email := input.email()
+	err = validation.Validate(email, validation.Required, is.Email)
+	if err != nil {
+		return app.writeSCIMError(w, http.StatusBadRequest, "invalidValue", "userName: "+err.Error())
+	}
+
+	user.Email = email
+	user.DisplayName = input.DisplayName
+	user.ExternalID = input.ExternalID
+	user.Active = input.Active == nil || *input.Active
+
+	if input.Password != "" {
+		user.PasswordHash, err = crypto.PasswordHash(input.Password)
+		if err != nil {
+			return err
+		}
+	}
+
+	return app.scimUpdateUser(w, r, user)
+}
+
+func (app *application) scimUserPatch(w http.ResponseWriter, r *http.Request) error {
+	user, err := app.scimGetUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeSCIMError(w, http.StatusNotFound, "", "user not found")
+		default:
+			return err
+		}
+	}
+
+	var input struct {
+		Schemas    []string             `json:"schemas"`
+		Operations []scimPatchOperation `json:"Operations"`
+	}
+
+	err = app.readSCIMJSON(w, r, &input)
+	if err != nil {
+		return app.writeSCIMError(w, http.StatusBadRequest, "invalidSyntax", err.Error())
+	}
+
+	for _, op := range input.Operations {
+		err = applySCIMPatch(user, op)
+		if err != nil {
+			var badRequest *scimBadRequest
+			switch {
+			case errors.As(err, &badRequest):
+				return app.writeSCIMError(w, http.StatusBadRequest, badRequest.scimType, badRequest.detail)
+			default:
+				return err
+			}
+		}
+	}
+
+	err = validation.Validate(user.Email, validation.Required, is.Email)
+	if err != nil {
+		return app.writeSCIMError(w, http.StatusBadRequest, "invalidValue", "userName: "+err.Error())
+	}
+
+	return app.scimUpdateUser(w, r, user)
+}
+
+func (app *application) scimUserDelete(w http.ResponseWriter, r *http.Request) error {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		return app.writeSCIMError(w, http.StatusNotFound, "", "user not found")
+	}
+
+	err = app.db.Users.Delete(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeSCIMError(w, http.StatusNotFound, "", "user not found")
+		default:
+			return err
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (app *application) scimGetUser(r *http.Request) (*data.User, error) {
+	id, err := uuid.FromString(chi.URLParam(r, "id"))
+	if err != nil {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return app.db.Users.Get(r.Context(), id)
+}
+
+// Save the user and sign out every client of a deactivated user
+func (app *application) scimUpdateUser(w http.ResponseWriter, r *http.Request, user *data.User) error {
+	err := app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+
+		if user.Active {
+			return nil
+		}
+
+		return tx.AuthenticationTokens.Purge(r.Context(), user.ID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateEmail):
+			return app.writeSCIMError(w, http.StatusConflict, "uniqueness", "userName is already in use")
+		case errors.Is(err, data.ErrDuplicateExternalID):
+			return app.writeSCIMError(w, http.StatusConflict, "uniqueness", "externalId is already in use")
+		case errors.Is(err, data.ErrEditConflict):
+			return app.writeSCIMError(w, http.StatusConflict, "", "user was modified concurrently")
+		default:
+			return err
+		}
+	}
+
+	return app.writeSCIM(w, newSCIMUser(user), http.StatusOK)
+}
+
+// Hash the provisioned password. Users provisioned without one get a
+// random password and must reset it before logging in.
+func scimPasswordHash(password string) ([]byte, error) {
+	if password == "" {
+		var err error
+		password, err = crypto.GeneratePlaintextToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return crypto.PasswordHash(password)
+}
+
+// Apply a single PATCH operation to the user
+func applySCIMPatch(user *data.User, op scimPatchOperation) error {
+	operation := strings.ToLower(op.Op)
+	if operation != "add" && operation != "replace" && operation != "remove" {
+		return &scimBadRequest{"invalidSyntax", fmt.Sprintf("unsupported op %q", op.Op)}
+	}
+
+	// Without a path the value holds the attributes to modify
+	if op.Path == "" {
+		if operation == "remove" {
+			return &scimBadRequest{"noTarget", "remove requires a path"}
+		}
+
+		var attrs map[string]json.RawMessage
+		err := json.Unmarshal(op.Value, &attrs)
+		if err != nil {
+			return &scimBadRequest{"invalidValue", "value must be an object when path is omitted"}
+		}
+
+		for path, value := range attrs {
+			err = applySCIMPatch(user, scimPatchOperation{Op: op.Op, Path: path, Value: value})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	path := strings.ToLower(op.Path)
+	if strings.HasPrefix(path, "emails") {
+		path = "emails"
+	}
+
+	if operation == "remove" {
+		switch path {
+		case "displayname":
+			user.DisplayName = ""
+		case "externalid":
+			user.ExternalID = ""
+		case "username", "emails", "active", "password":
+			return &scimBadRequest{"mutability", fmt.Sprintf("%s can't be removed", op.Path)}
+		default:
+			return &scimBadRequest{"invalidPath", fmt.Sprintf("unsupported path %q", op.Path)}
+		}
+
+		return nil
+	}
+
+	switch path {
+	case "username":
+		return unmarshalSCIMValue(op, &user.Email)
+	case "displayname":
+		return unmarshalSCIMValue(op, &user.DisplayName)
+	case "externalid":
+		return unmarshalSCIMValue(op, &user.ExternalID)
+	case "active":
+		// Some identity providers send booleans as strings
+		var active any
+		err := unmarshalSCIMValue(op, &active)
+		if err != nil {
+			return err
+		}
+		switch v := active.(type) {
+		case bool:
+			user.Active = v
+		case string:
+			user.Active, err = strconv.ParseBool(v)
+			if err != nil {
+				return &scimBadRequest{"invalidValue", "active must be a boolean"}
+			}
+		default:
+			return &scimBadRequest{"invalidValue", "active must be a boolean"}
+		}
+	case "emails":
+		// Either the value of a filtered email or a list of emails
+		var email string
+		if json.Unmarshal(op.Value, &email) == nil {
+			user.Email = email
+			return nil
+		}
+
+		var emails []scimEmail
+		err := unmarshalSCIMValue(op, &emails)
+		if err != nil {
+			return err
+		}
+		s := scimUser{UserName: user.Email, Emails: emails}
+		for _, e := range emails {
+			if e.Primary {
+				s.UserName = e.Value
+			}
+		}
+		user.Email = s.email()
+	case "password":
+		var password string
+		err := unmarshalSCIMValue(op, &password)
+		if err != nil {
+			return err
+		}
+		user.PasswordHash, err = crypto.PasswordHash(password)
+		if err != nil {
+			return err
+		}
+	default:
+		return &scimBadRequest{"invalidPath", fmt.Sprintf("unsupported path %q", op.Path)}
+	}
+
+	return nil
+}
+
+func unmarshalSCIMValue(op scimPatchOperation, dst any) error {
+	err := json.Unmarshal(op.Value, dst)
+	if err != nil {
+		return &scimBadRequest{"invalidValue", fmt.Sprintf("invalid value for %s", op.Path)}
+	}
+
+	return nil
+}
+
+type scimFilterToken struct {
+	text   string
+	quoted bool
+}
+
+// Parse a SCIM filter into a user filter. Supports the "eq" operator on
+// userName, emails, externalId, displayName and active, combined with "and".
+func parseSCIMFilter(filter string) (data.UserFilter, error) {
+	var f data.UserFilter
+
+	tokens, err := tokenizeSCIMFilter(filter)
+	if err != nil {
+		return f, err
+	}
+
+	for i := 0; i < len(tokens); i += 4 {
+		if i > 0 && (tokens[i-1].quoted || !strings.EqualFold(tokens[i-1].text, "and")) {
+			return f, fmt.Errorf("unsupported logical operator %q", tokens[i-1].text)
+		}
+		if len(tokens) < i+3 {
+			return f, errors.New("incomplete filter expression")
+		}
+
+		attr, op, value := tokens[i], tokens[i+1], tokens[i+2]
+		if !strings.EqualFold(op.text, "eq") {
+			return f, fmt.Errorf("unsupported operator %q", op.text)
+		}
+
+		switch strings.ToLower(attr.text) {
+		case "username", "emails", "emails.value":
+			f.Email = value.text
+		case "externalid":
+			f.ExternalID = value.text
+		case "displayname":
+			f.DisplayName = value.text
+		case "active":
+			active, err := strconv.ParseBool(value.text)
+			if err != nil || value.quoted {
+				return f, errors.New("active must be compared to a boolean")
+			}
+			f.Active = &active
+		default:
+			return f, fmt.Errorf("unsupported attribute %q", attr.text)
+		}
+	}
+
+	if len(tokens)%4 == 0 && len(tokens) > 0 {
+		return f, errors.New("incomplete filter expression")
+	}
+
+	return f, nil
+}
+
+func tokenizeSCIMFilter(filter string) ([]scimFilterToken, error) {
+	var tokens []scimFilterToken
+
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		switch {
+		case unicode.IsSpace(runes[i]):
+			i++
+		case runes[i] == '"':
+			// Quoted values are JSON strings
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return nil, errors.New("unterminated string")
+			}
+
+			var text string
+			err := json.Unmarshal([]byte(string(runes[i:j+1])), &text)
+			if err != nil {
+				return nil, errors.New("invalid string")
+			}
+
+			tokens = append(tokens, scimFilterToken{text, true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) {
+				j++
+			}
+
+			tokens = append(tokens, scimFilterToken{string(runes[i:j]), false})
+			i = j
+		}
+	}
+
+	return tokens, nil
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSCIMToken = "scim_secret"
+
+func TestParseSCIMFilter(t *testing.T) {
+	active, inactive := true, false
+
+	tests := []struct {
+		name   string
+		filter string
+		want   data.UserFilter
+		err    string
+	}{
+		{"empty", "", data.UserFilter{}, ""},
+		{"whitespace", " \t ", data.UserFilter{}, ""},
+		{"userName", `userName eq "alice@example.com"`, data.UserFilter{Email: "alice@example.com"}, ""},
+		{"emails", `emails eq "alice@example.com"`, data.UserFilter{Email: "alice@example.com"}, ""},
+		{"emails value", `emails.value eq "alice@example.com"`, data.UserFilter{Email: "alice@example.com"}, ""},
+		{"externalId", `externalId eq "ext-1"`, data.UserFilter{ExternalID: "ext-1"}, ""},
+		{"displayName", `displayName eq "Alice"`, data.UserFilter{DisplayName: "Alice"}, ""},
+		{"active", `active eq true`, data.UserFilter{Active: &active}, ""},
+		{"inactive", `active eq false`, data.UserFilter{Active: &inactive}, ""},
+		{"case insensitive", `USERNAME EQ "alice@example.com" AND Active Eq TRUE`, data.UserFilter{Email: "alice@example.com", Active: &active}, ""},
+		{"and", `userName eq "alice@example.com" and externalId eq "ext-1" and active eq true`, data.UserFilter{Email: "alice@example.com", ExternalID: "ext-1", Active: &active}, ""},
+		{"last value wins", `userName eq "alice@example.com" and userName eq "bob@example.com"`, data.UserFilter{Email: "bob@example.com"}, ""},
+
+		// Quoting
+		{"quoted spaces", `displayName eq "Alice  Smith"`, data.UserFilter{DisplayName: "Alice  Smith"}, ""},
+		{"escaped quote", `displayName eq "Alice \"Al\" Smith"`, data.UserFilter{DisplayName: `Alice "Al" Smith`}, ""},
+		{"escaped backslash", `displayName eq "Alice\\"`, data.UserFilter{DisplayName: `Alice\`}, ""},
+		{"unicode escape", `displayName eq "\u00c5sa"`, data.UserFilter{DisplayName: "Åsa"}, ""},
+		{"quoted keyword", `displayName eq "and"`, data.UserFilter{DisplayName: "and"}, ""},
+		{"unquoted value", `externalId eq ext-1`, data.UserFilter{ExternalID: "ext-1"}, ""},
+		{"unterminated string", `userName eq "alice@example.com`, data.UserFilter{}, "unterminated string"},
+		{"escaped end quote", `userName eq "alice\"`, data.UserFilter{}, "unterminated string"},
+		{"invalid escape", `userName eq "\x"`, data.UserFilter{}, "invalid string"},
+		{"quoted logical operator", `userName eq "a@example.com" "and" active eq true`, data.UserFilter{}, `unsupported logical operator "and"`},
+		{"quoted boolean", `active eq "true"`, data.UserFilter{}, "active must be compared to a boolean"},
+
+		// Only "and" is supported, so there is no precedence to resolve
+		{"or", `userName eq "a@example.com" or userName eq "b@example.com"`, data.UserFilter{}, `unsupported logical operator "or"`},
+		{"and before or", `active eq true and userName eq "a@example.com" or userName eq "b@example.com"`, data.UserFilter{}, `unsupported logical operator "or"`},
+		{"or before and", `userName eq "a@example.com" or userName eq "b@example.com" and active eq true`, data.UserFilter{}, `unsupported logical operator "or"`},
+		{"grouping", `(userName eq "a@example.com")`, data.UserFilter{}, `unsupported attribute "(userName"`},
+		{"not", `not userName eq "a@example.com"`, data.UserFilter{}, `unsupported operator "userName"`},
+
+		// Only "eq" is supported
+		{"pr", `externalId pr`, data.UserFilter{}, "incomplete filter expression"},
+		{"pr and", `externalId pr and active eq true`, data.UserFilter{}, `unsupported operator "pr"`},
+		{"ne", `userName ne "a@example.com"`, data.UserFilter{}, `unsupported operator "ne"`},
+		{"co", `userName co "example.com"`, data.UserFilter{}, `unsupported operator "co"`},
+
+		// Invalid input
+		{"attribute only", `userName`, data.UserFilter{}, "incomplete filter expression"},
+		{"trailing and", `userName eq "a@example.com" and`, data.UserFilter{}, "incomplete filter expression"},
+		{"trailing expression", `userName eq "a@example.com" and active`, data.UserFilter{}, "incomplete filter expression"},
+		{"unknown attribute", `name.givenName eq "Alice"`, data.UserFilter{}, `unsupported attribute "name.givenName"`},
+		{"not a boolean", `active eq yes`, data.UserFilter{}, "active must be compared to a boolean"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseSCIMFilter(tt.filter)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f)
+		})
+	}
+}
+
+func TestApplySCIMPatch(t *testing.T) {
+	type fields struct {
+		email       string
+		displayName string
+		externalID  string
+		active      bool
+	}
+
+	initial := fields{"alice@example.com", "Alice", "ext-1", true}
+
+	tests := []struct {
+		name     string
+		op       string
+		path     string
+		value    string
+		want     fields
+		scimType string
+	}{
+		// With a path
+		{"add displayName", "add", "displayName", `"Alice Smith"`, fields{"alice@example.com", "Alice Smith", "ext-1", true}, ""},
+		{"replace userName", "replace", "userName", `"bob@example.com"`, fields{"bob@example.com", "Alice", "ext-1", true}, ""},
+		{"replace externalId", "replace", "externalId", `"ext-2"`, fields{"alice@example.com", "Alice", "ext-2", true}, ""},
+		{"replace active", "replace", "active", `false`, fields{"alice@example.com", "Alice", "ext-1", false}, ""},
+		{"replace active string", "Replace", "active", `"False"`, fields{"alice@example.com", "Alice", "ext-1", false}, ""},
+		{"replace filtered email", "replace", `emails[type eq "work"].value`, `"bob@example.com"`, fields{"bob@example.com", "Alice", "ext-1", true}, ""},
+		{"replace emails", "replace", "emails", `[{"value": "carol@example.com"}, {"value": "bob@example.com", "primary": true}]`, fields{"bob@example.com", "Alice", "ext-1", true}, ""},
+		// Without a primary email, the userName remains the email
+		{"replace emails without primary", "replace", "emails", `[{"value": "carol@example.com"}]`, initial, ""},
+		{"path case insensitive", "REPLACE", "DISPLAYNAME", `"Al"`, fields{"alice@example.com", "Al", "ext-1", true}, ""},
+		{"remove displayName", "remove", "displayName", ``, fields{"alice@example.com", "", "ext-1", true}, ""},
+		{"remove externalId", "remove", "externalId", ``, fields{"alice@example.com", "Alice", "", true}, ""},
+
+		// Without a path
+		{"add attributes", "add", "", `{"displayName": "Alice Smith", "externalId": "ext-2"}`, fields{"alice@example.com", "Alice Smith", "ext-2", true}, ""},
+		{"replace attributes", "replace", "", `{"userName": "bob@example.com", "active": false}`, fields{"bob@example.com", "Alice", "ext-1", false}, ""},
+		{"replace nothing", "replace", "", `{}`, initial, ""},
+
+		// Errors
+		{"unsupported op", "move", "displayName", `"Al"`, initial, "invalidSyntax"},
+		{"remove without path", "remove", "", ``, initial, "noTarget"},
+		{"value not an object", "replace", "", `"Alice"`, initial, "invalidValue"},
+		{"unsupported path", "replace", "name.givenName", `"Alice"`, initial, "invalidPath"},
+		{"unsupported nested path", "add", "", `{"nickName": "Al"}`, initial, "invalidPath"},
+		{"remove unsupported path", "remove", "nickName", ``, initial, "invalidPath"},
+		{"remove userName", "remove", "userName", ``, initial, "mutability"},
+		{"remove emails", "remove", "emails", ``, initial, "mutability"},
+		{"remove active", "remove", "active", ``, initial, "mutability"},
+		{"remove password", "remove", "password", ``, initial, "mutability"},
+		{"invalid value", "replace", "displayName", `1`, initial, "invalidValue"},
+		{"active not a boolean", "replace", "active", `"yes"`, initial, "invalidValue"},
+		{"active a number", "replace", "active", `1`, initial, "invalidValue"},
+		{"invalid emails", "replace", "emails", `{"value": "bob@example.com"}`, initial, "invalidValue"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &data.User{
+				Email:       initial.email,
+				DisplayName: initial.displayName,
+				ExternalID:  initial.externalID,
+				Active:      initial.active,
+			}
+
+			op := scimPatchOperation{Op: tt.op, Path: tt.path}
+			if tt.value != "" {
+				op.Value = json.RawMessage(tt.value)
+			}
+
+			err := applySCIMPatch(user, op)
+			if tt.scimType != "" {
+				var badRequest *scimBadRequest
+				require.True(t, errors.As(err, &badRequest), "got %v", err)
+				assert.Equal(t, tt.scimType, badRequest.scimType)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, fields{user.Email, user.DisplayName, user.ExternalID, user.Active})
+		})
+	}
+
+	t.Run("password", func(t *testing.T) {
+		for _, op := range []scimPatchOperation{
+			{Op: "replace", Path: "password", Value: json.RawMessage(`"new_password"`)},
+			{Op: "add", Value: json.RawMessage(`{"password": "new_password"}`)},
+		} {
+			user := &data.User{}
+
+			err := applySCIMPatch(user, op)
+			require.NoError(t, err)
+
+			match, err := crypto.ComparePasswordAndHash("new_password", user.PasswordHash)
+			require.NoError(t, err)
+			assert.True(t, match)
+		}
+	})
+}
+
+// Test server with the SCIM routes, which are only registered when a
+// token is configured
+func newSCIMTestServer(t *testing.T) *testServer {
+	ts := newTestServer(t, nil)
+	ts.app.config.API.SCIMToken = testSCIMToken
+	ts.Config.Handler = ts.app.routes()
+
+	return ts
+}
+
+func doSCIM(t *testing.T, ts *testServer, method, path, body string) (*http.Response, map[string]any) {
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testSCIMToken)
+	if body != "" {
+		req.Header.Set("Content-Type", scimContentType)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	var v map[string]any
+	if res.StatusCode != http.StatusNoContent {
+		assert.Equal(t, scimContentType, res.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&v))
+	}
+
+	return res, v
+}
+
+func TestSCIMUsers(t *testing.T) {
+	ts := newSCIMTestServer(t)
+
+	res, alice := doSCIM(t, ts, http.MethodPost, "/scim/v2/Users", `{
+		"schemas": ["`+scimUserSchema+`"],
+		"userName": "alice@example.com",
+		"externalId": "ext-alice",
+		"displayName": "Alice"
+	}`)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	aliceID := alice["id"].(string)
+	assert.Equal(t, "/scim/v2/Users/"+aliceID, res.Header.Get("Location"))
+	assert.Equal(t, true, alice["active"])
+
+	res, bob := doSCIM(t, ts, http.MethodPost, "/scim/v2/Users", `{
+		"userName": "bob",
+		"externalId": "ext-bob",
+		"emails": [{"value": "bob@example.com", "primary": true}]
+	}`)
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	bobID := bob["id"].(string)
+	assert.Equal(t, "bob@example.com", bob["userName"])
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		status   int
+		scimType string
+	}{
+		{"service provider config", http.MethodGet, "/scim/v2/ServiceProviderConfig", "", http.StatusOK, ""},
+		{"list", http.MethodGet, "/scim/v2/Users", "", http.StatusOK, ""},
+		{"list filter", http.MethodGet, `/scim/v2/Users?filter=userName+eq+%22alice@example.com%22`, "", http.StatusOK, ""},
+		{"list invalid filter", http.MethodGet, `/scim/v2/Users?filter=userName+sw+%22alice%22`, "", http.StatusBadRequest, "invalidFilter"},
+		{"list invalid startIndex", http.MethodGet, "/scim/v2/Users?startIndex=first", "", http.StatusBadRequest, "invalidValue"},
+		{"list invalid count", http.MethodGet, "/scim/v2/Users?count=all", "", http.StatusBadRequest, "invalidValue"},
+		{"get", http.MethodGet, "/scim/v2/Users/" + aliceID, "", http.StatusOK, ""},
+		{"get unknown", http.MethodGet, "/scim/v2/Users/00000000-0000-4000-8000-000000000000", "", http.StatusNotFound, ""},
+		{"get invalid id", http.MethodGet, "/scim/v2/Users/alice", "", http.StatusNotFound, ""},
+
+		{"create invalid json", http.MethodPost, "/scim/v2/Users", `{"userName": `, http.StatusBadRequest, "invalidSyntax"},
+		{"create invalid userName", http.MethodPost, "/scim/v2/Users", `{"userName": "carol"}`, http.StatusBadRequest, "invalidValue"},
+		{"create duplicate email", http.MethodPost, "/scim/v2/Users", `{"userName": "ALICE@example.com"}`, http.StatusConflict, "uniqueness"},
+		{"create duplicate externalId", http.MethodPost, "/scim/v2/Users", `{"userName": "carol@example.com", "externalId": "ext-alice"}`, http.StatusConflict, "uniqueness"},
+
+		{"replace", http.MethodPut, "/scim/v2/Users/" + bobID, `{"userName": "bob@example.com", "externalId": "ext-bob", "displayName": "Bob"}`, http.StatusOK, ""},
+		{"replace unknown", http.MethodPut, "/scim/v2/Users/00000000-0000-4000-8000-000000000000", `{"userName": "carol@example.com"}`, http.StatusNotFound, ""},
+		{"replace invalid userName", http.MethodPut, "/scim/v2/Users/" + bobID, `{"userName": ""}`, http.StatusBadRequest, "invalidValue"},
+		{"replace duplicate email", http.MethodPut, "/scim/v2/Users/" + bobID, `{"userName": "alice@example.com", "externalId": "ext-bob"}`, http.StatusConflict, "uniqueness"},
+		{"replace duplicate externalId", http.MethodPut, "/scim/v2/Users/" + bobID, `{"userName": "bob@example.com", "externalId": "ext-alice"}`, http.StatusConflict, "uniqueness"},
+
+		{"patch", http.MethodPatch, "/scim/v2/Users/" + bobID, `{"schemas": ["` + scimPatchOpSchema + `"], "Operations": [{"op": "replace", "path": "displayName", "value": "Robert"}]}`, http.StatusOK, ""},
+		{"patch unknown", http.MethodPatch, "/scim/v2/Users/00000000-0000-4000-8000-000000000000", `{"Operations": []}`, http.StatusNotFound, ""},
+		{"patch invalid json", http.MethodPatch, "/scim/v2/Users/" + bobID, `{"Operations": {}}`, http.StatusBadRequest, "invalidSyntax"},
+		{"patch invalid path", http.MethodPatch, "/scim/v2/Users/" + bobID, `{"Operations": [{"op": "replace", "path": "nickName", "value": "Bobby"}]}`, http.StatusBadRequest, "invalidPath"},
+		{"patch invalid email", http.MethodPatch, "/scim/v2/Users/" + bobID, `{"Operations": [{"op": "replace", "path": "userName", "value": "bob"}]}`, http.StatusBadRequest, "invalidValue"},
+		{"patch duplicate email", http.MethodPatch, "/scim/v2/Users/" + bobID, `{"Operations": [{"op": "replace", "path": "userName", "value": "alice@example.com"}]}`, http.StatusConflict, "uniqueness"},
+		{"patch duplicate externalId", http.MethodPatch, "/scim/v2/Users/" + bobID, `{"Operations": [{"op": "replace", "value": {"externalId": "ext-alice"}}]}`, http.StatusConflict, "uniqueness"},
+
+		{"delete unknown", http.MethodDelete, "/scim/v2/Users/00000000-0000-4000-8000-000000000000", "", http.StatusNotFound, ""},
+		{"delete invalid id", http.MethodDelete, "/scim/v2/Users/alice", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, body := doSCIM(t, ts, tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.status, res.StatusCode)
+
+			if tt.status >= 400 {
+				assert.Equal(t, []any{scimErrorSchema}, body["schemas"])
+				if tt.scimType == "" {
+					assert.NotContains(t, body, "scimType")
+				} else {
+					assert.Equal(t, tt.scimType, body["scimType"], body["detail"])
+				}
+			}
+		})
+	}
+
+	t.Run("list filter results", func(t *testing.T) {
+		_, body := doSCIM(t, ts, http.MethodGet, `/scim/v2/Users?filter=externalId+eq+%22ext-bob%22`, "")
+		assert.Equal(t, float64(1), body["totalResults"])
+		resources := body["Resources"].([]any)
+		require.Len(t, resources, 1)
+		assert.Equal(t, bobID, resources[0].(map[string]any)["id"])
+	})
+
+	t.Run("conflicts leave users unchanged", func(t *testing.T) {
+		_, body := doSCIM(t, ts, http.MethodGet, "/scim/v2/Users/"+bobID, "")
+		assert.Equal(t, "bob@example.com", body["userName"])
+		assert.Equal(t, "ext-bob", body["externalId"])
+		assert.Equal(t, "Robert", body["displayName"])
+	})
+
+	t.Run("deactivate", func(t *testing.T) {
+		res, body := doSCIM(t, ts, http.MethodPatch, "/scim/v2/Users/"+aliceID, `{"Operations": [{"op": "replace", "path": "active", "value": "False"}]}`)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, false, body["active"])
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		res, _ := doSCIM(t, ts, http.MethodDelete, "/scim/v2/Users/"+aliceID, "")
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+		res, _ = doSCIM(t, ts, http.MethodGet, "/scim/v2/Users/"+aliceID, "")
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
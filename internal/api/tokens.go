@@ -0,0 +1,431 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/micahco/mono/internal/authn"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/internal/middleware"
+	"github.com/micahco/mono/ui/emails"
+)
+
+const (
+	emailVerificicationMessage      = "A verification email has been sent. Please check your inbox."
+	invalidCredentialsMessage       = "invalid credentials"
+	reauthenticationRequiredMessage = "recent authentication required. confirm your password at PUT /v1/tokens/authentication"
+	throttledMessage                = "too many verification emails. please try again later"
+)
+
+// Email address to mail a verification token to
+type verificationInput struct {
+	Email string `json:"email"`
+}
+
+// Create a verification token with registration scope and
+// mail it to the provided email address.
+func (app *application) tokensVerificaitonRegistrationPost(w http.ResponseWriter, r *http.Request) error {
+	var input verificationInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.Email, isRequired, isEmail),
+	)
+	if err != nil {
+		return err
+	}
+
+	// This will be the consistent message. Even if a user
+	// already exists with this email, send this message.
+	res := response{"message": emailVerificicationMessage}
+
+	// Check if user with email already exists
+	exists, err := app.db.Users.ExistsWithEmail(r.Context(), input.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// User with email already exists. Send the
+		// consistent respone message.
+		return app.writeJSON(w, res, http.StatusOK)
+	}
+
+	// Check if a verification token has already been created recently
+	exists, err = app.db.VerificationTokens.Exists(r.Context(), data.ScopeRegistration, input.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// A verfication token has already been sent.
+		// Send the same message.
+		return app.writeJSON(w, res, http.StatusOK)
+	}
+
+	// Create new token for user
+	token, err := crypto.NewToken(data.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		// Limit the verification emails that anyone can have sent. Only
+		// the emails that are queued count towards the limits.
+		err := tx.Throttle.Acquire(r.Context(), input.Email, middleware.ClientKey(r), app.config.Throttle)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.New(r.Context(), token.Hash, token.Expiry, data.ScopeRegistration, input.Email)
+		if err != nil {
+			return err
+		}
+
+		// Mail the plaintext token to the user's email address.
+		component := emails.Registration(token.Plaintext)
+		locale := i18n.Match(r.Header.Get("Accept-Language"))
+		return app.sendMail(r.Context(), tx, locale, input.Email, "Registration", component)
+	})
+	if errors.Is(err, data.ErrThrottled) {
+		return app.writeProblem(w, r, rateLimitProblem(throttledMessage))
+	}
+	// Mail isn't sent to addresses that bounced, but the response is the same
+	if err != nil && !errors.Is(err, data.ErrSuppressedAddress) {
+		return err
+	}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
+
+func (app *application) tokensVerificaitonEmailChangePost(w http.ResponseWriter, r *http.Request) error {
+	var input verificationInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.Email, isRequired, isEmail),
+	)
+	if err != nil {
+		return err
+	}
+
+	// This will be the consistent message. Even if a user
+	// already exists with this email, send this message.
+	res := response{"message": emailVerificicationMessage}
+
+	// Check if user with email already exists
+	exists, err := app.db.Users.ExistsWithEmail(r.Context(), input.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// User with email already exists. Send the
+		// consistent respone message.
+		return app.writeJSON(w, res, http.StatusOK)
+	}
+
+	// Check if a verification token has already been created recently
+	exists, err = app.db.VerificationTokens.Exists(r.Context(), data.ScopeEmailChange, input.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// Recent verification sent, don't mail another, just
+		// send the same message
+		return app.writeJSON(w, res, http.StatusOK)
+	}
+
+	// Create verification token for user with new email address
+	token, err := crypto.NewToken(data.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		// Limit the verification emails that anyone can have sent. Only
+		// the emails that are queued count towards the limits.
+		err := tx.Throttle.Acquire(r.Context(), input.Email, middleware.ClientKey(r), app.config.Throttle)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.New(r.Context(), token.Hash, token.Expiry, data.ScopeEmailChange, input.Email)
+		if err != nil {
+			return err
+		}
+
+		// Mail the plaintext token to the new email address
+		component := emails.EmailChange(token.Plaintext)
+		user := app.contextGetUser(r.Context())
+		return app.sendMail(r.Context(), tx, user.Locale, input.Email, "Email Verification", component)
+	})
+	if errors.Is(err, data.ErrThrottled) {
+		return app.writeProblem(w, r, rateLimitProblem(throttledMessage))
+	}
+	// Mail isn't sent to addresses that bounced, but the response is the same
+	if err != nil && !errors.Is(err, data.ErrSuppressedAddress) {
+		return err
+	}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
+
+func (app *application) tokensVerificaitonPasswordResetPost(w http.ResponseWriter, r *http.Request) error {
+	var input verificationInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.Email, isRequired, isEmail),
+	)
+	if err != nil {
+		return err
+	}
+
+	// This will be the consistent message. Even if a user
+	// already exists with this email, send this message.
+	res := response{"message": emailVerificicationMessage}
+
+	// Check if user with email exists
+	user, err := app.db.Users.GetWithEmail(r.Context(), input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			// User with email does not exist. Still send the same
+			// message.
+			return app.writeJSON(w, res, http.StatusOK)
+		default:
+			return err
+		}
+	}
+
+	// Check if a verification token has already been created recently
+	exists, err := app.db.VerificationTokens.Exists(r.Context(), data.ScopePasswordReset, input.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// Recent verification sent, don't mail another
+		return app.writeJSON(w, res, http.StatusOK)
+	}
+
+	token, err := crypto.NewToken(data.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		// Limit the verification emails that anyone can have sent. Only
+		// the emails that are queued count towards the limits.
+		err := tx.Throttle.Acquire(r.Context(), input.Email, middleware.ClientKey(r), app.config.Throttle)
+		if err != nil {
+			return err
+		}
+
+		// Create verification token for user with email address
+		err = tx.VerificationTokens.New(r.Context(), token.Hash, token.Expiry, data.ScopePasswordReset, input.Email)
+		if err != nil {
+			return err
+		}
+
+		// Mail the plaintext token to the provided email address
+		component := emails.PasswordReset(token.Plaintext)
+		return app.sendMail(r.Context(), tx, user.Locale, input.Email, "Password Reset", component)
+	})
+	if errors.Is(err, data.ErrThrottled) {
+		return app.writeProblem(w, r, rateLimitProblem(throttledMessage))
+	}
+	// Mail isn't sent to addresses that bounced, but the response is the same
+	if err != nil && !errors.Is(err, data.ErrSuppressedAddress) {
+		return err
+	}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
+
+type credentialsInput struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func (app *application) tokensAuthenticationPost(w http.ResponseWriter, r *http.Request) error {
+	var input credentialsInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.Email, isRequired, isEmail),
+		validation.Field(&input.Password, isRequired, passwordLength),
+	)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.authn.Authenticate(r.Context(), input.Email, input.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, authn.ErrInvalidCredentials):
+			return app.writeProblem(w, r, unauthorizedProblem(invalidCredentialsMessage))
+		default:
+			return err
+		}
+	}
+
+	if !user.Active {
+		return app.writeProblem(w, r, forbiddenProblem(accountDisabledMessage))
+	}
+	if user.Locked {
+		return app.writeProblem(w, r, forbiddenProblem(accountLockedMessage))
+	}
+
+	// Create authentication token for user with new email address
+	token, err := crypto.NewToken(data.AuthenticationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	ip := middleware.ClientIP(r)
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.AuthenticationTokens.New(r.Context(), token.Hash, token.Expiry, user.ID)
+		if err != nil {
+			return err
+		}
+
+		known, err := tx.Devices.Remember(r.Context(), user.ID, ip, r.UserAgent())
+		if err != nil || known {
+			return err
+		}
+
+		// Warn the user about logins from devices they haven't used before
+		return app.notifyNewDevice(r.Context(), tx, user, ip, r.UserAgent(), crypto.EncodeTokenHash(token.Hash))
+	})
+	if err != nil {
+		return err
+	}
+
+	res := response{"authentication_token": token.Plaintext}
+
+	return app.writeJSON(w, res, http.StatusCreated)
+}
+
+// Mail the user about a login from a new device along with the session
+// identifier that revokes it.
+func (app *application) notifyNewDevice(ctx context.Context, db *data.DB, user *data.User, ip, userAgent, sessionID string) error {
+	when := time.Now().UTC().Format(time.RFC1123)
+	where := fmt.Sprintf("%s (%s)", app.locator.Locate(ip), ip)
+
+	component := emails.NewDevice(when, where, userAgent, sessionID)
+	return app.sendMail(ctx, db, user.Locale, user.Email, "New Login", component)
+}
+
+type sessionInput struct {
+	Session string `json:"session"`
+}
+
+// Revoke the authentication token identified by the session identifier
+// from a new device email.
+func (app *application) tokensAuthenticationRevokePost(w http.ResponseWriter, r *http.Request) error {
+	var input sessionInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.Session, isRequired),
+	)
+	if err != nil {
+		return err
+	}
+
+	tokenHash, err := crypto.DecodeTokenHash(input.Session)
+	if err != nil {
+		return app.writeProblem(w, r, unauthorizedProblem(""))
+	}
+
+	err = app.db.AuthenticationTokens.Delete(r.Context(), tokenHash)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, notFoundProblem())
+		default:
+			return err
+		}
+	}
+
+	res := response{"message": "the session was signed out. reset your password if you don't recognize it"}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
+
+type passwordInput struct {
+	Password string `json:"password"`
+}
+
+// Confirm the password of the authenticated user to unlock sensitive
+// operations for the current token.
+func (app *application) tokensAuthenticationPut(w http.ResponseWriter, r *http.Request) error {
+	var input passwordInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.Password, isRequired, passwordLength),
+	)
+	if err != nil {
+		return err
+	}
+
+	user := app.contextGetUser(r.Context())
+
+	confirmed, err := app.authn.Authenticate(r.Context(), user.Email, input.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, authn.ErrInvalidCredentials):
+			return app.writeProblem(w, r, unauthorizedProblem(invalidCredentialsMessage))
+		default:
+			return err
+		}
+	}
+	if confirmed.ID != user.ID {
+		return app.writeProblem(w, r, unauthorizedProblem(invalidCredentialsMessage))
+	}
+
+	tokenHash := app.contextGetAuthenticationToken(r.Context())
+
+	err = app.db.AuthenticationTokens.Reauthenticate(r.Context(), tokenHash)
+	if err != nil {
+		return err
+	}
+
+	at, err := app.db.AuthenticationTokens.Get(r.Context(), tokenHash)
+	if err != nil {
+		return err
+	}
+
+	res := response{"authentication_token": at}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
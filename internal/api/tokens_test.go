@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	ts := newTestServer(t, nil)
+	ts.app.config.Throttle = data.ThrottleLimits{PerIP: 2}
+	ts.app.config.TrustedProxies = []string{"10.0.0.1"}
+	h := ts.app.routes()
+
+	requestRegistration := func(email, remoteAddr, forwardedFor string) int {
+		body := strings.NewReader(`{"email": "` + email + `"}`)
+		r := httptest.NewRequest(http.MethodPost, "/v1/tokens/verification/registration", body)
+		r.Header.Set("Content-Type", "application/json")
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, requestRegistration("a@example.com", "203.0.113.7:1234", ""))
+	// No email is sent while the token of the first is valid, so it isn't
+	// counted
+	assert.Equal(t, http.StatusOK, requestRegistration("a@example.com", "203.0.113.7:1234", ""))
+	assert.Equal(t, http.StatusOK, requestRegistration("b@example.com", "203.0.113.7:1235", ""))
+	assert.Equal(t, http.StatusTooManyRequests, requestRegistration("c@example.com", "203.0.113.7:1236", ""))
+
+	// Forwarding headers of clients that aren't trusted proxies are ignored
+	assert.Equal(t, http.StatusTooManyRequests, requestRegistration("d@example.com", "203.0.113.7:1237", "198.51.100.1"))
+
+	// Other clients are counted separately
+	assert.Equal(t, http.StatusOK, requestRegistration("c@example.com", "198.51.100.1:1234", ""))
+
+	// Clients behind a trusted proxy are counted separately
+	assert.Equal(t, http.StatusOK, requestRegistration("e@example.com", "10.0.0.1:1234", "198.51.100.2"))
+	assert.Equal(t, http.StatusOK, requestRegistration("f@example.com", "10.0.0.1:1234", "198.51.100.3"))
+	assert.Equal(t, http.StatusTooManyRequests, requestRegistration("g@example.com", "10.0.0.1:1234", "203.0.113.7"))
+}
@@ -0,0 +1,378 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/ui/emails"
+)
+
+const (
+	expiredTokenMessage    = "expired token"
+	accountLockedMessage   = "account locked. reset your password to unlock it"
+	accountDisabledMessage = "account disabled"
+)
+
+type newUserInput struct {
+	Email          string `json:"email"`
+	Password       string `json:"password"`
+	PlaintextToken string `json:"token"`
+	Locale         string `json:"locale,omitempty"`
+}
+
+// Create new user with email and password if provided token
+// matches verification.
+func (app *application) usersPost(w http.ResponseWriter, r *http.Request) error {
+	var input newUserInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.Email, isRequired, isEmail),
+		validation.Field(&input.Password, isRequired, passwordLength),
+		validation.Field(&input.PlaintextToken, isRequired),
+		validation.Field(&input.Locale, supportedLocale),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Default to the language of the client
+	if input.Locale == "" {
+		input.Locale = i18n.Match(r.Header.Get("Accept-Language"))
+	}
+
+	tokenHash := crypto.TokenHash(input.PlaintextToken)
+
+	passwordHash, err := crypto.PasswordHash(input.Password)
+	if err != nil {
+		return err
+	}
+
+	var user *data.User
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.VerificationTokens.Consume(r.Context(), tokenHash, data.ScopeRegistration, input.Email)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.Purge(r.Context(), input.Email)
+		if err != nil {
+			return err
+		}
+
+		user, err = tx.Users.New(r.Context(), input.Email, passwordHash)
+		if err != nil || user.Locale == input.Locale {
+			return err
+		}
+
+		user.Locale = input.Locale
+		return tx.Users.Update(r.Context(), user)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, unauthorizedProblem(""))
+		case errors.Is(err, data.ErrExpiredToken):
+			return app.writeProblem(w, r, expiredTokenProblem(expiredTokenMessage))
+		default:
+			return err
+		}
+	}
+
+	res := response{"user": user}
+
+	return app.writeJSON(w, res, http.StatusCreated)
+}
+
+type passwordResetInput struct {
+	NewPassword    string `json:"password"`
+	PlaintextToken string `json:"token"`
+}
+
+// Password reset handler
+func (app *application) usersPasswordPut(w http.ResponseWriter, r *http.Request) error {
+	var input passwordResetInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.NewPassword, isRequired, passwordLength),
+		validation.Field(&input.PlaintextToken, isRequired),
+	)
+	if err != nil {
+		return err
+	}
+
+	tokenHash := crypto.TokenHash(input.PlaintextToken)
+
+	user, err := app.db.Users.GetWithVerificationToken(r.Context(), data.ScopePasswordReset, tokenHash)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, unauthorizedProblem(""))
+		case errors.Is(err, data.ErrExpiredToken):
+			return app.writeProblem(w, r, expiredTokenProblem(expiredTokenMessage))
+		default:
+			return err
+		}
+	}
+
+	user.PasswordHash, err = crypto.PasswordHash(input.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	// Resetting the password proves ownership of the email address,
+	// so it also unlocks the account.
+	user.Locked = false
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.VerificationTokens.Consume(r.Context(), tokenHash, data.ScopePasswordReset, user.Email)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+
+		return tx.VerificationTokens.Purge(r.Context(), user.Email)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, unauthorizedProblem(""))
+		default:
+			return err
+		}
+	}
+
+	res := response{"message": "your password was successfully reset"}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
+
+func (app *application) usersMeGet(w http.ResponseWriter, r *http.Request) error {
+	user := app.contextGetUser(r.Context())
+	res := response{"user": user}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
+
+// Fields of the user to change. Fields that are missing are unchanged.
+type userUpdateInput struct {
+	Email          *string `json:"email"`
+	Password       *string `json:"password"`
+	PlaintextToken *string `json:"token"`
+	Locale         *string `json:"locale"`
+}
+
+// Every field is optional. Updating email requires a verificaiton token.
+func (app *application) usersMePut(w http.ResponseWriter, r *http.Request) error {
+	var input userUpdateInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.Email, isEmail),
+		validation.Field(&input.Password, passwordLength),
+		validation.Field(&input.Locale, supportedLocale),
+	)
+	if err != nil {
+		return err
+	}
+
+	user := app.contextGetUser(r.Context())
+	previousEmail := user.Email
+
+	if input.Email != nil && input.PlaintextToken == nil {
+		return app.writeProblem(w, r, unauthorizedProblem("missing token"))
+	}
+
+	if input.Locale != nil {
+		user.Locale = *input.Locale
+	}
+
+	// Update user password
+	if input.Password != nil {
+		user.PasswordHash, err = crypto.PasswordHash(*input.Password)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		// Update user email address
+		if input.Email != nil {
+			tokenHash := crypto.TokenHash(*input.PlaintextToken)
+			err := tx.VerificationTokens.Consume(r.Context(), tokenHash, data.ScopeEmailChange, *input.Email)
+			if err != nil {
+				return err
+			}
+
+			err = tx.VerificationTokens.Purge(r.Context(), user.Email)
+			if err != nil {
+				return err
+			}
+
+			err = tx.VerificationTokens.Purge(r.Context(), *input.Email)
+			if err != nil {
+				return err
+			}
+
+			user.Email = *input.Email
+		}
+
+		err := tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+
+		if user.Email == previousEmail {
+			return nil
+		}
+
+		// Let the previous address undo a change it didn't make
+		token, err := crypto.NewToken(data.EmailRevertTokenTTL)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.NewForUser(r.Context(), token.Hash, token.Expiry, data.ScopeEmailRevert, previousEmail, user.ID)
+		if errors.Is(err, data.ErrSuppressedAddress) {
+			// The previous address bounced, so there's no one to warn
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		// Mail the plaintext token to the previous email address
+		component := emails.EmailRevert(user.Email, token.Plaintext)
+		return app.sendMail(r.Context(), tx, user.Locale, previousEmail, "Email Changed", component)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, unauthorizedProblem(""))
+		case errors.Is(err, data.ErrExpiredToken):
+			return app.writeProblem(w, r, expiredTokenProblem(expiredTokenMessage))
+		default:
+			return err
+		}
+	}
+
+	res := response{"user": user}
+
+	return app.writeJSON(w, res, http.StatusCreated)
+}
+
+type emailRevertInput struct {
+	PlaintextToken string `json:"token"`
+}
+
+// Restore the email address that was replaced by the last email
+// change and lock the account until the password is reset.
+func (app *application) usersEmailRevertPut(w http.ResponseWriter, r *http.Request) error {
+	var input emailRevertInput
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		return err
+	}
+
+	err = validation.ValidateStruct(&input,
+		validation.Field(&input.PlaintextToken, isRequired),
+	)
+	if err != nil {
+		return err
+	}
+
+	tokenHash := crypto.TokenHash(input.PlaintextToken)
+
+	vt, err := app.db.VerificationTokens.Get(r.Context(), tokenHash)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, unauthorizedProblem(""))
+		default:
+			return err
+		}
+	}
+	if vt.Scope != data.ScopeEmailRevert || !vt.UserID.Valid {
+		return app.writeProblem(w, r, unauthorizedProblem(""))
+	}
+	if time.Now().After(vt.Expiry) {
+		return app.writeProblem(w, r, expiredTokenProblem(expiredTokenMessage))
+	}
+
+	user, err := app.db.Users.Get(r.Context(), vt.UserID.UUID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, unauthorizedProblem(""))
+		default:
+			return err
+		}
+	}
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.VerificationTokens.Consume(r.Context(), tokenHash, data.ScopeEmailRevert, vt.Email)
+		if err != nil {
+			return err
+		}
+
+		// Tokens mailed to the replacement address can't be trusted
+		err = tx.VerificationTokens.Purge(r.Context(), user.Email)
+		if err != nil {
+			return err
+		}
+
+		user.Email = vt.Email
+		user.Locked = true
+
+		err = tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+
+		// Other tokens of the restored address were sent before the change
+		err = tx.VerificationTokens.Purge(r.Context(), user.Email)
+		if err != nil {
+			return err
+		}
+
+		// Sign out every client
+		return tx.AuthenticationTokens.Purge(r.Context(), user.ID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return app.writeProblem(w, r, unauthorizedProblem(""))
+		case errors.Is(err, data.ErrDuplicateEmail):
+			return app.writeProblem(w, r, conflictProblem("email address is already in use"))
+		default:
+			return err
+		}
+	}
+
+	res := response{"message": "your email was restored and your account is locked. reset your password to unlock it"}
+
+	return app.writeJSON(w, res, http.StatusOK)
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/micahco/mono/internal/fielderr"
+	"github.com/micahco/mono/internal/i18n"
+)
+
+// Rules that report their failures with the error codes of fielderr
+var (
+	isRequired      = coded(validation.Required, fielderr.CodeRequired, nil)
+	isEmail         = coded(is.Email, fielderr.CodeInvalidEmail, nil)
+	passwordLength  = length(8, 72)
+	supportedLocale = coded(validation.In(locales()...), fielderr.CodeNotAllowed, map[string]any{"allowed": i18n.Locales()})
+)
+
+func locales() []any {
+	var locales []any
+	for _, locale := range i18n.Locales() {
+		locales = append(locales, locale)
+	}
+
+	return locales
+}
+
+type codedRule struct {
+	rule   validation.Rule
+	code   string
+	params map[string]any
+}
+
+func coded(rule validation.Rule, code string, params map[string]any) validation.Rule {
+	return &codedRule{rule, code, params}
+}
+
+func (r *codedRule) Validate(value any) error {
+	err := r.rule.Validate(value)
+	if err == nil {
+		return nil
+	}
+
+	return &fielderr.Error{
+		Code:    r.code,
+		Message: err.Error(),
+		Params:  r.params,
+	}
+}
+
+// Length rule that tells values that are too short from those that are
+// too long. Empty values are valid.
+type lengthRule struct {
+	min, max int
+}
+
+func length(min, max int) validation.Rule {
+	return &lengthRule{min, max}
+}
+
+func (r *lengthRule) Validate(value any) error {
+	value, isNil := validation.Indirect(value)
+	if isNil || validation.IsEmpty(value) {
+		return nil
+	}
+
+	l, err := validation.LengthOfValue(value)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("the length must be between %d and %d", r.min, r.max)
+	switch {
+	case l < r.min:
+		return &fielderr.Error{
+			Code:    fielderr.CodeTooShort,
+			Message: msg,
+			Params:  map[string]any{"min": r.min},
+		}
+	case l > r.max:
+		return &fielderr.Error{
+			Code:    fielderr.CodeTooLong,
+			Message: msg,
+			Params:  map[string]any{"max": r.max},
+		}
+	}
+
+	return nil
+}
+
+// Field errors of the validation errors. Failures of rules without a
+// code are invalid.
+func fieldErrors(errs validation.Errors) fielderr.Errors {
+	fields := make(fielderr.Errors, len(errs))
+	for field, err := range errs {
+		var fieldErr *fielderr.Error
+		if errors.As(err, &fieldErr) {
+			fields[field] = append(fields[field], fieldErr)
+			continue
+		}
+
+		fields.Add(field, fielderr.CodeInvalid, err.Error(), nil)
+	}
+
+	return fields
+}
@@ -0,0 +1,81 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+)
+
+var ErrInvalidCredentials = errors.New("authn: invalid credentials")
+
+// Verifies the credentials of a user. Returns ErrInvalidCredentials when
+// the email or password is incorrect.
+type Authenticator interface {
+	Authenticate(ctx context.Context, email, password string) (*data.User, error)
+}
+
+// Authenticates users with the argon2 password hash of the local user
+type Local struct {
+	Users data.UserRepository
+}
+
+func (a *Local) Authenticate(ctx context.Context, email, password string) (*data.User, error) {
+	user, err := a.Users.GetWithEmail(ctx, email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			// User with email does not exist
+			return nil, ErrInvalidCredentials
+		default:
+			return nil, err
+		}
+	}
+
+	match, err := crypto.ComparePasswordAndHash(password, user.PasswordHash)
+	if err != nil {
+		return nil, err
+	}
+	if !match {
+		// Incorrect password
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// Tries each authenticator in order and returns the first user whose
+// credentials are valid. An authenticator that fails, such as a directory
+// that is down, is logged and skipped so that the next one can still sign
+// in its users.
+type Chain struct {
+	Authenticators []Authenticator
+	Logger         *slog.Logger
+}
+
+func (c *Chain) Authenticate(ctx context.Context, email, password string) (*data.User, error) {
+	var failed error
+	for _, a := range c.Authenticators {
+		user, err := a.Authenticate(ctx, email, password)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			c.Logger.Error("authn: authenticator failed", slog.String("authenticator", fmt.Sprintf("%T", a)), slog.Any("err", err))
+			if failed == nil {
+				failed = err
+			}
+		}
+	}
+
+	// Report the failure rather than invalid credentials, which might
+	// only be invalid because the authenticator of the user failed
+	if failed != nil {
+		return nil, failed
+	}
+
+	return nil, ErrInvalidCredentials
+}
@@ -0,0 +1,300 @@
+package authn
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+)
+
+type LDAPConfig struct {
+	// Directory server URL, ldap:// or ldaps://
+	URL string
+
+	// Upgrade ldap:// connections with StartTLS
+	StartTLS bool
+
+	// TLS configuration for ldaps:// and StartTLS. Defaults to verifying
+	// the host of the URL.
+	TLSConfig *tls.Config
+
+	// Service account used to search for users. Searches are anonymous
+	// if empty.
+	BindDN       string
+	BindPassword string
+
+	// Base of the user search
+	BaseDN string
+
+	// Filter that selects a single user, where {email} is replaced by the
+	// escaped email. Defaults to "(mail={email})".
+	UserFilter string
+
+	// Attributes mapped to user fields. Values of the ID attribute that
+	// are not printable (e.g. objectGUID) are hex encoded. Default to
+	// "mail", "displayName" and "entryUUID".
+	EmailAttribute       string
+	DisplayNameAttribute string
+	IDAttribute          string
+
+	// Dial and request timeout. Defaults to 10 seconds.
+	Timeout time.Duration
+
+	// Link directory users to the local user with the same email, which
+	// then can no longer sign in with the local password. Otherwise the
+	// directory user can't sign in while the local user exists.
+	LinkByEmail bool
+}
+
+// Authenticates users against an LDAP directory, such as Active Directory,
+// with a search for the user followed by a bind as the user. Directory
+// users are created or updated locally on every successful login.
+type LDAP struct {
+	config LDAPConfig
+	db     *data.DB
+}
+
+func NewLDAP(config LDAPConfig, db *data.DB) *LDAP {
+	if config.UserFilter == "" {
+		config.UserFilter = "(mail={email})"
+	}
+	if config.EmailAttribute == "" {
+		config.EmailAttribute = "mail"
+	}
+	if config.DisplayNameAttribute == "" {
+		config.DisplayNameAttribute = "displayName"
+	}
+	if config.IDAttribute == "" {
+		config.IDAttribute = "entryUUID"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.TLSConfig == nil {
+		config.TLSConfig = &tls.Config{}
+		if u, err := url.Parse(config.URL); err == nil {
+			config.TLSConfig.ServerName = u.Hostname()
+		}
+	}
+
+	return &LDAP{config, db}
+}
+
+// A directory user mapped to user fields
+type ldapUser struct {
+	email       string
+	displayName string
+	externalID  string
+}
+
+func (a *LDAP) Authenticate(ctx context.Context, email, password string) (*data.User, error) {
+	// Directories treat a bind without a password as anonymous, which
+	// always succeeds.
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	entry, err := a.verify(email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.provision(ctx, entry)
+}
+
+func (a *LDAP) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: a.config.Timeout}),
+		ldap.DialWithTLSConfig(a.config.TLSConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetTimeout(a.config.Timeout)
+
+	if a.config.StartTLS {
+		err = conn.StartTLS(a.config.TLSConfig)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// Search for the user and bind with their password
+func (a *LDAP) verify(email, password string) (*ldapUser, error) {
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.config.BindDN != "" {
+		err = conn.Bind(a.config.BindDN, a.config.BindPassword)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	filter := strings.ReplaceAll(a.config.UserFilter, "{email}", ldap.EscapeFilter(email))
+	attributes := []string{a.config.EmailAttribute, a.config.DisplayNameAttribute, a.config.IDAttribute}
+
+	req := ldap.NewSearchRequest(
+		a.config.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2, // more than one entry is ambiguous
+		int(a.config.Timeout.Seconds()),
+		false,
+		filter,
+		attributes,
+		nil,
+	)
+
+	res, err := conn.Search(req)
+	if err != nil {
+		switch {
+		case ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject),
+			ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded):
+			return nil, ErrInvalidCredentials
+		default:
+			return nil, err
+		}
+	}
+	if len(res.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	entry := res.Entries[0]
+
+	err = conn.Bind(entry.DN, password)
+	if err != nil {
+		switch {
+		case ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials):
+			return nil, ErrInvalidCredentials
+		default:
+			return nil, err
+		}
+	}
+
+	user := &ldapUser{
+		email:       entry.GetAttributeValue(a.config.EmailAttribute),
+		displayName: entry.GetAttributeValue(a.config.DisplayNameAttribute),
+		externalID:  ldapID(entry.GetRawAttributeValue(a.config.IDAttribute)),
+	}
+	if user.email == "" {
+		user.email = email
+	}
+	if user.externalID == "" {
+		user.externalID = entry.DN
+	}
+
+	return user, nil
+}
+
+// Create or update the local user of the directory user
+func (a *LDAP) provision(ctx context.Context, u *ldapUser) (*data.User, error) {
+	var user *data.User
+	err := a.db.WithTx(ctx, func(tx *data.DB) error {
+		var err error
+		user, err = a.provisionTx(ctx, tx, u)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (a *LDAP) provisionTx(ctx context.Context, tx *data.DB, u *ldapUser) (*data.User, error) {
+	users, _, err := tx.Users.List(ctx, data.UserFilter{ExternalID: u.externalID}, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *data.User
+	if len(users) > 0 {
+		user = users[0]
+	} else {
+		user, err = tx.Users.GetWithEmail(ctx, u.email)
+		switch {
+		case err == nil:
+			if !a.config.LinkByEmail {
+				return nil, ErrInvalidCredentials
+			}
+		case errors.Is(err, data.ErrRecordNotFound):
+			passwordHash, err := unusablePasswordHash()
+			if err != nil {
+				return nil, err
+			}
+
+			user, err = tx.Users.New(ctx, u.email, passwordHash)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+	}
+
+	if user.Email == u.email && user.DisplayName == u.displayName && user.ExternalID == u.externalID {
+		return user, nil
+	}
+
+	// Directory users sign in with their directory password only
+	if user.ExternalID != u.externalID {
+		user.PasswordHash, err = unusablePasswordHash()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	user.Email = u.email
+	user.DisplayName = u.displayName
+	user.ExternalID = u.externalID
+
+	err = tx.Users.Update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Hash of a random password that nobody knows
+func unusablePasswordHash() ([]byte, error) {
+	password, err := crypto.GeneratePlaintextToken()
+	if err != nil {
+		return nil, err
+	}
+
+	return crypto.PasswordHash(password)
+}
+
+func ldapID(raw []byte) string {
+	if !utf8.Valid(raw) {
+		return hex.EncodeToString(raw)
+	}
+
+	for _, r := range string(raw) {
+		if !unicode.IsPrint(r) {
+			return hex.EncodeToString(raw)
+		}
+	}
+
+	return string(raw)
+}
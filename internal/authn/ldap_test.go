@@ -0,0 +1,319 @@
+package authn_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jimlambrt/gldap"
+	"github.com/jimlambrt/gldap/testdirectory"
+	"github.com/micahco/mono/internal/authn"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserDN        = "ou=people,dc=example,dc=org"
+	testServiceDN     = "cn=service," + testUserDN
+	testAlicePassword = "alice_directory_password"
+)
+
+// User repository that keeps users in memory. Only the methods used by
+// the authenticators are implemented.
+type userRepository struct {
+	data.UserRepository
+
+	mu    sync.Mutex
+	users []*data.User
+}
+
+func (r *userRepository) New(ctx context.Context, email string, passwordHash []byte) (*data.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) {
+			return nil, data.ErrDuplicateEmail
+		}
+	}
+
+	user := &data.User{
+		ID:           uuid.Must(uuid.NewV4()),
+		CreatedAt:    time.Now(),
+		Email:        email,
+		PasswordHash: passwordHash,
+		Active:       true,
+		Version:      1,
+	}
+	r.users = append(r.users, user)
+
+	copy := *user
+	return &copy, nil
+}
+
+func (r *userRepository) GetWithEmail(ctx context.Context, email string) (*data.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) {
+			copy := *u
+			return &copy, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (r *userRepository) List(ctx context.Context, filter data.UserFilter, offset, limit int) ([]*data.User, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var users []*data.User
+	for _, u := range r.users {
+		if filter.ExternalID == "" || u.ExternalID == filter.ExternalID {
+			copy := *u
+			users = append(users, &copy)
+		}
+	}
+
+	return users, len(users), nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *data.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, u := range r.users {
+		if u.ID == user.ID {
+			user.Version++
+			copy := *user
+			r.users[i] = &copy
+			return nil
+		}
+	}
+
+	return data.ErrRecordNotFound
+}
+
+// Transactor that binds the user repository to its transactions only, so
+// that writes outside of a transaction fail
+type transactor struct {
+	users data.UserRepository
+}
+
+func (t transactor) WithTx(ctx context.Context, fn func(tx *data.DB) error) error {
+	return fn(&data.DB{Users: t.users})
+}
+
+func newDirectoryUser(email, displayName, id, password string) *gldap.Entry {
+	return gldap.NewEntry(
+		fmt.Sprintf("mail=%s,%s", email, testUserDN),
+		map[string][]string{
+			"mail":        {email},
+			"displayName": {displayName},
+			"entryUUID":   {id},
+			"password":    {password},
+		},
+	)
+}
+
+func startDirectory(t *testing.T) *testdirectory.Directory {
+	td := testdirectory.Start(t, testdirectory.WithNoTLS(t))
+	td.SetUsers(
+		gldap.NewEntry(testServiceDN, map[string][]string{"password": {"service_password"}}),
+		newDirectoryUser("alice@example.com", "Alice", "4f3c2a1e-0000-4000-8000-000000000001", testAlicePassword),
+	)
+
+	return td
+}
+
+func newLDAP(td *testdirectory.Directory, users data.UserRepository, linkByEmail bool) *authn.LDAP {
+	return authn.NewLDAP(authn.LDAPConfig{
+		URL:          fmt.Sprintf("ldap://%s:%d", td.Host(), td.Port()),
+		BindDN:       testServiceDN,
+		BindPassword: "service_password",
+		BaseDN:       testUserDN,
+		LinkByEmail:  linkByEmail,
+	}, &data.DB{Transactor: transactor{users}})
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	td := startDirectory(t)
+	users := &userRepository{}
+	a := newLDAP(td, users, false)
+
+	t.Run("TestProvision", func(t *testing.T) {
+		user, err := a.Authenticate(ctx, "alice@example.com", testAlicePassword)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", user.Email)
+		assert.Equal(t, "Alice", user.DisplayName)
+		assert.Equal(t, "4f3c2a1e-0000-4000-8000-000000000001", user.ExternalID)
+		assert.Len(t, users.users, 1)
+
+		// Directory password is not stored locally
+		match, err := crypto.ComparePasswordAndHash(testAlicePassword, user.PasswordHash)
+		require.NoError(t, err)
+		assert.False(t, match)
+	})
+
+	t.Run("TestUpdate", func(t *testing.T) {
+		td.SetUsers(
+			gldap.NewEntry(testServiceDN, map[string][]string{"password": {"service_password"}}),
+			newDirectoryUser("alice@example.org", "Alice Smith", "4f3c2a1e-0000-4000-8000-000000000001", testAlicePassword),
+		)
+
+		user, err := a.Authenticate(ctx, "alice@example.org", testAlicePassword)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.org", user.Email)
+		assert.Equal(t, "Alice Smith", user.DisplayName)
+		assert.Len(t, users.users, 1)
+	})
+
+	t.Run("TestInvalidPassword", func(t *testing.T) {
+		_, err := a.Authenticate(ctx, "alice@example.org", "wrong_password")
+		assert.ErrorIs(t, err, authn.ErrInvalidCredentials)
+	})
+
+	t.Run("TestEmptyPassword", func(t *testing.T) {
+		_, err := a.Authenticate(ctx, "alice@example.org", "")
+		assert.ErrorIs(t, err, authn.ErrInvalidCredentials)
+	})
+
+	t.Run("TestUnknownUser", func(t *testing.T) {
+		_, err := a.Authenticate(ctx, "bob@example.org", testAlicePassword)
+		assert.ErrorIs(t, err, authn.ErrInvalidCredentials)
+	})
+}
+
+func TestLDAPLinkExistingUser(t *testing.T) {
+	ctx := context.Background()
+	td := startDirectory(t)
+
+	newUsers := func(t *testing.T) (*userRepository, *data.User) {
+		users := &userRepository{}
+		localHash, err := crypto.PasswordHash("local_password")
+		require.NoError(t, err)
+		existing, err := users.New(ctx, "alice@example.com", localHash)
+		require.NoError(t, err)
+
+		return users, existing
+	}
+
+	t.Run("TestRefused", func(t *testing.T) {
+		users, existing := newUsers(t)
+
+		_, err := newLDAP(td, users, false).Authenticate(ctx, "alice@example.com", testAlicePassword)
+		assert.ErrorIs(t, err, authn.ErrInvalidCredentials)
+		require.Len(t, users.users, 1)
+		assert.Empty(t, users.users[0].ExternalID)
+
+		// The local password still signs in the local user
+		user, err := (&authn.Local{Users: users}).Authenticate(ctx, "alice@example.com", "local_password")
+		require.NoError(t, err)
+		assert.Equal(t, existing.ID, user.ID)
+	})
+
+	t.Run("TestLinked", func(t *testing.T) {
+		users, existing := newUsers(t)
+
+		user, err := newLDAP(td, users, true).Authenticate(ctx, "alice@example.com", testAlicePassword)
+		require.NoError(t, err)
+		assert.Equal(t, existing.ID, user.ID)
+		assert.Equal(t, "4f3c2a1e-0000-4000-8000-000000000001", user.ExternalID)
+		assert.Len(t, users.users, 1)
+
+		// The local password no longer signs in a directory user
+		_, err = (&authn.Local{Users: users}).Authenticate(ctx, "alice@example.com", "local_password")
+		assert.ErrorIs(t, err, authn.ErrInvalidCredentials)
+	})
+}
+
+func TestLDAPStartTLS(t *testing.T) {
+	ctx := context.Background()
+	td := startDirectory(t)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM([]byte(td.Cert())))
+
+	a := authn.NewLDAP(authn.LDAPConfig{
+		URL:          fmt.Sprintf("ldap://%s:%d", td.Host(), td.Port()),
+		StartTLS:     true,
+		TLSConfig:    &tls.Config{RootCAs: roots, ServerName: td.Host()},
+		BindDN:       testServiceDN,
+		BindPassword: "service_password",
+		BaseDN:       testUserDN,
+	}, &data.DB{Transactor: transactor{&userRepository{}}})
+
+	user, err := a.Authenticate(ctx, "alice@example.com", testAlicePassword)
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", user.Email)
+}
+
+// Authenticator that always fails, such as a directory that is down
+type failingAuthenticator struct{}
+
+func (failingAuthenticator) Authenticate(ctx context.Context, email, password string) (*data.User, error) {
+	return nil, errors.New("directory unavailable")
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	td := startDirectory(t)
+	users := &userRepository{}
+
+	localHash, err := crypto.PasswordHash("local_password")
+	require.NoError(t, err)
+	_, err = users.New(ctx, "admin@example.com", localHash)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("TestAuthenticate", func(t *testing.T) {
+		chain := &authn.Chain{
+			Authenticators: []authn.Authenticator{newLDAP(td, users, false), &authn.Local{Users: users}},
+			Logger:         logger,
+		}
+
+		user, err := chain.Authenticate(ctx, "alice@example.com", testAlicePassword)
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", user.Email)
+
+		// Local users that aren't in the directory
+		user, err = chain.Authenticate(ctx, "admin@example.com", "local_password")
+		require.NoError(t, err)
+		assert.Equal(t, "admin@example.com", user.Email)
+
+		_, err = chain.Authenticate(ctx, "admin@example.com", "wrong_password")
+		assert.ErrorIs(t, err, authn.ErrInvalidCredentials)
+	})
+
+	t.Run("TestFailedAuthenticator", func(t *testing.T) {
+		chain := &authn.Chain{
+			Authenticators: []authn.Authenticator{failingAuthenticator{}, &authn.Local{Users: users}},
+			Logger:         logger,
+		}
+
+		// Local users still sign in
+		user, err := chain.Authenticate(ctx, "admin@example.com", "local_password")
+		require.NoError(t, err)
+		assert.Equal(t, "admin@example.com", user.Email)
+
+		// Others get the failure rather than invalid credentials
+		_, err = chain.Authenticate(ctx, "alice@example.com", testAlicePassword)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, authn.ErrInvalidCredentials)
+	})
+}
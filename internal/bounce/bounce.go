@@ -0,0 +1,317 @@
+// Package bounce records the addresses that outbound mail bounced from,
+// so that nothing is sent to them again. It reads delivery status
+// notifications (RFC 3464) and abuse reports (RFC 5965), either posted to
+// the webhook of the API or delivered to a local maildir.
+package bounce
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+const DefaultInterval = time.Minute
+
+// Published at /debug/vars
+var metrics = expvar.NewMap("bounce")
+
+var ErrNotReport = errors.New("bounce: not a delivery status notification or abuse report")
+
+// Failed delivery to a recipient
+type Bounce struct {
+	Email string
+	// One of data.BounceHard, data.BounceSoft or data.BounceComplaint
+	Kind       string
+	Diagnostic string
+}
+
+// Parse the bounces of a report. Notifications of delayed or successful
+// deliveries have none. Returns ErrNotReport if the message is neither a
+// delivery status notification nor an abuse report.
+func Parse(r io.Reader) ([]Bounce, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNotReport, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/report" {
+		return nil, ErrNotReport
+	}
+
+	var parse func(part io.Reader, original *mail.Header) ([]Bounce, error)
+	var statusType string
+	switch strings.ToLower(params["report-type"]) {
+	case "delivery-status":
+		parse, statusType = parseDeliveryStatus, "message/delivery-status"
+	case "feedback-report":
+		parse, statusType = parseFeedbackReport, "message/feedback-report"
+	default:
+		return nil, ErrNotReport
+	}
+
+	// The status part comes before the returned message, whose headers
+	// name the recipient of abuse reports that don't
+	var status []byte
+	var original *mail.Header
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		switch partType {
+		case statusType:
+			status, err = io.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+		case "message/rfc822", "text/rfc822-headers":
+			returned, err := mail.ReadMessage(io.MultiReader(part, strings.NewReader("\r\n\r\n")))
+			if err == nil {
+				original = &returned.Header
+			}
+		}
+	}
+
+	if status == nil {
+		return nil, ErrNotReport
+	}
+
+	return parse(bytes.NewReader(status), original)
+}
+
+// Per-recipient fields of a delivery status notification. Failures with
+// a permanent (5.x.x) status are hard bounces, and with a transient
+// (4.x.x) status soft bounces.
+func parseDeliveryStatus(r io.Reader, _ *mail.Header) ([]Bounce, error) {
+	tr := textproto.NewReader(bufio.NewReader(r))
+
+	// Per-message fields
+	_, err := tr.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	bounces := []Bounce{}
+	for err != io.EOF {
+		var fields textproto.MIMEHeader
+		fields, err = tr.ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if !strings.EqualFold(fields.Get("Action"), "failed") {
+			continue
+		}
+
+		email := recipientAddress(fields.Get("Original-Recipient"))
+		if email == "" {
+			email = recipientAddress(fields.Get("Final-Recipient"))
+		}
+		if email == "" {
+			continue
+		}
+
+		kind := data.BounceHard
+		if strings.HasPrefix(fields.Get("Status"), "4") {
+			kind = data.BounceSoft
+		}
+
+		diagnostic := fields.Get("Diagnostic-Code")
+		if _, code, ok := strings.Cut(diagnostic, ";"); ok {
+			diagnostic = strings.TrimSpace(code)
+		}
+		if diagnostic == "" {
+			diagnostic = fields.Get("Status")
+		}
+
+		bounces = append(bounces, Bounce{
+			Email:      email,
+			Kind:       kind,
+			Diagnostic: diagnostic,
+		})
+	}
+
+	return bounces, nil
+}
+
+// Complaint about the recipient of an abuse report, which is named by the
+// report or else by the returned message
+func parseFeedbackReport(r io.Reader, original *mail.Header) ([]Bounce, error) {
+	fields, err := textproto.NewReader(bufio.NewReader(r)).ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	email := fields.Get("Original-Rcpt-To")
+	if email == "" && original != nil {
+		email = original.Get("To")
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil {
+		return []Bounce{}, nil
+	}
+
+	bounce := Bounce{
+		Email:      addr.Address,
+		Kind:       data.BounceComplaint,
+		Diagnostic: fields.Get("Feedback-Type"),
+	}
+
+	return []Bounce{bounce}, nil
+}
+
+// Address of a recipient field with an address type, such as
+// "rfc822; user@example.com". Only RFC 822 addresses are supported.
+func recipientAddress(field string) string {
+	addrType, addr, ok := strings.Cut(field, ";")
+	if !ok || !strings.EqualFold(strings.TrimSpace(addrType), "rfc822") {
+		return ""
+	}
+
+	return strings.Trim(strings.TrimSpace(addr), "<>")
+}
+
+type Processor struct {
+	db       *data.DB
+	logger   *slog.Logger
+	dir      string
+	interval time.Duration
+}
+
+// Create a processor that reads reports from the maildir, unless it's
+// empty
+func New(db *data.DB, logger *slog.Logger, dir string, interval time.Duration) *Processor {
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	return &Processor{
+		db:       db,
+		logger:   logger,
+		dir:      dir,
+		interval: interval,
+	}
+}
+
+// Record the bounces of the report. Returns the number of bounces.
+func (p *Processor) Process(ctx context.Context, r io.Reader) (int, error) {
+	bounces, err := Parse(r)
+	if err != nil {
+		return 0, err
+	}
+
+	err = p.record(ctx, bounces)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(bounces), nil
+}
+
+func (p *Processor) record(ctx context.Context, bounces []Bounce) error {
+	for _, b := range bounces {
+		err := p.db.Suppressions.Record(ctx, b.Email, b.Kind, b.Diagnostic)
+		if err != nil {
+			return err
+		}
+
+		metrics.Add(b.Kind, 1)
+		p.logger.Info("bounce: recorded bounce", slog.String("email", b.Email), slog.String("kind", b.Kind))
+	}
+
+	return nil
+}
+
+// Read the maildir on every interval until the context is canceled
+func (p *Processor) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		err := p.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			metrics.Add("errors", 1)
+			p.logger.Error("bounce: poll failed", slog.Any("err", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Process the new messages of the maildir and move them to cur, marked as
+// seen. Messages that aren't reports are moved as well, so that they
+// aren't read again.
+func (p *Processor) Poll(ctx context.Context) error {
+	if p.dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(filepath.Join(p.dir, "new"))
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if e.IsDir() {
+			continue
+		}
+
+		err := p.processFile(ctx, e.Name())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Processor) processFile(ctx context.Context, name string) error {
+	path := filepath.Join(p.dir, "new", name)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bounces, err := Parse(f)
+	if err != nil {
+		metrics.Add("ignored", 1)
+		p.logger.Warn("bounce: ignored message", slog.String("file", name), slog.Any("err", err))
+	}
+
+	err = p.record(ctx, bounces)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path, filepath.Join(p.dir, "cur", name+":2,S"))
+}
@@ -0,0 +1,131 @@
+package bounce
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/data/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testDSN = "From: MAILER-DAEMON@example.com\r\n" +
+	"To: no-reply@example.com\r\n" +
+	"Subject: Undelivered Mail Returned to Sender\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=delivery-status; boundary=\"b\"\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Your message could not be delivered.\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/delivery-status\r\n" +
+	"\r\n" +
+	"Reporting-MTA: dns; mx.example.com\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; hard@example.com\r\n" +
+	"Action: failed\r\n" +
+	"Status: 5.1.1\r\n" +
+	"Diagnostic-Code: smtp; 550 5.1.1 user unknown\r\n" +
+	"\r\n" +
+	"Original-Recipient: rfc822;soft@example.com\r\n" +
+	"Final-Recipient: rfc822; forwarded@example.com\r\n" +
+	"Action: failed\r\n" +
+	"Status: 4.2.2\r\n" +
+	"\r\n" +
+	"Final-Recipient: rfc822; delayed@example.com\r\n" +
+	"Action: delayed\r\n" +
+	"Status: 4.4.1\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/rfc822-headers\r\n" +
+	"\r\n" +
+	"To: hard@example.com\r\n" +
+	"Subject: Registration\r\n" +
+	"--b--\r\n"
+
+const testARF = "From: abuse@example.net\r\n" +
+	"To: no-reply@example.com\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/report; report-type=feedback-report; boundary=\"b\"\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"This is an email abuse report.\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/feedback-report\r\n" +
+	"\r\n" +
+	"Feedback-Type: abuse\r\n" +
+	"User-Agent: ExampleFBL/1.0\r\n" +
+	"Version: 1\r\n" +
+	"\r\n" +
+	"--b\r\n" +
+	"Content-Type: message/rfc822\r\n" +
+	"\r\n" +
+	"From: Do Not Reply <no-reply@example.com>\r\n" +
+	"To: complaint@example.net\r\n" +
+	"Subject: Registration\r\n" +
+	"\r\n" +
+	"Hello\r\n" +
+	"--b--\r\n"
+
+func TestParse(t *testing.T) {
+	bounces, err := Parse(strings.NewReader(testDSN))
+	require.NoError(t, err)
+	assert.Equal(t, []Bounce{
+		{Email: "hard@example.com", Kind: data.BounceHard, Diagnostic: "550 5.1.1 user unknown"},
+		{Email: "soft@example.com", Kind: data.BounceSoft, Diagnostic: "4.2.2"},
+	}, bounces)
+
+	bounces, err = Parse(strings.NewReader(testARF))
+	require.NoError(t, err)
+	assert.Equal(t, []Bounce{
+		{Email: "complaint@example.net", Kind: data.BounceComplaint, Diagnostic: "abuse"},
+	}, bounces)
+
+	_, err = Parse(strings.NewReader("Subject: Hello\r\n\r\nNot a report\r\n"))
+	assert.ErrorIs(t, err, ErrNotReport)
+}
+
+func TestPoll(t *testing.T) {
+	ctx := context.Background()
+	db := memory.NewMemoryDB().DB
+
+	dir := t.TempDir()
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, sub), 0o755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new", "1.dsn"), []byte(testDSN), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new", "2.txt"), []byte("Subject: Hello\r\n\r\n"), 0o644))
+
+	p := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)), dir, 0)
+
+	err := p.Poll(ctx)
+	require.NoError(t, err)
+
+	suppressed, err := db.Suppressions.IsSuppressed(ctx, "hard@example.com")
+	require.NoError(t, err)
+	assert.True(t, suppressed)
+
+	s, err := db.Suppressions.Get(ctx, "soft@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, s.SoftBounces)
+	assert.False(t, s.Suppressed)
+
+	// Every message is moved out of new, reports or not
+	entries, err := os.ReadDir(filepath.Join(dir, "new"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	entries, err = os.ReadDir(filepath.Join(dir, "cur"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
@@ -0,0 +1,265 @@
+// Package config is the configuration of the mono servers and tools. Each
+// section registers its settings with a Source and validates them once
+// they are parsed.
+package config
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/micahco/mono/internal/authn"
+	"github.com/micahco/mono/internal/bounce"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/janitor"
+	"github.com/micahco/mono/internal/middleware"
+)
+
+const DefaultBodyMaxBytes = 1 << 20
+
+var (
+	isPort     = []validation.Rule{validation.Required, validation.Min(1), validation.Max(65535)}
+	transports = []any{"", "smtp", "file", "memory", "sink"}
+)
+
+// Settings shared by the servers
+type Config struct {
+	Dev bool
+	DB  DB
+	// GeoIP2 City database file
+	GeoIP    string
+	Janitor  time.Duration
+	Throttle data.ThrottleLimits
+	LDAP     authn.LDAPConfig
+	Mail     Mail
+	API      API
+	Web      Web
+
+	// IP addresses or prefixes of the reverse proxies whose forwarding
+	// headers identify the client
+	TrustedProxies []string
+}
+
+type DB struct {
+	// PostgreSQL DSN, or sqlite:path
+	DSN string
+}
+
+type Mail struct {
+	Transport string
+	Dir       string
+	DKIM      struct {
+		Domain   string
+		Selector string
+		KeyFile  string
+	}
+	SMTP struct {
+		Host     string
+		Port     int
+		Username string
+		Password string
+	}
+}
+
+type API struct {
+	Port   int
+	Sender string
+	Bounce struct {
+		Token    string
+		Dir      string
+		Interval time.Duration
+	}
+	Limiter struct {
+		Enabled bool
+		RPS     int
+	}
+	TrustedOrigins []string
+	SCIMToken      string
+	AdminToken     string
+	LegacyErrors   bool
+	BodyMaxBytes   int64
+}
+
+type Web struct {
+	Port   int
+	URL    string
+	Sender string
+	SAML   struct {
+		CertFile string
+		KeyFile  string
+	}
+}
+
+// Register the shared settings, but not those of the API or web servers
+func (c *Config) Register(s *Source) {
+	s.Bool(&c.Dev, "dev", "", false, "Development mode")
+
+	c.DB.Register(s)
+
+	s.String(&c.GeoIP, "geoip-db", "GEOIP_DB", "", "GeoIP2 City database file")
+
+	s.Duration(&c.Janitor, "janitor-interval", "JANITOR_INTERVAL", janitor.DefaultInterval, "Interval between purges of expired tokens (disabled if 0)")
+
+	s.Fields(&c.TrustedProxies, "trusted-proxies", "TRUSTED_PROXIES", nil, "IP addresses or CIDR prefixes of trusted reverse proxies (space separated)")
+
+	s.Duration(&c.Throttle.Cooldown, "throttle-cooldown", "THROTTLE_COOLDOWN", data.DefaultThrottleLimits.Cooldown, "Time between verification emails to a recipient (disabled if 0)")
+	s.Int(&c.Throttle.PerIP, "throttle-per-ip", "THROTTLE_PER_IP", data.DefaultThrottleLimits.PerIP, "Verification emails per hour requested by an IP address (disabled if 0)")
+	s.Int(&c.Throttle.Hourly, "throttle-hourly", "THROTTLE_HOURLY", data.DefaultThrottleLimits.Hourly, "Verification emails per hour in total (disabled if 0)")
+	s.Int(&c.Throttle.Daily, "throttle-daily", "THROTTLE_DAILY", data.DefaultThrottleLimits.Daily, "Verification emails per day in total (disabled if 0)")
+
+	s.String(&c.LDAP.URL, "ldap-url", "LDAP_URL", "", "LDAP directory URL (disabled if empty)")
+	s.Bool(&c.LDAP.StartTLS, "ldap-starttls", "LDAP_STARTTLS", false, "Upgrade LDAP connection with StartTLS")
+	s.String(&c.LDAP.BindDN, "ldap-bind-dn", "LDAP_BIND_DN", "", "LDAP service account DN")
+	s.String(&c.LDAP.BindPassword, "ldap-bind-password", "LDAP_BIND_PASSWORD", "", "LDAP service account password")
+	s.String(&c.LDAP.BaseDN, "ldap-base-dn", "LDAP_BASE_DN", "", "LDAP user search base DN")
+	s.String(&c.LDAP.UserFilter, "ldap-user-filter", "LDAP_USER_FILTER", "", "LDAP user search filter, {email} is replaced by the email")
+	s.String(&c.LDAP.EmailAttribute, "ldap-email-attr", "LDAP_EMAIL_ATTR", "", "LDAP email attribute")
+	s.String(&c.LDAP.DisplayNameAttribute, "ldap-display-name-attr", "LDAP_DISPLAY_NAME_ATTR", "", "LDAP display name attribute")
+	s.String(&c.LDAP.IDAttribute, "ldap-id-attr", "LDAP_ID_ATTR", "", "LDAP unique identifier attribute")
+	s.Bool(&c.LDAP.LinkByEmail, "ldap-link-by-email", "LDAP_LINK_BY_EMAIL", false, "Link LDAP users to the local user with the same email")
+
+	c.Mail.Register(s)
+}
+
+func (c *DB) Register(s *Source) {
+	s.String(&c.DSN, "db-dsn", "DATABASE_URL", "", "PostgreSQL DSN, or sqlite:path for a SQLite database file")
+}
+
+func (c *Mail) Register(s *Source) {
+	s.String(&c.Transport, "mail-transport", "MAIL_TRANSPORT", "", "Mail transport: smtp, file, memory or sink (smtp if empty)")
+	s.String(&c.Dir, "mail-dir", "MAIL_DIR", "", "Maildir that the file transport writes messages to")
+
+	s.String(&c.DKIM.Domain, "dkim-domain", "DKIM_DOMAIN", "", "DKIM signing domain")
+	s.String(&c.DKIM.Selector, "dkim-selector", "DKIM_SELECTOR", "", "DKIM selector of the public key record")
+	s.String(&c.DKIM.KeyFile, "dkim-key-file", "DKIM_KEY_FILE", "", "DKIM RSA or Ed25519 private key PEM file (disabled if empty)")
+
+	s.Int(&c.SMTP.Port, "smtp-port", "SMTP_PORT", 0, "SMTP port")
+	s.String(&c.SMTP.Host, "smtp-host", "SMTP_HOST", "", "SMTP host")
+	s.String(&c.SMTP.Username, "smtp-username", "SMTP_USERNAME", "", "SMTP username")
+	s.String(&c.SMTP.Password, "smtp-password", "SMTP_PASSWORD", "", "SMTP password")
+}
+
+func (c *API) Register(s *Source) {
+	s.Int(&c.Port, "api-port", "API_PORT", 0, "API server port")
+	s.String(&c.Sender, "api-smtp-sender", "API_SMTP_SENDER", "", "Sender of the API emails")
+
+	s.String(&c.Bounce.Token, "bounce-token", "API_BOUNCE_TOKEN", "", "Bounce webhook bearer token (disabled if empty)")
+	s.String(&c.Bounce.Dir, "bounce-dir", "BOUNCE_DIR", "", "Maildir that bounces are delivered to (disabled if empty)")
+	s.Duration(&c.Bounce.Interval, "bounce-interval", "BOUNCE_INTERVAL", bounce.DefaultInterval, "Interval between reads of the bounce maildir")
+
+	s.Bool(&c.Limiter.Enabled, "limiter-enabled", "API_LIMITER_ENABLED", false, "Enable rate limiter")
+	s.Int(&c.Limiter.RPS, "limiter-rps", "API_LIMITER_RPS", 0, "Rate limiter maximum requests per second")
+
+	s.Fields(&c.TrustedOrigins, "cors-trusted-origins", "API_CORS_TRUSTED_ORIGINS", nil, "Trusted CORS origins (space separated)")
+	s.String(&c.SCIMToken, "scim-token", "API_SCIM_TOKEN", "", "SCIM provisioning bearer token (disabled if empty)")
+	s.String(&c.AdminToken, "admin-token", "API_ADMIN_TOKEN", "", "Admin bearer token for the outbox and suppression endpoints (disabled if empty)")
+
+	s.Int64(&c.BodyMaxBytes, "body-max-bytes", "API_BODY_MAX_BYTES", DefaultBodyMaxBytes, "Maximum size of JSON request bodies in bytes")
+	s.Bool(&c.LegacyErrors, "legacy-errors", "API_LEGACY_ERRORS", true, "Respond with deprecated {\"error\": ...} bodies to clients that don't accept problem+json")
+}
+
+func (c *Web) Register(s *Source) {
+	s.Int(&c.Port, "web-port", "WEB_PORT", 0, "Web server port")
+	s.String(&c.URL, "web-url", "WEB_URL", "", "Base URL of the web server for building links")
+	s.String(&c.Sender, "web-smtp-sender", "WEB_SMTP_SENDER", "", "Sender of the web emails")
+
+	s.String(&c.SAML.CertFile, "saml-cert", "WEB_SAML_CERT_FILE", "", "SAML service provider certificate file (disabled if empty)")
+	s.String(&c.SAML.KeyFile, "saml-key", "WEB_SAML_KEY_FILE", "", "SAML service provider private key file")
+}
+
+// Validate the shared settings. Errors are keyed by flag.
+func (c *Config) Validate() error {
+	return errors.Join(
+		c.DB.Validate(),
+		validation.Errors{
+			"janitor-interval":  validation.Validate(c.Janitor, validation.Min(time.Duration(0))),
+			"trusted-proxies":   validation.Validate(c.TrustedProxies, validation.Each(validation.By(isProxy))),
+			"throttle-cooldown": validation.Validate(c.Throttle.Cooldown, validation.Min(time.Duration(0))),
+			"throttle-per-ip":   validation.Validate(c.Throttle.PerIP, validation.Min(0)),
+			"throttle-hourly":   validation.Validate(c.Throttle.Hourly, validation.Min(0)),
+			"throttle-daily":    validation.Validate(c.Throttle.Daily, validation.Min(0)),
+			"ldap-base-dn":      validation.Validate(c.LDAP.BaseDN, when(c.LDAP.URL != "", validation.Required)...),
+		}.Filter(),
+		c.Mail.Validate(c.Dev),
+	)
+}
+
+func (c *DB) Validate() error {
+	return validation.Errors{
+		"db-dsn": validation.Validate(c.DSN, validation.Required),
+	}.Filter()
+}
+
+// The sink transport is only started in development mode
+func (c *Mail) Validate(dev bool) error {
+	return validation.Errors{
+		"mail-transport": validation.Validate(c.Transport,
+			validation.In(transports...),
+			validation.By(func(any) error {
+				if c.Transport == "sink" && !dev {
+					return errors.New("the sink transport requires -dev")
+				}
+				return nil
+			}),
+		),
+		"mail-dir":      validation.Validate(c.Dir, when(c.Transport == "file", validation.Required)...),
+		"dkim-domain":   validation.Validate(c.DKIM.Domain, when(c.DKIM.KeyFile != "", validation.Required, is.Domain)...),
+		"dkim-selector": validation.Validate(c.DKIM.Selector, when(c.DKIM.KeyFile != "", validation.Required)...),
+		"smtp-port":     validation.Validate(c.SMTP.Port, validation.Min(0), validation.Max(65535)),
+	}.Filter()
+}
+
+func (c *API) Validate() error {
+	return validation.Errors{
+		"api-port":             validation.Validate(c.Port, isPort...),
+		"api-smtp-sender":      validation.Validate(c.Sender, is.Email),
+		"bounce-interval":      validation.Validate(c.Bounce.Interval, when(c.Bounce.Dir != "", validation.Required)...),
+		"limiter-rps":          validation.Validate(c.Limiter.RPS, when(c.Limiter.Enabled, validation.Required, validation.Min(1))...),
+		"cors-trusted-origins": validation.Validate(c.TrustedOrigins, validation.Each(validation.By(isOrigin))),
+		"body-max-bytes":       validation.Validate(c.BodyMaxBytes, validation.Required, validation.Min(int64(1))),
+	}.Filter()
+}
+
+func (c *Web) Validate() error {
+	return validation.Errors{
+		"web-port":        validation.Validate(c.Port, isPort...),
+		"web-url":         validation.Validate(c.URL, validation.Required, validation.By(isOrigin)),
+		"web-smtp-sender": validation.Validate(c.Sender, is.Email),
+		"saml-key":        validation.Validate(c.SAML.KeyFile, when(c.SAML.CertFile != "", validation.Required)...),
+	}.Filter()
+}
+
+// Rules that only apply if the condition holds
+func when(cond bool, rules ...validation.Rule) []validation.Rule {
+	if !cond {
+		return nil
+	}
+
+	return rules
+}
+
+// IP address or prefix of a proxy
+func isProxy(value any) error {
+	s, _ := value.(string)
+
+	_, err := middleware.ParseProxy(s)
+	if err != nil {
+		return errors.New("must be an IP address or CIDR prefix")
+	}
+
+	return nil
+}
+
+// Absolute http or https URL
+func isOrigin(value any) error {
+	s, _ := value.(string)
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("must be an http or https URL")
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "mono.env")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func newTestSource() (*Source, *flag.FlagSet) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+
+	return NewSource(fs), fs
+}
+
+func TestSource(t *testing.T) {
+	path := writeFile(t, `
+# comment
+export FLAG_VALUE=file
+ENV_VALUE=file
+FILE_VALUE=file
+FILE_INT=42
+FILE_ORIGINS="http://a.example.com http://b.example.com"
+`)
+	t.Setenv(FileEnv, path)
+	t.Setenv("FLAG_VALUE", "env")
+	t.Setenv("ENV_VALUE", "env")
+	t.Setenv("EMPTY_VALUE", "")
+
+	var flagValue, envValue, fileValue, emptyValue, defaultValue string
+	var fileInt int
+	var origins []string
+
+	s, _ := newTestSource()
+	s.String(&flagValue, "flag-value", "FLAG_VALUE", "default", "")
+	s.String(&envValue, "env-value", "ENV_VALUE", "default", "")
+	s.String(&fileValue, "file-value", "FILE_VALUE", "default", "")
+	s.String(&emptyValue, "empty-value", "EMPTY_VALUE", "default", "")
+	s.String(&defaultValue, "default-value", "DEFAULT_VALUE", "default", "")
+	s.Int(&fileInt, "file-int", "FILE_INT", 0, "")
+	s.Fields(&origins, "file-origins", "FILE_ORIGINS", nil, "")
+
+	err := s.Parse([]string{"-flag-value", "flag"})
+	require.NoError(t, err)
+	assert.Equal(t, "flag", flagValue)
+	assert.Equal(t, "env", envValue)
+	assert.Equal(t, "file", fileValue)
+	assert.Equal(t, "default", emptyValue)
+	assert.Equal(t, "default", defaultValue)
+	assert.Equal(t, 42, fileInt)
+	assert.Equal(t, []string{"http://a.example.com", "http://b.example.com"}, origins)
+
+	t.Run("config flag", func(t *testing.T) {
+		var value string
+		s, _ := newTestSource()
+		s.String(&value, "value", "OTHER_VALUE", "", "")
+
+		err := s.Parse([]string{"-config", writeFile(t, "OTHER_VALUE=other")})
+		require.NoError(t, err)
+		assert.Equal(t, "other", value)
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		t.Setenv("INVALID_INT", "four")
+
+		var value int
+		s, _ := newTestSource()
+		s.Int(&value, "invalid-int", "INVALID_INT", 0, "")
+
+		err := s.Parse(nil)
+		assert.ErrorContains(t, err, "INVALID_INT")
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		var value time.Duration
+		s, fs := newTestSource()
+		s.Duration(&value, "interval", "INTERVAL", time.Minute, "Interval")
+
+		assert.Equal(t, "Interval ($INTERVAL)", fs.Lookup("interval").Usage)
+	})
+}
+
+func TestReadFile(t *testing.T) {
+	t.Setenv("POSTGRES_HOST", "db.example.com")
+
+	values, err := ReadFile(writeFile(t, `
+export POSTGRES_USER="postgres"
+DATABASE_URL="postgresql://${POSTGRES_USER}@${POSTGRES_HOST}/mono"
+LITERAL='${POSTGRES_USER}'
+UNQUOTED=value # comment
+EMPTY=""
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"POSTGRES_USER": "postgres",
+		"DATABASE_URL":  "postgresql://postgres@db.example.com/mono",
+		"LITERAL":       "${POSTGRES_USER}",
+		"UNQUOTED":      "value",
+		"EMPTY":         "",
+	}, values)
+
+	_, err = ReadFile(writeFile(t, "KEY=value\nnot a setting\n"))
+	assert.ErrorContains(t, err, ":2: expected KEY=value")
+
+	_, err = ReadFile(filepath.Join(t.TempDir(), "missing.env"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// Config of both servers with the defaults of the flags
+func newTestConfig(t *testing.T, args ...string) *Config {
+	t.Setenv(FileEnv, "")
+
+	var cfg Config
+	s, _ := newTestSource()
+	cfg.Register(s)
+	cfg.API.Register(s)
+	cfg.Web.Register(s)
+	require.NoError(t, s.Parse(args))
+
+	return &cfg
+}
+
+func TestValidate(t *testing.T) {
+	for _, key := range []string{"DATABASE_URL", "MAIL_TRANSPORT", "MAIL_DIR", "DKIM_KEY_FILE", "API_PORT", "WEB_PORT", "WEB_URL", "API_LIMITER_ENABLED", "API_CORS_TRUSTED_ORIGINS", "WEB_SAML_CERT_FILE", "TRUSTED_PROXIES"} {
+		t.Setenv(key, "")
+	}
+
+	cfg := newTestConfig(t)
+	assert.EqualError(t, cfg.Validate(), "db-dsn: cannot be blank.")
+	assert.EqualError(t, cfg.API.Validate(), "api-port: cannot be blank.")
+	assert.EqualError(t, cfg.Web.Validate(), "web-port: cannot be blank; web-url: cannot be blank.")
+
+	cfg = newTestConfig(t,
+		"-db-dsn", "sqlite:mono.db",
+		"-trusted-proxies", "10.0.0.0/8 2001:db8::1",
+		"-api-port", "4000",
+		"-cors-trusted-origins", "http://localhost:9000",
+		"-web-port", "5000",
+		"-web-url", "http://localhost:5000",
+	)
+	assert.NoError(t, cfg.Validate())
+	assert.NoError(t, cfg.API.Validate())
+	assert.NoError(t, cfg.Web.Validate())
+	assert.Equal(t, int64(DefaultBodyMaxBytes), cfg.API.BodyMaxBytes)
+	assert.True(t, cfg.API.LegacyErrors)
+	assert.Equal(t, []string{"10.0.0.0/8", "2001:db8::1"}, cfg.TrustedProxies)
+
+	cfg = newTestConfig(t,
+		"-db-dsn", "sqlite:mono.db",
+		"-mail-transport", "file",
+		"-dkim-key-file", "dkim.pem",
+		"-api-port", "70000",
+		"-limiter-enabled",
+		"-cors-trusted-origins", "localhost:9000",
+		"-web-port", "5000",
+		"-web-url", "/path",
+		"-saml-cert", "sp.crt",
+	)
+	assert.EqualError(t, cfg.Validate(), "dkim-domain: cannot be blank; dkim-selector: cannot be blank; mail-dir: cannot be blank.")
+	assert.EqualError(t, cfg.API.Validate(), "api-port: must be no greater than 65535; cors-trusted-origins: (0: must be an http or https URL.); limiter-rps: cannot be blank.")
+	assert.EqualError(t, cfg.Web.Validate(), "saml-key: cannot be blank; web-url: must be an http or https URL.")
+
+	cfg = newTestConfig(t, "-db-dsn", "sqlite:mono.db", "-trusted-proxies", "10.0.0.1 proxy.example 10.0.0.0/33")
+	assert.EqualError(t, cfg.Validate(), "trusted-proxies: (1: must be an IP address or CIDR prefix; 2: must be an IP address or CIDR prefix.).")
+
+	cfg = newTestConfig(t, "-db-dsn", "sqlite:mono.db", "-mail-transport", "sink")
+	assert.EqualError(t, cfg.Validate(), "mail-transport: the sink transport requires -dev.")
+
+	cfg = newTestConfig(t, "-db-dsn", "sqlite:mono.db", "-mail-transport", "sink", "-dev")
+	assert.NoError(t, cfg.Validate())
+}
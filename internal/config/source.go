@@ -0,0 +1,197 @@
+package config
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variable of the config file, when the -config flag isn't set
+const FileEnv = "MONO_CONFIG"
+
+// Settings that can be given as flags, as environment variables or in a
+// config file of KEY=value lines, in that order of precedence. Empty
+// values are ignored, like unset ones.
+type Source struct {
+	fs   *flag.FlagSet
+	file string
+	// Environment variable of each flag
+	env map[string]string
+}
+
+func NewSource(fs *flag.FlagSet) *Source {
+	s := &Source{
+		fs:  fs,
+		env: map[string]string{},
+	}
+	fs.StringVar(&s.file, "config", "", fmt.Sprintf("Config file of KEY=value lines, e.g. .envrc.local ($%s)", FileEnv))
+
+	return s
+}
+
+func (s *Source) String(p *string, name, env, value, usage string) {
+	s.fs.StringVar(p, name, value, s.usage(name, env, usage))
+}
+
+func (s *Source) Int(p *int, name, env string, value int, usage string) {
+	s.fs.IntVar(p, name, value, s.usage(name, env, usage))
+}
+
+func (s *Source) Int64(p *int64, name, env string, value int64, usage string) {
+	s.fs.Int64Var(p, name, value, s.usage(name, env, usage))
+}
+
+func (s *Source) Bool(p *bool, name, env string, value bool, usage string) {
+	s.fs.BoolVar(p, name, value, s.usage(name, env, usage))
+}
+
+func (s *Source) Duration(p *time.Duration, name, env string, value time.Duration, usage string) {
+	s.fs.DurationVar(p, name, value, s.usage(name, env, usage))
+}
+
+// List of space separated values
+func (s *Source) Fields(p *[]string, name, env string, value []string, usage string) {
+	*p = value
+	s.fs.Var((*fields)(p), name, s.usage(name, env, usage))
+}
+
+// Record the environment variable of the flag and mention it in the usage
+func (s *Source) usage(name, env, usage string) string {
+	if env == "" {
+		return usage
+	}
+
+	s.env[name] = env
+	return fmt.Sprintf("%s ($%s)", usage, env)
+}
+
+// Parse the flags, then set those that weren't given from the environment
+// or the config file
+func (s *Source) Parse(args []string) error {
+	err := s.fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	s.fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if !set["config"] {
+		s.file = os.Getenv(FileEnv)
+	}
+
+	var file map[string]string
+	if s.file != "" {
+		file, err = ReadFile(s.file)
+		if err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(s.env))
+	for name := range s.env {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if set[name] {
+			continue
+		}
+
+		key := s.env[name]
+		val := os.Getenv(key)
+		if val == "" {
+			val = file[key]
+		}
+		if val == "" {
+			continue
+		}
+
+		err := s.fs.Set(name, val)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// Read the KEY=value lines of the config file. Lines may start with
+// "export", as in a shell script, and values may be quoted. ${KEY} in
+// values that aren't single quoted is replaced with the value of an
+// earlier line or of the environment.
+func ReadFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := map[string]string{}
+	expand := func(key string) string {
+		if val, ok := values[key]; ok {
+			return val
+		}
+		return os.Getenv(key)
+	}
+
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, val, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=value", path, n)
+		}
+
+		val = strings.TrimSpace(val)
+		switch {
+		case len(val) >= 2 && val[0] == '\'' && val[len(val)-1] == '\'':
+			values[key] = val[1 : len(val)-1]
+		case len(val) >= 2 && val[0] == '"' && val[len(val)-1] == '"':
+			unquoted, err := strconv.Unquote(val)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+			}
+			values[key] = os.Expand(unquoted, expand)
+		default:
+			// Trailing comments of unquoted values
+			val, _, _ = strings.Cut(val, " #")
+			values[key] = os.Expand(strings.TrimSpace(val), expand)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Value of a list flag, which is set to the fields of the string
+type fields []string
+
+func (f *fields) String() string {
+	if f == nil {
+		return ""
+	}
+
+	return strings.Join(*f, " ")
+}
+
+func (f *fields) Set(val string) error {
+	*f = strings.Fields(val)
+	return nil
+}
@@ -55,6 +55,17 @@ func TokenHash(plaintextToken string) []byte {
 	return hash[:] // convert array to slice
 }
 
+// Encode a token hash as a base-32 string. A hash is not a credential, so
+// it can be shared to refer to a token, e.g. in a link to revoke a session.
+func EncodeTokenHash(hash []byte) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(hash)
+}
+
+// Decode a token hash encoded by EncodeTokenHash
+func DecodeTokenHash(s string) ([]byte, error) {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+}
+
 // Generate cryptographically secure password hash
 func PasswordHash(plaintextPassword string) ([]byte, error) {
 	hash, err := argon2id.CreateHash(plaintextPassword, argon2id.DefaultParams)
@@ -24,6 +24,19 @@ func TestTokenHash(t *testing.T) {
 	assert.NotEqual(t, hash1, hash3)
 }
 
+func TestEncodeTokenHash(t *testing.T) {
+	hash := crypto.TokenHash("test_token")
+	encoded := crypto.EncodeTokenHash(hash)
+	assert.NotContains(t, encoded, "=")
+
+	decoded, err := crypto.DecodeTokenHash(encoded)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, decoded)
+
+	_, err = crypto.DecodeTokenHash("not base32!")
+	assert.Error(t, err)
+}
+
 func TestPasswordHash(t *testing.T) {
 	plaintextPassword := "super_secure_password"
 	hash, err := crypto.PasswordHash(plaintextPassword)
@@ -7,17 +7,34 @@ import (
 	"github.com/gofrs/uuid/v5"
 )
 
-// Default expiry duration
-const AuthenticationTokenTTL = time.Hour * 36
+const (
+	// Default expiry duration
+	AuthenticationTokenTTL = time.Hour * 36
+	// How long after entering a password sensitive operations are allowed
+	FreshAuthenticationTTL = time.Minute * 15
+)
 
 type AuthenticationTokenRepository interface {
 	New(ctx context.Context, tokenHash []byte, expiry time.Time, userID uuid.UUID) error
 	Get(ctx context.Context, tokenHash []byte) (*AuthenticationToken, error)
+	Reauthenticate(ctx context.Context, tokenHash []byte) error
+	Delete(ctx context.Context, tokenHash []byte) error
 	Purge(ctx context.Context, userID uuid.UUID) error
+	// Tokens of the user that haven't expired, latest expiry first
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]*AuthenticationToken, error)
+	// Delete up to limit expired tokens. Returns the number deleted.
+	DeleteExpired(ctx context.Context, limit int) (int, error)
 }
 
 type AuthenticationToken struct {
-	Hash   []byte    `json:"-"`
-	Expiry time.Time `json:"expiry"`
-	UserID uuid.UUID `json:"-"`
+	Hash            []byte    `json:"-"`
+	Expiry          time.Time `json:"expiry"`
+	UserID          uuid.UUID `json:"-"`
+	AuthenticatedAt time.Time `json:"authenticated_at"`
+}
+
+// Reports whether the user entered their password recently enough to
+// perform a sensitive operation.
+func (at *AuthenticationToken) IsFresh() bool {
+	return time.Since(at.AuthenticatedAt) < FreshAuthenticationTTL
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"github.com/micahco/mono/internal/data"
 	"github.com/stretchr/testify/assert"
 )
@@ -33,6 +34,69 @@ func runAuthenticationTokenRepositoryTests(t *testing.T, db *data.DB) {
 		assert.Equal(t, tokenHash, at.Hash)
 		assert.WithinDuration(t, at.Expiry, expiry, time.Minute)
 		assert.Equal(t, testUser.ID, at.UserID)
+		assert.WithinDuration(t, time.Now(), at.AuthenticatedAt, time.Minute)
+		assert.True(t, at.IsFresh())
+	})
+
+	t.Run("TestReauthenticate", func(t *testing.T) {
+		before, err := db.AuthenticationTokens.Get(ctx, tokenHash)
+		assert.NoError(t, err)
+
+		err = db.AuthenticationTokens.Reauthenticate(ctx, tokenHash)
+		assert.NoError(t, err)
+
+		after, err := db.AuthenticationTokens.Get(ctx, tokenHash)
+		assert.NoError(t, err)
+		assert.False(t, after.AuthenticatedAt.Before(before.AuthenticatedAt))
+
+		// Non-existant token
+		err = db.AuthenticationTokens.Reauthenticate(ctx, []byte("unknown_token"))
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("TestDelete", func(t *testing.T) {
+		otherHash := []byte("other_token")
+		err := db.AuthenticationTokens.New(ctx, otherHash, expiry, testUser.ID)
+		assert.NoError(t, err)
+
+		err = db.AuthenticationTokens.Delete(ctx, otherHash)
+		assert.NoError(t, err)
+
+		_, err = db.AuthenticationTokens.Get(ctx, otherHash)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		// Only the deleted token is affected
+		_, err = db.AuthenticationTokens.Get(ctx, tokenHash)
+		assert.NoError(t, err)
+
+		err = db.AuthenticationTokens.Delete(ctx, otherHash)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("TestListForUser", func(t *testing.T) {
+		err := db.AuthenticationTokens.New(ctx, []byte("later_token"), expiry.Add(time.Hour), testUser.ID)
+		assert.NoError(t, err)
+		err = db.AuthenticationTokens.New(ctx, []byte("old_token"), time.Now().Add(-time.Minute), testUser.ID)
+		assert.NoError(t, err)
+
+		tokens, err := db.AuthenticationTokens.ListForUser(ctx, testUser.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, tokens, 2) {
+			// Expired tokens aren't listed
+			assert.Equal(t, []byte("later_token"), tokens[0].Hash)
+			assert.Equal(t, tokenHash, tokens[1].Hash)
+			assert.Equal(t, testUser.ID, tokens[1].UserID)
+		}
+
+		err = db.AuthenticationTokens.Delete(ctx, []byte("later_token"))
+		assert.NoError(t, err)
+		err = db.AuthenticationTokens.Delete(ctx, []byte("old_token"))
+		assert.NoError(t, err)
+
+		// Unknown user
+		tokens, err = db.AuthenticationTokens.ListForUser(ctx, uuid.Must(uuid.NewV4()))
+		assert.NoError(t, err)
+		assert.Empty(t, tokens)
 	})
 
 	t.Run("TestPurge", func(t *testing.T) {
@@ -42,4 +106,22 @@ func runAuthenticationTokenRepositoryTests(t *testing.T, db *data.DB) {
 		_, err := db.AuthenticationTokens.Get(ctx, tokenHash)
 		assert.ErrorIs(t, err, data.ErrRecordNotFound)
 	})
+
+	t.Run("TestDeleteExpired", func(t *testing.T) {
+		expiredHash := []byte("expired_token")
+		err := db.AuthenticationTokens.New(ctx, expiredHash, time.Now().Add(-time.Minute), testUser.ID)
+		assert.NoError(t, err)
+		err = db.AuthenticationTokens.New(ctx, tokenHash, expiry, testUser.ID)
+		assert.NoError(t, err)
+
+		n, err := db.AuthenticationTokens.DeleteExpired(ctx, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		_, err = db.AuthenticationTokens.Get(ctx, expiredHash)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		_, err = db.AuthenticationTokens.Get(ctx, tokenHash)
+		assert.NoError(t, err)
+	})
 }
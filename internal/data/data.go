@@ -1,17 +1,53 @@
 package data
 
-import "errors"
+import (
+	"context"
+	"errors"
+)
 
 // Sentinel errors
 var (
-	ErrRecordNotFound = errors.New("data: no matching record found")
-	ErrDuplicateEmail = errors.New("data: duplicate email")
-	ErrExpiredToken   = errors.New("data: expired token")
-	ErrEditConflict   = errors.New("data: edit conflict")
+	ErrRecordNotFound      = errors.New("data: no matching record found")
+	ErrDuplicateEmail      = errors.New("data: duplicate email")
+	ErrDuplicateExternalID = errors.New("data: duplicate external id")
+	ErrExpiredToken        = errors.New("data: expired token")
+	ErrEditConflict        = errors.New("data: edit conflict")
+	ErrReplayedAssertion   = errors.New("data: replayed assertion")
+	ErrSuppressedAddress   = errors.New("data: suppressed address")
+	ErrThrottled           = errors.New("data: throttled")
+	ErrNoTransactor        = errors.New("data: no transactor")
 )
 
 type DB struct {
 	Users                UserRepository
 	VerificationTokens   VerificationTokenRepository
 	AuthenticationTokens AuthenticationTokenRepository
+	Devices              DeviceRepository
+	SAML                 SAMLRepository
+	Outbox               OutboxRepository
+	Suppressions         SuppressionRepository
+	Throttle             ThrottleRepository
+	Stats                StatsRepository
+	Transactor           Transactor
+}
+
+// Runs functions in a database transaction. Within fn, use only tx: the
+// DB that began the transaction may wait for it to end, which it never
+// does. Transactions begun on tx are savepoints on every backend, so an
+// inner rollback only undoes the changes of the inner function.
+type Transactor interface {
+	// Call fn with repositories bound to a new transaction. The
+	// transaction commits if fn returns nil and rolls back otherwise.
+	WithTx(ctx context.Context, fn func(tx *DB) error) error
+}
+
+// Run fn in a transaction so that its changes are applied together or
+// not at all. Returns ErrNoTransactor, without calling fn, if the DB has
+// no Transactor.
+func (db *DB) WithTx(ctx context.Context, fn func(tx *DB) error) error {
+	if db.Transactor == nil {
+		return ErrNoTransactor
+	}
+
+	return db.Transactor.WithTx(ctx, fn)
 }
@@ -0,0 +1,39 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Devices are the IP address and user agent pairs a user has logged in from
+type DeviceRepository interface {
+	// Record a login from the device. Reports whether the user has
+	// logged in from it before.
+	Remember(ctx context.Context, userID uuid.UUID, ip, userAgent string) (bool, error)
+	// Record the session store key of a login from a new device, so that
+	// the user can sign it out by the hash of the key
+	NewSession(ctx context.Context, sessionHash []byte, session string, expiry time.Time, userID uuid.UUID) error
+	// Delete the session of the hash and return its store key. Returns
+	// ErrRecordNotFound if there is none or it expired.
+	PopSession(ctx context.Context, sessionHash []byte) (string, error)
+	// Delete up to limit expired sessions. Returns the number deleted.
+	DeleteExpiredSessions(ctx context.Context, limit int) (int, error)
+}
+
+type Device struct {
+	UserID     uuid.UUID `json:"-"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// Session of a login from a new device
+type DeviceSession struct {
+	Hash    []byte
+	Session string
+	Expiry  time.Time
+	UserID  uuid.UUID
+}
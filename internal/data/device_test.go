@@ -0,0 +1,62 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func runDeviceRepositoryTests(t *testing.T, db *data.DB) {
+	ctx := context.Background()
+	testEmail := "test@email.com"
+	validPassword := []byte("super_secret_password")
+	testIP := "203.0.113.7"
+	testUserAgent := "Mozilla/5.0"
+
+	// Create a test user
+	testUser, err := db.Users.New(ctx, testEmail, validPassword)
+	assert.NoError(t, err)
+
+	t.Run("TestRemember", func(t *testing.T) {
+		known, err := db.Devices.Remember(ctx, testUser.ID, testIP, testUserAgent)
+		assert.NoError(t, err)
+		assert.False(t, known)
+
+		known, err = db.Devices.Remember(ctx, testUser.ID, testIP, testUserAgent)
+		assert.NoError(t, err)
+		assert.True(t, known)
+
+		// Different user agent from the same address
+		known, err = db.Devices.Remember(ctx, testUser.ID, testIP, "curl/8.0")
+		assert.NoError(t, err)
+		assert.False(t, known)
+	})
+
+	t.Run("TestPopSession", func(t *testing.T) {
+		hash := []byte("session_hash")
+		err := db.Devices.NewSession(ctx, hash, "session_token", time.Now().Add(time.Hour), testUser.ID)
+		assert.NoError(t, err)
+
+		session, err := db.Devices.PopSession(ctx, hash)
+		assert.NoError(t, err)
+		assert.Equal(t, "session_token", session)
+
+		// Sessions are only signed out once
+		_, err = db.Devices.PopSession(ctx, hash)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		expiredHash := []byte("expired_session_hash")
+		err = db.Devices.NewSession(ctx, expiredHash, "expired_session_token", time.Now().Add(-time.Minute), testUser.ID)
+		assert.NoError(t, err)
+
+		_, err = db.Devices.PopSession(ctx, expiredHash)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		n, err := db.Devices.DeleteExpiredSessions(ctx, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+	})
+}
@@ -0,0 +1,130 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type AuthenticationTokenRepository struct {
+	s *store
+}
+
+func (r *AuthenticationTokenRepository) New(ctx context.Context, tokenHash []byte, expiry time.Time, userID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.authenticationTokens[string(tokenHash)]; ok {
+		return errDuplicateKey
+	}
+	if _, ok := r.s.users[userID]; !ok {
+		return errForeignKey
+	}
+
+	r.s.authenticationTokens[string(tokenHash)] = &data.AuthenticationToken{
+		Hash:            clone(tokenHash),
+		Expiry:          expiry,
+		UserID:          userID,
+		AuthenticatedAt: now(),
+	}
+
+	return nil
+}
+
+func (r *AuthenticationTokenRepository) Get(ctx context.Context, tokenHash []byte) (*data.AuthenticationToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	at, ok := r.s.authenticationTokens[string(tokenHash)]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	c := *at
+	c.Hash = clone(at.Hash)
+
+	return &c, nil
+}
+
+// Mark the token as freshly authenticated after the user re-entered
+// their password.
+func (r *AuthenticationTokenRepository) Reauthenticate(ctx context.Context, tokenHash []byte) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	at, ok := r.s.authenticationTokens[string(tokenHash)]
+	if !ok {
+		return data.ErrRecordNotFound
+	}
+
+	at.AuthenticatedAt = now()
+
+	return nil
+}
+
+func (r *AuthenticationTokenRepository) Delete(ctx context.Context, tokenHash []byte) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.authenticationTokens[string(tokenHash)]; !ok {
+		return data.ErrRecordNotFound
+	}
+
+	delete(r.s.authenticationTokens, string(tokenHash))
+
+	return nil
+}
+
+func (r *AuthenticationTokenRepository) Purge(ctx context.Context, userID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for k, at := range r.s.authenticationTokens {
+		if at.UserID == userID {
+			delete(r.s.authenticationTokens, k)
+		}
+	}
+
+	return nil
+}
+
+func (r *AuthenticationTokenRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]*data.AuthenticationToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	tokens := []*data.AuthenticationToken{}
+	for _, at := range r.s.authenticationTokens {
+		if at.UserID == userID && time.Now().Before(at.Expiry) {
+			c := *at
+			c.Hash = clone(at.Hash)
+			tokens = append(tokens, &c)
+		}
+	}
+
+	slices.SortFunc(tokens, func(a, b *data.AuthenticationToken) int {
+		return b.Expiry.Compare(a.Expiry)
+	})
+
+	return tokens, nil
+}
+
+func (r *AuthenticationTokenRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var n int
+	for k, at := range r.s.authenticationTokens {
+		if n == limit {
+			break
+		}
+		if time.Now().After(at.Expiry) {
+			delete(r.s.authenticationTokens, k)
+			n++
+		}
+	}
+
+	return n, nil
+}
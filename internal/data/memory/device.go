@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+// Primary key of a device
+type deviceKey struct {
+	userID    uuid.UUID
+	ip        string
+	userAgent string
+}
+
+type DeviceRepository struct {
+	s *store
+}
+
+func (r *DeviceRepository) Remember(ctx context.Context, userID uuid.UUID, ip, userAgent string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[userID]; !ok {
+		return false, errForeignKey
+	}
+
+	key := deviceKey{userID, ip, userAgent}
+
+	d, ok := r.s.devices[key]
+	if ok {
+		d.LastSeenAt = now()
+		return true, nil
+	}
+
+	t := now()
+	r.s.devices[key] = &data.Device{
+		UserID:     userID,
+		IP:         ip,
+		UserAgent:  userAgent,
+		CreatedAt:  t,
+		LastSeenAt: t,
+	}
+
+	return false, nil
+}
+
+func (r *DeviceRepository) NewSession(ctx context.Context, sessionHash []byte, session string, expiry time.Time, userID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.deviceSessions[string(sessionHash)]; ok {
+		return errDuplicateKey
+	}
+	if _, ok := r.s.users[userID]; !ok {
+		return errForeignKey
+	}
+
+	r.s.deviceSessions[string(sessionHash)] = &data.DeviceSession{
+		Hash:    clone(sessionHash),
+		Session: session,
+		Expiry:  expiry,
+		UserID:  userID,
+	}
+
+	return nil
+}
+
+func (r *DeviceRepository) PopSession(ctx context.Context, sessionHash []byte) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	ds, ok := r.s.deviceSessions[string(sessionHash)]
+	if !ok || time.Now().After(ds.Expiry) {
+		return "", data.ErrRecordNotFound
+	}
+
+	delete(r.s.deviceSessions, string(sessionHash))
+
+	return ds.Session, nil
+}
+
+func (r *DeviceRepository) DeleteExpiredSessions(ctx context.Context, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var n int
+	for k, ds := range r.s.deviceSessions {
+		if n == limit {
+			break
+		}
+		if time.Now().After(ds.Expiry) {
+			delete(r.s.deviceSessions, k)
+			n++
+		}
+	}
+
+	return n, nil
+}
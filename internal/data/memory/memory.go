@@ -0,0 +1,221 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+// Errors for constraints that Postgres enforces with its own error codes
+var (
+	errDuplicateKey = errors.New("memory: duplicate key")
+	errForeignKey   = errors.New("memory: foreign key violation")
+)
+
+// In-memory database with the same semantics as the Postgres database.
+// Useful for tests and local demos; nothing is persisted.
+type MemoryDB struct {
+	*data.DB
+}
+
+func NewMemoryDB() *MemoryDB {
+	s := &store{
+		mu: &sync.RWMutex{},
+		tables: &tables{
+			users:                make(map[uuid.UUID]*data.User),
+			verificationTokens:   make(map[string]*data.VerificationToken),
+			authenticationTokens: make(map[string]*data.AuthenticationToken),
+			devices:              make(map[deviceKey]*data.Device),
+			deviceSessions:       make(map[string]*data.DeviceSession),
+			samlProviders:        make(map[uuid.UUID]*data.SAMLProvider),
+			samlUsers:            make(map[uuid.UUID]uuid.UUID),
+			samlRequests:         make(map[string]*data.SAMLRequest),
+			samlAssertions:       make(map[string]time.Time),
+			outbox:               make(map[uuid.UUID]*data.OutboxMessage),
+			suppressions:         make(map[string]*data.Suppression),
+		},
+	}
+
+	db := newDB(s)
+	db.Transactor = &transactor{s}
+
+	return &MemoryDB{DB: db}
+}
+
+func newDB(s *store) *data.DB {
+	return &data.DB{
+		Users:                &UserRepository{s},
+		VerificationTokens:   &VerificationTokenRepository{s},
+		AuthenticationTokens: &AuthenticationTokenRepository{s},
+		Devices:              &DeviceRepository{s},
+		SAML:                 &SAMLRepository{s},
+		Outbox:               &OutboxRepository{s},
+		Suppressions:         &SuppressionRepository{s},
+		Throttle:             &ThrottleRepository{s},
+		Stats:                &StatsRepository{s},
+	}
+}
+
+// Tables shared by the repositories. A single lock guards every table so
+// that joins and cascading deletes are consistent.
+type store struct {
+	mu locker
+	*tables
+}
+
+type tables struct {
+	users                map[uuid.UUID]*data.User
+	verificationTokens   map[string]*data.VerificationToken
+	authenticationTokens map[string]*data.AuthenticationToken
+	devices              map[deviceKey]*data.Device
+	deviceSessions       map[string]*data.DeviceSession
+	samlProviders        map[uuid.UUID]*data.SAMLProvider
+	samlUsers            map[uuid.UUID]uuid.UUID // provider ID of each linked user
+	samlRequests         map[string]*data.SAMLRequest
+	samlAssertions       map[string]time.Time
+	outbox               map[uuid.UUID]*data.OutboxMessage
+	suppressions         map[string]*data.Suppression
+	throttle             []throttleRow
+}
+
+type locker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// Lock of a transaction, which already holds the store's lock
+type nopLocker struct{}
+
+func (nopLocker) Lock()    {}
+func (nopLocker) Unlock()  {}
+func (nopLocker) RLock()   {}
+func (nopLocker) RUnlock() {}
+
+// Transactions hold the store's lock until they end, and roll back by
+// restoring a copy of the tables taken when they began. Transactions
+// started within a transaction are savepoints, like on Postgres.
+type transactor struct {
+	s *store
+}
+
+func (t *transactor) WithTx(ctx context.Context, fn func(tx *data.DB) error) error {
+	t.s.mu.Lock()
+	defer t.s.mu.Unlock()
+
+	snapshot := t.s.tables.clone()
+
+	err := fn(newTxDB(&store{nopLocker{}, t.s.tables}))
+	if err != nil {
+		t.s.tables = snapshot
+		return err
+	}
+
+	return nil
+}
+
+// Repositories of a transaction on the store, which already holds the
+// lock
+func newTxDB(s *store) *data.DB {
+	db := newDB(s)
+	db.Transactor = &savepointer{s}
+
+	return db
+}
+
+// Savepoints roll back by restoring a copy of the tables in place, since
+// the enclosing transaction shares them
+type savepointer struct {
+	s *store
+}
+
+func (sp *savepointer) WithTx(ctx context.Context, fn func(tx *data.DB) error) error {
+	snapshot := sp.s.tables.clone()
+
+	err := fn(newTxDB(sp.s))
+	if err != nil {
+		*sp.s.tables = *snapshot
+		return err
+	}
+
+	return nil
+}
+
+// Copy of the tables. Rows are copied too since repositories update them
+// in place.
+func (t *tables) clone() *tables {
+	c := &tables{
+		users:                make(map[uuid.UUID]*data.User, len(t.users)),
+		verificationTokens:   make(map[string]*data.VerificationToken, len(t.verificationTokens)),
+		authenticationTokens: make(map[string]*data.AuthenticationToken, len(t.authenticationTokens)),
+		devices:              make(map[deviceKey]*data.Device, len(t.devices)),
+		deviceSessions:       make(map[string]*data.DeviceSession, len(t.deviceSessions)),
+		samlProviders:        make(map[uuid.UUID]*data.SAMLProvider, len(t.samlProviders)),
+		samlUsers:            maps.Clone(t.samlUsers),
+		samlRequests:         make(map[string]*data.SAMLRequest, len(t.samlRequests)),
+		samlAssertions:       maps.Clone(t.samlAssertions),
+		outbox:               make(map[uuid.UUID]*data.OutboxMessage, len(t.outbox)),
+		suppressions:         make(map[string]*data.Suppression, len(t.suppressions)),
+		throttle:             slices.Clone(t.throttle),
+	}
+
+	for k, v := range t.users {
+		row := *v
+		c.users[k] = &row
+	}
+	for k, v := range t.verificationTokens {
+		row := *v
+		c.verificationTokens[k] = &row
+	}
+	for k, v := range t.authenticationTokens {
+		row := *v
+		c.authenticationTokens[k] = &row
+	}
+	for k, v := range t.devices {
+		row := *v
+		c.devices[k] = &row
+	}
+	for k, v := range t.deviceSessions {
+		row := *v
+		c.deviceSessions[k] = &row
+	}
+	for k, v := range t.samlProviders {
+		row := *v
+		c.samlProviders[k] = &row
+	}
+	for k, v := range t.samlRequests {
+		row := *v
+		c.samlRequests[k] = &row
+	}
+	for k, v := range t.outbox {
+		row := *v
+		c.outbox[k] = &row
+	}
+	for k, v := range t.suppressions {
+		row := *v
+		c.suppressions[k] = &row
+	}
+
+	return c
+}
+
+// Timestamp with the precision of a Postgres TIMESTAMPTZ
+func now() time.Time {
+	return time.Now().Truncate(time.Microsecond)
+}
+
+// Copy of a byte slice so callers can't modify stored values
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+
+	return append([]byte{}, b...)
+}
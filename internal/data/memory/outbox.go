@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type OutboxRepository struct {
+	s *store
+}
+
+func (r *OutboxRepository) New(ctx context.Context, msg *data.OutboxMessage) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	msg.ID = id
+	msg.CreatedAt = now()
+	msg.Status = data.OutboxStatusPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = msg.CreatedAt
+
+	c := *msg
+	r.s.outbox[msg.ID] = &c
+
+	return nil
+}
+
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, leaseExpiry time.Time) ([]*data.OutboxMessage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	due := []*data.OutboxMessage{}
+	for _, msg := range r.s.outbox {
+		if msg.Status == data.OutboxStatusPending && !msg.NextAttemptAt.After(time.Now()) {
+			due = append(due, msg)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b *data.OutboxMessage) int {
+		return a.NextAttemptAt.Compare(b.NextAttemptAt)
+	})
+
+	msgs := []*data.OutboxMessage{}
+	for _, msg := range due[:min(limit, len(due))] {
+		msg.NextAttemptAt = leaseExpiry.Truncate(time.Microsecond)
+
+		c := *msg
+		msgs = append(msgs, &c)
+	}
+
+	return msgs, nil
+}
+
+func (r *OutboxRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.outbox[id]; !ok {
+		return data.ErrRecordNotFound
+	}
+
+	delete(r.s.outbox, id)
+
+	return nil
+}
+
+func (r *OutboxRepository) Fail(ctx context.Context, id uuid.UUID, lastError string, nextAttempt time.Time, dead bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	msg, ok := r.s.outbox[id]
+	if !ok {
+		return data.ErrRecordNotFound
+	}
+
+	msg.Attempts++
+	msg.LastError = lastError
+	msg.NextAttemptAt = nextAttempt.Truncate(time.Microsecond)
+	msg.Status = data.OutboxStatusPending
+	if dead {
+		msg.Status = data.OutboxStatusDead
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) GetDead(ctx context.Context) ([]*data.OutboxMessage, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	msgs := []*data.OutboxMessage{}
+	for _, msg := range r.s.outbox {
+		if msg.Status == data.OutboxStatusDead {
+			c := *msg
+			msgs = append(msgs, &c)
+		}
+	}
+
+	slices.SortFunc(msgs, func(a, b *data.OutboxMessage) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return msgs, nil
+}
+
+func (r *OutboxRepository) Retry(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	msg, ok := r.s.outbox[id]
+	if !ok || msg.Status != data.OutboxStatusDead {
+		return data.ErrRecordNotFound
+	}
+
+	msg.Status = data.OutboxStatusPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = now()
+
+	return nil
+}
+
+func (r *OutboxRepository) DeleteDead(ctx context.Context, before time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var n int
+	for id, msg := range r.s.outbox {
+		if n == limit {
+			break
+		}
+		if msg.Status == data.OutboxStatusDead && msg.CreatedAt.Before(before) {
+			delete(r.s.outbox, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
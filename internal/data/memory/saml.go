@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type SAMLRepository struct {
+	s *store
+}
+
+func (r *SAMLRepository) NewProvider(ctx context.Context, p *data.SAMLProvider) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, other := range r.s.samlProviders {
+		if other.Slug == p.Slug || strings.EqualFold(other.Domain, p.Domain) {
+			return errDuplicateKey
+		}
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+
+	p.ID = id
+	p.CreatedAt = now()
+
+	c := *p
+	r.s.samlProviders[p.ID] = &c
+
+	return nil
+}
+
+func (r *SAMLRepository) GetProvider(ctx context.Context, slug string) (*data.SAMLProvider, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, p := range r.s.samlProviders {
+		if p.Slug == slug {
+			c := *p
+			return &c, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (r *SAMLRepository) GetProviderWithDomain(ctx context.Context, domain string) (*data.SAMLProvider, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, p := range r.s.samlProviders {
+		if strings.EqualFold(p.Domain, domain) {
+			c := *p
+			return &c, nil
+		}
+	}
+
+	return nil, data.ErrRecordNotFound
+}
+
+func (r *SAMLRepository) GetProviderWithUser(ctx context.Context, userID uuid.UUID) (*data.SAMLProvider, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	providerID, ok := r.s.samlUsers[userID]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	c := *r.s.samlProviders[providerID]
+
+	return &c, nil
+}
+
+func (r *SAMLRepository) LinkUser(ctx context.Context, userID, providerID uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[userID]; !ok {
+		return errForeignKey
+	}
+	if _, ok := r.s.samlProviders[providerID]; !ok {
+		return errForeignKey
+	}
+
+	r.s.samlUsers[userID] = providerID
+
+	return nil
+}
+
+func (r *SAMLRepository) NewRequest(ctx context.Context, stateHash []byte, expiry time.Time, providerID uuid.UUID, requestID, returnTo string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.samlRequests[string(stateHash)]; ok {
+		return errDuplicateKey
+	}
+	if _, ok := r.s.samlProviders[providerID]; !ok {
+		return errForeignKey
+	}
+
+	r.s.samlRequests[string(stateHash)] = &data.SAMLRequest{
+		Hash:       clone(stateHash),
+		Expiry:     expiry,
+		ProviderID: providerID,
+		RequestID:  requestID,
+		ReturnTo:   returnTo,
+	}
+
+	return nil
+}
+
+func (r *SAMLRepository) ConsumeRequest(ctx context.Context, stateHash []byte) (*data.SAMLRequest, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	req, ok := r.s.samlRequests[string(stateHash)]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	delete(r.s.samlRequests, string(stateHash))
+
+	if time.Now().After(req.Expiry) {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return req, nil
+}
+
+func (r *SAMLRepository) RememberAssertion(ctx context.Context, assertionID string, expiry time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.samlAssertions[assertionID]; ok {
+		return data.ErrReplayedAssertion
+	}
+
+	r.s.samlAssertions[assertionID] = expiry
+
+	return nil
+}
+
+func (r *SAMLRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var requests, assertions int
+	for k, req := range r.s.samlRequests {
+		if requests == limit {
+			break
+		}
+		if time.Now().After(req.Expiry) {
+			delete(r.s.samlRequests, k)
+			requests++
+		}
+	}
+	for id, expiry := range r.s.samlAssertions {
+		if assertions == limit {
+			break
+		}
+		if time.Now().After(expiry) {
+			delete(r.s.samlAssertions, id)
+			assertions++
+		}
+	}
+
+	return requests + assertions, nil
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+type StatsRepository struct {
+	s *store
+}
+
+func (r *StatsRepository) Get(ctx context.Context) (*data.Stats, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	stats := data.Stats{
+		VerificationTokens: map[string]int{},
+	}
+
+	for _, u := range r.s.users {
+		stats.Users++
+		if u.Active {
+			stats.ActiveUsers++
+		}
+		if u.Locked {
+			stats.LockedUsers++
+		}
+	}
+
+	t := time.Now()
+	for _, at := range r.s.authenticationTokens {
+		if t.Before(at.Expiry) {
+			stats.AuthenticationTokens++
+		} else {
+			stats.ExpiredAuthenticationTokens++
+		}
+	}
+	for _, vt := range r.s.verificationTokens {
+		if t.Before(vt.Expiry) {
+			stats.VerificationTokens[vt.Scope]++
+		} else {
+			stats.ExpiredVerificationTokens++
+		}
+	}
+
+	return &stats, nil
+}
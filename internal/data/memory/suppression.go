@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"strings"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+type SuppressionRepository struct {
+	s *store
+}
+
+func (r *SuppressionRepository) Record(ctx context.Context, email, kind, diagnostic string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	key := strings.ToLower(email)
+	s, ok := r.s.suppressions[key]
+	if !ok {
+		s = &data.Suppression{
+			Email:     email,
+			CreatedAt: now(),
+		}
+		r.s.suppressions[key] = s
+	}
+
+	s.Kind = kind
+	s.Diagnostic = diagnostic
+	s.UpdatedAt = now()
+	if kind == data.BounceSoft {
+		s.SoftBounces++
+	} else {
+		s.Suppressed = true
+	}
+	if s.SoftBounces >= data.SoftBounceLimit {
+		s.Suppressed = true
+	}
+
+	return nil
+}
+
+func (r *SuppressionRepository) Get(ctx context.Context, email string) (*data.Suppression, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	s, ok := r.s.suppressions[strings.ToLower(email)]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	c := *s
+
+	return &c, nil
+}
+
+func (r *SuppressionRepository) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.s.isSuppressed(email), nil
+}
+
+func (r *SuppressionRepository) Delete(ctx context.Context, email string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	key := strings.ToLower(email)
+	if _, ok := r.s.suppressions[key]; !ok {
+		return data.ErrRecordNotFound
+	}
+
+	delete(r.s.suppressions, key)
+
+	return nil
+}
+
+// Must be called with the store's lock held
+func (s *store) isSuppressed(email string) bool {
+	suppression, ok := s.suppressions[strings.ToLower(email)]
+
+	return ok && suppression.Suppressed
+}
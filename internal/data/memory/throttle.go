@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+type ThrottleRepository struct {
+	s *store
+}
+
+// Email counted by the throttle
+type throttleRow struct {
+	email     string
+	ip        string
+	createdAt time.Time
+}
+
+func (r *ThrottleRepository) Acquire(ctx context.Context, email, ip string, limits data.ThrottleLimits) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	t := now()
+	var fromIP, hourly, daily int
+	for _, row := range r.s.throttle {
+		if strings.EqualFold(row.email, email) && row.createdAt.After(t.Add(-limits.Cooldown)) {
+			return data.ErrThrottled
+		}
+		if row.createdAt.After(t.Add(-time.Hour)) {
+			hourly++
+			if row.ip == ip {
+				fromIP++
+			}
+		}
+		if row.createdAt.After(t.Add(-24 * time.Hour)) {
+			daily++
+		}
+	}
+
+	if exceeds(fromIP, limits.PerIP) || exceeds(hourly, limits.Hourly) || exceeds(daily, limits.Daily) {
+		return data.ErrThrottled
+	}
+
+	r.s.throttle = append(r.s.throttle, throttleRow{
+		email:     email,
+		ip:        ip,
+		createdAt: t,
+	})
+
+	return nil
+}
+
+func (r *ThrottleRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	cutoff := now().Add(-data.ThrottleWindow)
+	rows := r.s.throttle[:0]
+	var n int
+	for _, row := range r.s.throttle {
+		if n < limit && row.createdAt.Before(cutoff) {
+			n++
+			continue
+		}
+		rows = append(rows, row)
+	}
+	r.s.throttle = rows
+
+	return n, nil
+}
+
+// Whether another email would exceed the limit, if there is one
+func exceeds(count, limit int) bool {
+	return limit > 0 && count >= limit
+}
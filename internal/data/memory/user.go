@@ -0,0 +1,247 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type UserRepository struct {
+	s *store
+}
+
+func (r *UserRepository) New(ctx context.Context, email string, passwordHash []byte) (*data.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.s.userWithEmail(email) != nil {
+		return nil, data.ErrDuplicateEmail
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	u := &data.User{
+		ID:           id,
+		Version:      1,
+		CreatedAt:    now(),
+		Email:        email,
+		PasswordHash: clone(passwordHash),
+		Active:       true,
+		Locale:       "en",
+	}
+	r.s.users[u.ID] = u
+
+	return copyUser(u), nil
+}
+
+func (r *UserRepository) Get(ctx context.Context, id uuid.UUID) (*data.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return copyUser(u), nil
+}
+
+func (r *UserRepository) GetWithEmail(ctx context.Context, email string) (*data.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	u := r.s.userWithEmail(email)
+	if u == nil {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return copyUser(u), nil
+}
+
+func (r *UserRepository) GetWithVerificationToken(ctx context.Context, scope string, tokenHash []byte) (*data.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	vt, ok := r.s.verificationTokens[string(tokenHash)]
+	if !ok || vt.Scope != scope {
+		return nil, data.ErrRecordNotFound
+	}
+
+	u := r.s.userWithEmail(vt.Email)
+	if u == nil {
+		return nil, data.ErrRecordNotFound
+	}
+
+	if time.Now().After(vt.Expiry) {
+		return nil, data.ErrExpiredToken
+	}
+
+	return copyUser(u), nil
+}
+
+func (r *UserRepository) GetWithAuthenticationToken(ctx context.Context, tokenHash []byte) (*data.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	at, ok := r.s.authenticationTokens[string(tokenHash)]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	u, ok := r.s.users[at.UserID]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	if time.Now().After(at.Expiry) {
+		return nil, data.ErrExpiredToken
+	}
+
+	return copyUser(u), nil
+}
+
+func (r *UserRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	_, ok := r.s.users[id]
+
+	return ok, nil
+}
+
+func (r *UserRepository) ExistsWithEmail(ctx context.Context, email string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.s.userWithEmail(email) != nil, nil
+}
+
+// List the users matching the filter ordered by creation. Also returns
+// the total number of matching users.
+func (r *UserRepository) List(ctx context.Context, filter data.UserFilter, offset, limit int) ([]*data.User, int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var matches []*data.User
+	for _, u := range r.s.users {
+		if filter.Email != "" && !strings.EqualFold(u.Email, filter.Email) {
+			continue
+		}
+		if filter.ExternalID != "" && u.ExternalID != filter.ExternalID {
+			continue
+		}
+		if filter.DisplayName != "" && u.DisplayName != filter.DisplayName {
+			continue
+		}
+		if filter.Active != nil && u.Active != *filter.Active {
+			continue
+		}
+
+		matches = append(matches, u)
+	}
+
+	slices.SortFunc(matches, func(a, b *data.User) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+
+		return bytes.Compare(a.ID.Bytes(), b.ID.Bytes())
+	})
+
+	users := []*data.User{}
+	for i := offset; i < len(matches) && i < offset+limit; i++ {
+		users = append(users, copyUser(matches[i]))
+	}
+
+	return users, len(matches), nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, u *data.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	current, ok := r.s.users[u.ID]
+	if !ok || current.Version != u.Version {
+		return data.ErrEditConflict
+	}
+
+	for _, other := range r.s.users {
+		if other.ID == u.ID {
+			continue
+		}
+		if u.ExternalID != "" && other.ExternalID == u.ExternalID {
+			return data.ErrDuplicateExternalID
+		}
+		if strings.EqualFold(other.Email, u.Email) {
+			return data.ErrDuplicateEmail
+		}
+	}
+
+	u.Version++
+	r.s.users[u.ID] = copyUser(u)
+
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	_, ok := r.s.users[id]
+	if !ok {
+		return data.ErrRecordNotFound
+	}
+
+	delete(r.s.users, id)
+
+	// ON DELETE CASCADE
+	for k, at := range r.s.authenticationTokens {
+		if at.UserID == id {
+			delete(r.s.authenticationTokens, k)
+		}
+	}
+	for k, vt := range r.s.verificationTokens {
+		if vt.UserID.Valid && vt.UserID.UUID == id {
+			delete(r.s.verificationTokens, k)
+		}
+	}
+	for k := range r.s.devices {
+		if k.userID == id {
+			delete(r.s.devices, k)
+		}
+	}
+	for k, ds := range r.s.deviceSessions {
+		if ds.UserID == id {
+			delete(r.s.deviceSessions, k)
+		}
+	}
+	delete(r.s.samlUsers, id)
+
+	return nil
+}
+
+// Emails are case insensitive like the CITEXT column. Requires the lock.
+func (s *store) userWithEmail(email string) *data.User {
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return u
+		}
+	}
+
+	return nil
+}
+
+func copyUser(u *data.User) *data.User {
+	c := *u
+	c.PasswordHash = clone(u.PasswordHash)
+
+	return &c
+}
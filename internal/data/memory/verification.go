@@ -0,0 +1,139 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type VerificationTokenRepository struct {
+	s *store
+}
+
+// Create a verification token for an email without an associated User.
+// Returns ErrSuppressedAddress if mail to the email is suppressed.
+func (r *VerificationTokenRepository) New(ctx context.Context, tokenHash []byte, expiry time.Time, scope, email string) error {
+	vt := &data.VerificationToken{
+		Hash:   clone(tokenHash),
+		Expiry: expiry,
+		Scope:  scope,
+		Email:  email,
+	}
+
+	return r.insert(vt)
+}
+
+// Create a verification token for an email that belongs to an existing User.
+// Returns ErrSuppressedAddress if mail to the email is suppressed.
+func (r *VerificationTokenRepository) NewForUser(ctx context.Context, tokenHash []byte, expiry time.Time, scope, email string, userID uuid.UUID) error {
+	vt := &data.VerificationToken{
+		Hash:   clone(tokenHash),
+		Expiry: expiry,
+		Scope:  scope,
+		Email:  email,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+
+	return r.insert(vt)
+}
+
+func (r *VerificationTokenRepository) insert(vt *data.VerificationToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.verificationTokens[string(vt.Hash)]; ok {
+		return errDuplicateKey
+	}
+	if r.s.isSuppressed(vt.Email) {
+		return data.ErrSuppressedAddress
+	}
+	if vt.UserID.Valid {
+		if _, ok := r.s.users[vt.UserID.UUID]; !ok {
+			return errForeignKey
+		}
+	}
+
+	r.s.verificationTokens[string(vt.Hash)] = vt
+
+	return nil
+}
+
+func (r *VerificationTokenRepository) Get(ctx context.Context, tokenHash []byte) (*data.VerificationToken, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	vt, ok := r.s.verificationTokens[string(tokenHash)]
+	if !ok {
+		return nil, data.ErrRecordNotFound
+	}
+
+	c := *vt
+	c.Hash = clone(vt.Hash)
+
+	return &c, nil
+}
+
+func (r *VerificationTokenRepository) Exists(ctx context.Context, scope, email string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, vt := range r.s.verificationTokens {
+		if vt.Scope == scope && strings.EqualFold(vt.Email, email) && time.Now().Before(vt.Expiry) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *VerificationTokenRepository) Purge(ctx context.Context, email string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for k, vt := range r.s.verificationTokens {
+		if strings.EqualFold(vt.Email, email) {
+			delete(r.s.verificationTokens, k)
+		}
+	}
+
+	return nil
+}
+
+func (r *VerificationTokenRepository) Consume(ctx context.Context, tokenHash []byte, scope, email string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	vt, ok := r.s.verificationTokens[string(tokenHash)]
+	if !ok || vt.Scope != scope || !strings.EqualFold(vt.Email, email) {
+		return data.ErrRecordNotFound
+	}
+
+	delete(r.s.verificationTokens, string(tokenHash))
+
+	if time.Now().After(vt.Expiry) {
+		return data.ErrExpiredToken
+	}
+
+	return nil
+}
+
+func (r *VerificationTokenRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var n int
+	for k, vt := range r.s.verificationTokens {
+		if n == limit {
+			break
+		}
+		if time.Now().After(vt.Expiry) {
+			delete(r.s.verificationTokens, k)
+			n++
+		}
+	}
+
+	return n, nil
+}
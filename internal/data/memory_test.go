@@ -0,0 +1,67 @@
+package data_test
+
+import (
+	"testing"
+
+	"github.com/micahco/mono/internal/data/memory"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	t.Parallel()
+
+	runUserRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemoryAuthenticationTokenRepository(t *testing.T) {
+	t.Parallel()
+
+	runAuthenticationTokenRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemoryVerificationTokenRepository(t *testing.T) {
+	t.Parallel()
+
+	runVerificationTokenRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemoryDeviceRepository(t *testing.T) {
+	t.Parallel()
+
+	runDeviceRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemorySAMLRepository(t *testing.T) {
+	t.Parallel()
+
+	runSAMLRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemoryOutboxRepository(t *testing.T) {
+	t.Parallel()
+
+	runOutboxRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemorySuppressionRepository(t *testing.T) {
+	t.Parallel()
+
+	runSuppressionRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemoryThrottleRepository(t *testing.T) {
+	t.Parallel()
+
+	runThrottleRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemoryStatsRepository(t *testing.T) {
+	t.Parallel()
+
+	runStatsRepositoryTests(t, memory.NewMemoryDB().DB)
+}
+
+func TestMemoryTx(t *testing.T) {
+	t.Parallel()
+
+	runTxTests(t, memory.NewMemoryDB().DB)
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const (
+	OutboxStatusPending = "pending"
+	OutboxStatusDead    = "dead"
+	// How long dead messages are kept for operators to retry. Bodies hold
+	// plaintext tokens, which have all expired by then.
+	OutboxRetention = EmailRevertTokenTTL
+)
+
+// Emails waiting to be delivered. Messages are written in the same
+// transaction as the changes they announce, so neither is lost without
+// the other.
+type OutboxRepository interface {
+	New(ctx context.Context, msg *OutboxMessage) error
+	// Lease up to limit pending messages that are due. Their next attempt
+	// moves to leaseExpiry so that other workers skip them meanwhile.
+	Claim(ctx context.Context, limit int, leaseExpiry time.Time) ([]*OutboxMessage, error)
+	// Delete a delivered message
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Record a failed attempt. The message is tried again at nextAttempt,
+	// or never if dead.
+	Fail(ctx context.Context, id uuid.UUID, lastError string, nextAttempt time.Time, dead bool) error
+	GetDead(ctx context.Context) ([]*OutboxMessage, error)
+	// Make a dead message pending again with a fresh set of attempts.
+	// Returns ErrRecordNotFound if there isn't a dead message with the id.
+	Retry(ctx context.Context, id uuid.UUID) error
+	// Delete up to limit dead messages created before the time. Delivered
+	// messages are deleted right away. Returns the number deleted.
+	DeleteDead(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type OutboxMessage struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	Sender        string    `json:"sender"`
+	Recipient     string    `json:"recipient"`
+	Subject       string    `json:"subject"`
+	Body          string    `json:"-"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+}
@@ -0,0 +1,191 @@
+package data_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runOutboxRepositoryTests(t *testing.T, db *data.DB) {
+	ctx := context.Background()
+
+	testMessage := &data.OutboxMessage{
+		Sender:    "no-reply@example.com",
+		Recipient: "test@example.com",
+		Subject:   "Registration",
+		Body:      "<p>Hello</p>",
+	}
+
+	t.Run("TestNew", func(t *testing.T) {
+		err := db.Outbox.New(ctx, testMessage)
+		assert.NoError(t, err)
+		assert.False(t, testMessage.ID.IsNil())
+		assert.False(t, testMessage.CreatedAt.IsZero())
+		assert.Equal(t, data.OutboxStatusPending, testMessage.Status)
+		assert.Equal(t, 0, testMessage.Attempts)
+	})
+
+	t.Run("TestClaim", func(t *testing.T) {
+		lease := time.Now().Add(time.Minute)
+
+		msgs, err := db.Outbox.Claim(ctx, 10, lease)
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, testMessage.ID, msgs[0].ID)
+		assert.Equal(t, testMessage.Recipient, msgs[0].Recipient)
+		assert.Equal(t, testMessage.Body, msgs[0].Body)
+		assert.WithinDuration(t, lease, msgs[0].NextAttemptAt, time.Millisecond)
+
+		// Leased messages aren't claimed again
+		msgs, err = db.Outbox.Claim(ctx, 10, lease)
+		assert.NoError(t, err)
+		assert.Empty(t, msgs)
+	})
+
+	t.Run("TestFail", func(t *testing.T) {
+		err := db.Outbox.Fail(ctx, testMessage.ID, "connection refused", time.Now().Add(-time.Second), false)
+		assert.NoError(t, err)
+
+		// Due again
+		msgs, err := db.Outbox.Claim(ctx, 10, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, 1, msgs[0].Attempts)
+		assert.Equal(t, "connection refused", msgs[0].LastError)
+
+		err = db.Outbox.Fail(ctx, testMessage.ID, "connection refused", time.Now().Add(-time.Second), true)
+		assert.NoError(t, err)
+
+		// Dead messages aren't claimed
+		msgs, err = db.Outbox.Claim(ctx, 10, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Empty(t, msgs)
+
+		err = db.Outbox.Fail(ctx, uuid.Must(uuid.NewV4()), "", time.Now(), false)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("TestGetDead", func(t *testing.T) {
+		msgs, err := db.Outbox.GetDead(ctx)
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, testMessage.ID, msgs[0].ID)
+		assert.Equal(t, data.OutboxStatusDead, msgs[0].Status)
+		assert.Equal(t, 2, msgs[0].Attempts)
+	})
+
+	t.Run("TestRetry", func(t *testing.T) {
+		err := db.Outbox.Retry(ctx, testMessage.ID)
+		assert.NoError(t, err)
+
+		// Only dead messages can be retried
+		err = db.Outbox.Retry(ctx, testMessage.ID)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		msgs, err := db.Outbox.GetDead(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, msgs)
+
+		msgs, err = db.Outbox.Claim(ctx, 10, time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, 0, msgs[0].Attempts)
+	})
+
+	t.Run("TestDelete", func(t *testing.T) {
+		err := db.Outbox.Delete(ctx, testMessage.ID)
+		assert.NoError(t, err)
+
+		err = db.Outbox.Delete(ctx, testMessage.ID)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("TestDeleteDead", func(t *testing.T) {
+		dead := &data.OutboxMessage{
+			Sender:    "no-reply@example.com",
+			Recipient: "test@example.com",
+			Subject:   "Password Reset",
+			Body:      "<p>Token</p>",
+		}
+		err := db.Outbox.New(ctx, dead)
+		require.NoError(t, err)
+		err = db.Outbox.Fail(ctx, dead.ID, "mailbox unavailable", time.Now(), true)
+		require.NoError(t, err)
+
+		pending := &data.OutboxMessage{
+			Sender:    "no-reply@example.com",
+			Recipient: "test@example.com",
+			Subject:   "Registration",
+			Body:      "<p>Hello</p>",
+		}
+		err = db.Outbox.New(ctx, pending)
+		require.NoError(t, err)
+
+		// Not old enough
+		n, err := db.Outbox.DeleteDead(ctx, time.Now().Add(-time.Hour), 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		// Only dead messages are deleted
+		n, err = db.Outbox.DeleteDead(ctx, time.Now().Add(time.Minute), 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		msgs, err := db.Outbox.GetDead(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, msgs)
+
+		err = db.Outbox.Delete(ctx, pending.ID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestClaimConcurrent", func(t *testing.T) {
+		for range 20 {
+			msg := &data.OutboxMessage{
+				Sender:    "no-reply@example.com",
+				Recipient: "test@example.com",
+				Subject:   "Registration",
+				Body:      "<p>Hello</p>",
+			}
+			err := db.Outbox.New(ctx, msg)
+			require.NoError(t, err)
+		}
+
+		// Every message is claimed by exactly one worker
+		var mu sync.Mutex
+		claimed := make(map[uuid.UUID]int)
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				for {
+					msgs, err := db.Outbox.Claim(ctx, 3, time.Now().Add(time.Minute))
+					if !assert.NoError(t, err) || len(msgs) == 0 {
+						return
+					}
+
+					mu.Lock()
+					for _, msg := range msgs {
+						claimed[msg.ID]++
+					}
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, claimed, 20)
+		for _, n := range claimed {
+			assert.Equal(t, 1, n)
+		}
+	})
+}
@@ -7,12 +7,11 @@ import (
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/micahco/mono/internal/data"
 )
 
 type AuthenticationTokenRepository struct {
-	Pool *pgxpool.Pool
+	DB querier
 }
 
 func (r *AuthenticationTokenRepository) New(ctx context.Context, tokenHash []byte, expiry time.Time, userID uuid.UUID) error {
@@ -24,7 +23,7 @@ func (r *AuthenticationTokenRepository) New(ctx context.Context, tokenHash []byt
 		expiry,
 		userID,
 	}
-	_, err := r.Pool.Exec(ctx, sql, args...)
+	_, err := r.DB.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -36,15 +35,16 @@ func (r *AuthenticationTokenRepository) Get(ctx context.Context, tokenHash []byt
 	var at data.AuthenticationToken
 
 	sql := `
-		SELECT hash_, expiry_, user_id_
+		SELECT hash_, expiry_, user_id_, authenticated_at_
 		FROM authentication_token_ WHERE hash_ = $1;`
 	args := []any{
 		tokenHash,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
 		&at.Hash,
 		&at.Expiry,
 		&at.UserID,
+		&at.AuthenticatedAt,
 	)
 	if err != nil {
 		switch {
@@ -58,6 +58,47 @@ func (r *AuthenticationTokenRepository) Get(ctx context.Context, tokenHash []byt
 	return &at, nil
 }
 
+// Mark the token as freshly authenticated after the user re-entered
+// their password.
+func (r *AuthenticationTokenRepository) Reauthenticate(ctx context.Context, tokenHash []byte) error {
+	sql := `
+		UPDATE authentication_token_
+		SET authenticated_at_ = NOW()
+		WHERE hash_ = $1;`
+	args := []any{
+		tokenHash,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *AuthenticationTokenRepository) Delete(ctx context.Context, tokenHash []byte) error {
+	sql := `
+		DELETE FROM authentication_token_
+		WHERE hash_ = $1;`
+	args := []any{
+		tokenHash,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
 func (r *AuthenticationTokenRepository) Purge(ctx context.Context, userID uuid.UUID) error {
 	sql := `
 		DELETE FROM authentication_token_
@@ -66,10 +107,69 @@ func (r *AuthenticationTokenRepository) Purge(ctx context.Context, userID uuid.U
 	args := []any{
 		userID,
 	}
-	_, err := r.Pool.Exec(ctx, sql, args...)
+	_, err := r.DB.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func (r *AuthenticationTokenRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]*data.AuthenticationToken, error) {
+	sql := `
+		SELECT hash_, expiry_, user_id_, authenticated_at_
+		FROM authentication_token_
+		WHERE user_id_ = $1
+		AND expiry_ > NOW()
+		ORDER BY expiry_ DESC;`
+	args := []any{
+		userID,
+	}
+	rows, err := r.DB.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*data.AuthenticationToken{}
+	for rows.Next() {
+		var at data.AuthenticationToken
+
+		err := rows.Scan(
+			&at.Hash,
+			&at.Expiry,
+			&at.UserID,
+			&at.AuthenticatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, &at)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *AuthenticationTokenRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	sql := `
+		DELETE FROM authentication_token_
+		WHERE hash_ IN (
+			SELECT hash_
+			FROM authentication_token_
+			WHERE expiry_ < NOW()
+			LIMIT $1
+		);`
+	args := []any{
+		limit,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
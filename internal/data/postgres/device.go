@@ -0,0 +1,101 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type DeviceRepository struct {
+	DB querier
+}
+
+func (r *DeviceRepository) Remember(ctx context.Context, userID uuid.UUID, ip, userAgent string) (bool, error) {
+	var known bool
+
+	// Both timestamps default to the same transaction time on insert, so
+	// they only differ when the device was already known.
+	sql := `
+		INSERT INTO device_ (user_id_, ip_, user_agent_)
+		VALUES($1, $2, $3)
+		ON CONFLICT (user_id_, ip_, user_agent_)
+		DO UPDATE SET last_seen_at_ = NOW()
+		RETURNING created_at_ <> last_seen_at_;`
+	args := []any{
+		userID,
+		ip,
+		userAgent,
+	}
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&known)
+	if err != nil {
+		return false, err
+	}
+
+	return known, nil
+}
+
+func (r *DeviceRepository) NewSession(ctx context.Context, sessionHash []byte, session string, expiry time.Time, userID uuid.UUID) error {
+	sql := `
+		INSERT INTO device_session_ (hash_, session_, expiry_, user_id_)
+		VALUES($1, $2, $3, $4);`
+	args := []any{
+		sessionHash,
+		session,
+		expiry,
+		userID,
+	}
+	_, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *DeviceRepository) PopSession(ctx context.Context, sessionHash []byte) (string, error) {
+	var session string
+
+	sql := `
+		DELETE FROM device_session_
+		WHERE hash_ = $1
+		AND expiry_ > NOW()
+		RETURNING session_;`
+	args := []any{
+		sessionHash,
+	}
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&session)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return "", data.ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return session, nil
+}
+
+func (r *DeviceRepository) DeleteExpiredSessions(ctx context.Context, limit int) (int, error) {
+	sql := `
+		DELETE FROM device_session_
+		WHERE hash_ IN (
+			SELECT hash_
+			FROM device_session_
+			WHERE expiry_ < NOW()
+			LIMIT $1
+		);`
+	args := []any{
+		limit,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
@@ -0,0 +1,197 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type OutboxRepository struct {
+	DB querier
+}
+
+func (r *OutboxRepository) New(ctx context.Context, msg *data.OutboxMessage) error {
+	sql := `
+		INSERT INTO outbox_ (sender_, recipient_, subject_, body_)
+		VALUES($1, $2, $3, $4)
+		RETURNING id_, created_at_, status_, attempts_, next_attempt_at_;`
+	args := []any{
+		msg.Sender,
+		msg.Recipient,
+		msg.Subject,
+		msg.Body,
+	}
+	dest := []any{
+		&msg.ID,
+		&msg.CreatedAt,
+		&msg.Status,
+		&msg.Attempts,
+		&msg.NextAttemptAt,
+	}
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(dest...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, leaseExpiry time.Time) ([]*data.OutboxMessage, error) {
+	sql := `
+		UPDATE outbox_
+		SET next_attempt_at_ = $2
+		WHERE id_ IN (
+			SELECT id_
+			FROM outbox_
+			WHERE status_ = 'pending'
+			AND next_attempt_at_ <= NOW()
+			ORDER BY next_attempt_at_
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id_, created_at_, sender_, recipient_, subject_, body_, status_, attempts_, next_attempt_at_, last_error_;`
+	args := []any{
+		limit,
+		leaseExpiry,
+	}
+
+	return r.getMessages(ctx, sql, args...)
+}
+
+func (r *OutboxRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	sql := `
+		DELETE FROM outbox_
+		WHERE id_ = $1;`
+	args := []any{
+		id,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) Fail(ctx context.Context, id uuid.UUID, lastError string, nextAttempt time.Time, dead bool) error {
+	status := data.OutboxStatusPending
+	if dead {
+		status = data.OutboxStatusDead
+	}
+
+	sql := `
+		UPDATE outbox_
+		SET attempts_ = attempts_ + 1, last_error_ = $2, next_attempt_at_ = $3, status_ = $4
+		WHERE id_ = $1;`
+	args := []any{
+		id,
+		lastError,
+		nextAttempt,
+		status,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) GetDead(ctx context.Context) ([]*data.OutboxMessage, error) {
+	sql := `
+		SELECT id_, created_at_, sender_, recipient_, subject_, body_, status_, attempts_, next_attempt_at_, last_error_
+		FROM outbox_
+		WHERE status_ = 'dead'
+		ORDER BY created_at_;`
+
+	return r.getMessages(ctx, sql)
+}
+
+func (r *OutboxRepository) Retry(ctx context.Context, id uuid.UUID) error {
+	sql := `
+		UPDATE outbox_
+		SET status_ = 'pending', attempts_ = 0, next_attempt_at_ = NOW()
+		WHERE id_ = $1
+		AND status_ = 'dead';`
+	args := []any{
+		id,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) DeleteDead(ctx context.Context, before time.Time, limit int) (int, error) {
+	sql := `
+		DELETE FROM outbox_
+		WHERE id_ IN (
+			SELECT id_
+			FROM outbox_
+			WHERE status_ = 'dead'
+			AND created_at_ < $1
+			LIMIT $2
+		);`
+	args := []any{
+		before,
+		limit,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
+
+func (r *OutboxRepository) getMessages(ctx context.Context, sql string, args ...any) ([]*data.OutboxMessage, error) {
+	rows, err := r.DB.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	msgs := []*data.OutboxMessage{}
+	for rows.Next() {
+		var msg data.OutboxMessage
+
+		err := rows.Scan(
+			&msg.ID,
+			&msg.CreatedAt,
+			&msg.Sender,
+			&msg.Recipient,
+			&msg.Subject,
+			&msg.Body,
+			&msg.Status,
+			&msg.Attempts,
+			&msg.NextAttemptAt,
+			&msg.LastError,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		msgs = append(msgs, &msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
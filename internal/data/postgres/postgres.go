@@ -25,17 +25,48 @@ func NewPostgresDB(dsn string) (*PostgresDB, error) {
 	}
 
 	pg := &PostgresDB{
-		DB: &data.DB{
-			Users:                &UserRepository{pool},
-			VerificationTokens:   &VerificationTokenRepository{pool},
-			AuthenticationTokens: &AuthenticationTokenRepository{pool},
-		},
+		DB:   newDB(pool),
 		Pool: pool,
 	}
 
 	return pg, nil
 }
 
+// Connection pool or transaction that repositories run queries on
+type querier interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+func newDB(q querier) *data.DB {
+	return &data.DB{
+		Users:                &UserRepository{q},
+		VerificationTokens:   &VerificationTokenRepository{q},
+		AuthenticationTokens: &AuthenticationTokenRepository{q},
+		Devices:              &DeviceRepository{q},
+		SAML:                 &SAMLRepository{q},
+		Outbox:               &OutboxRepository{q},
+		Suppressions:         &SuppressionRepository{q},
+		Throttle:             &ThrottleRepository{q},
+		Stats:                &StatsRepository{q},
+		Transactor:           &transactor{q},
+	}
+}
+
+// Begins transactions on the pool. Transactions started within a
+// transaction are savepoints.
+type transactor struct {
+	q querier
+}
+
+func (t *transactor) WithTx(ctx context.Context, fn func(tx *data.DB) error) error {
+	return pgx.BeginFunc(ctx, t.q, func(tx pgx.Tx) error {
+		return fn(newDB(tx))
+	})
+}
+
 // Close closes all connections in the database pool
 func (pg *PostgresDB) Close() {
 	pg.Pool.Close()
@@ -75,3 +106,12 @@ func pgErrCode(err error) string {
 
 	return ""
 }
+
+func pgErrConstraint(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.ConstraintName
+	}
+
+	return ""
+}
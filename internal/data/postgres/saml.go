@@ -0,0 +1,218 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type SAMLRepository struct {
+	DB querier
+}
+
+func (r *SAMLRepository) NewProvider(ctx context.Context, p *data.SAMLProvider) error {
+	sql := `
+		INSERT INTO saml_provider_ (slug_, name_, domain_, metadata_, allow_idp_initiated_, link_by_email_)
+		VALUES($1, $2, $3, $4, $5, $6)
+		RETURNING id_, created_at_;`
+	args := []any{
+		p.Slug,
+		p.Name,
+		p.Domain,
+		p.Metadata,
+		p.AllowIDPInitiated,
+		p.LinkByEmail,
+	}
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&p.ID, &p.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SAMLRepository) GetProvider(ctx context.Context, slug string) (*data.SAMLProvider, error) {
+	sql := `
+		SELECT id_, created_at_, slug_, name_, domain_, metadata_, allow_idp_initiated_, link_by_email_
+		FROM saml_provider_
+		WHERE slug_ = $1;`
+
+	return r.getProvider(ctx, sql, slug)
+}
+
+func (r *SAMLRepository) GetProviderWithDomain(ctx context.Context, domain string) (*data.SAMLProvider, error) {
+	sql := `
+		SELECT id_, created_at_, slug_, name_, domain_, metadata_, allow_idp_initiated_, link_by_email_
+		FROM saml_provider_
+		WHERE domain_ = $1;`
+
+	return r.getProvider(ctx, sql, domain)
+}
+
+func (r *SAMLRepository) GetProviderWithUser(ctx context.Context, userID uuid.UUID) (*data.SAMLProvider, error) {
+	sql := `
+		SELECT saml_provider_.id_, saml_provider_.created_at_, saml_provider_.slug_, saml_provider_.name_,
+			saml_provider_.domain_, saml_provider_.metadata_, saml_provider_.allow_idp_initiated_, saml_provider_.link_by_email_
+		FROM saml_provider_
+		INNER JOIN saml_user_
+		ON saml_provider_.id_ = saml_user_.provider_id_
+		WHERE saml_user_.user_id_ = $1;`
+
+	return r.getProvider(ctx, sql, userID)
+}
+
+func (r *SAMLRepository) getProvider(ctx context.Context, sql string, args ...any) (*data.SAMLProvider, error) {
+	var p data.SAMLProvider
+
+	dest := []any{
+		&p.ID,
+		&p.CreatedAt,
+		&p.Slug,
+		&p.Name,
+		&p.Domain,
+		&p.Metadata,
+		&p.AllowIDPInitiated,
+		&p.LinkByEmail,
+	}
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(dest...)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+func (r *SAMLRepository) LinkUser(ctx context.Context, userID, providerID uuid.UUID) error {
+	sql := `
+		INSERT INTO saml_user_ (user_id_, provider_id_)
+		VALUES($1, $2)
+		ON CONFLICT (user_id_) DO UPDATE
+		SET provider_id_ = EXCLUDED.provider_id_;`
+	args := []any{
+		userID,
+		providerID,
+	}
+	_, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SAMLRepository) NewRequest(ctx context.Context, stateHash []byte, expiry time.Time, providerID uuid.UUID, requestID, returnTo string) error {
+	sql := `
+		INSERT INTO saml_request_ (hash_, expiry_, provider_id_, request_id_, return_to_)
+		VALUES($1, $2, $3, $4, $5);`
+	args := []any{
+		stateHash,
+		expiry,
+		providerID,
+		requestID,
+		returnTo,
+	}
+	_, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SAMLRepository) ConsumeRequest(ctx context.Context, stateHash []byte) (*data.SAMLRequest, error) {
+	var req data.SAMLRequest
+
+	sql := `
+		DELETE FROM saml_request_
+		WHERE hash_ = $1
+		RETURNING hash_, expiry_, provider_id_, request_id_, return_to_;`
+	args := []any{
+		stateHash,
+	}
+	dest := []any{
+		&req.Hash,
+		&req.Expiry,
+		&req.ProviderID,
+		&req.RequestID,
+		&req.ReturnTo,
+	}
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(dest...)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if time.Now().After(req.Expiry) {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return &req, nil
+}
+
+func (r *SAMLRepository) RememberAssertion(ctx context.Context, assertionID string, expiry time.Time) error {
+	sql := `
+		INSERT INTO saml_assertion_ (id_, expiry_)
+		VALUES($1, $2);`
+	args := []any{
+		assertionID,
+		expiry,
+	}
+	_, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		switch {
+		case pgErrCode(err) == pgerrcode.UniqueViolation:
+			return data.ErrReplayedAssertion
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *SAMLRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	sql := `
+		DELETE FROM saml_request_
+		WHERE hash_ IN (
+			SELECT hash_
+			FROM saml_request_
+			WHERE expiry_ < NOW()
+			LIMIT $1
+		);`
+	args := []any{
+		limit,
+	}
+	requests, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	sql = `
+		DELETE FROM saml_assertion_
+		WHERE id_ IN (
+			SELECT id_
+			FROM saml_assertion_
+			WHERE expiry_ < NOW()
+			LIMIT $1
+		);`
+	assertions, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(requests.RowsAffected() + assertions.RowsAffected()), nil
+}
@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+type StatsRepository struct {
+	DB querier
+}
+
+func (r *StatsRepository) Get(ctx context.Context) (*data.Stats, error) {
+	stats := data.Stats{
+		VerificationTokens: map[string]int{},
+	}
+
+	sql := `
+		SELECT
+			(SELECT COUNT(*) FROM user_),
+			(SELECT COUNT(*) FROM user_ WHERE active_),
+			(SELECT COUNT(*) FROM user_ WHERE locked_),
+			(SELECT COUNT(*) FROM authentication_token_ WHERE expiry_ > NOW()),
+			(SELECT COUNT(*) FROM authentication_token_ WHERE expiry_ <= NOW()),
+			(SELECT COUNT(*) FROM verification_token_ WHERE expiry_ <= NOW());`
+	err := r.DB.QueryRow(ctx, sql).Scan(
+		&stats.Users,
+		&stats.ActiveUsers,
+		&stats.LockedUsers,
+		&stats.AuthenticationTokens,
+		&stats.ExpiredAuthenticationTokens,
+		&stats.ExpiredVerificationTokens,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sql = `
+		SELECT scope_, COUNT(*)
+		FROM verification_token_
+		WHERE expiry_ > NOW()
+		GROUP BY scope_;`
+	rows, err := r.DB.Query(ctx, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scope string
+		var n int
+
+		err := rows.Scan(&scope, &n)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.VerificationTokens[scope] = n
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type SuppressionRepository struct {
+	DB querier
+}
+
+func (r *SuppressionRepository) Record(ctx context.Context, email, kind, diagnostic string) error {
+	softBounces := 0
+	if kind == data.BounceSoft {
+		softBounces = 1
+	}
+
+	sql := `
+		INSERT INTO suppression_ (email_, kind_, diagnostic_, soft_bounces_, suppressed_)
+		VALUES($1, $2, $3, $4, $2::text <> 'soft' OR $4::integer >= $5::integer)
+		ON CONFLICT (email_) DO UPDATE
+		SET kind_ = EXCLUDED.kind_, diagnostic_ = EXCLUDED.diagnostic_,
+			soft_bounces_ = suppression_.soft_bounces_ + EXCLUDED.soft_bounces_,
+			suppressed_ = suppression_.suppressed_ OR EXCLUDED.suppressed_
+				OR suppression_.soft_bounces_ + EXCLUDED.soft_bounces_ >= $5::integer,
+			updated_at_ = NOW();`
+	args := []any{
+		email,
+		kind,
+		diagnostic,
+		softBounces,
+		data.SoftBounceLimit,
+	}
+	_, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SuppressionRepository) Get(ctx context.Context, email string) (*data.Suppression, error) {
+	var s data.Suppression
+
+	sql := `
+		SELECT email_, kind_, diagnostic_, soft_bounces_, suppressed_, created_at_, updated_at_
+		FROM suppression_ WHERE email_ = $1;`
+	args := []any{
+		email,
+	}
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
+		&s.Email,
+		&s.Kind,
+		&s.Diagnostic,
+		&s.SoftBounces,
+		&s.Suppressed,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &s, nil
+}
+
+func (r *SuppressionRepository) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	var suppressed bool
+
+	sql := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM suppression_
+			WHERE email_ = $1 AND suppressed_
+		);`
+	args := []any{
+		email,
+	}
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&suppressed)
+	if err != nil {
+		return false, err
+	}
+
+	return suppressed, nil
+}
+
+func (r *SuppressionRepository) Delete(ctx context.Context, email string) error {
+	sql := `
+		DELETE FROM suppression_
+		WHERE email_ = $1;`
+
+	res, err := r.DB.Exec(ctx, sql, email)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+type ThrottleRepository struct {
+	DB querier
+}
+
+// The counts and the insert are one statement, but concurrent requests
+// may still exceed a cap by a few emails
+func (r *ThrottleRepository) Acquire(ctx context.Context, email, ip string, limits data.ThrottleLimits) error {
+	sql := `
+		INSERT INTO throttle_ (email_, ip_)
+		SELECT $1::citext, $2::text
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM throttle_
+			WHERE email_ = $1::citext
+			AND created_at_ > NOW() - make_interval(secs => $3::double precision)
+		)
+		AND ($4::integer = 0 OR (
+			SELECT COUNT(*)
+			FROM throttle_
+			WHERE ip_ = $2::text
+			AND created_at_ > NOW() - INTERVAL '1 hour'
+		) < $4::integer)
+		AND ($5::integer = 0 OR (
+			SELECT COUNT(*)
+			FROM throttle_
+			WHERE created_at_ > NOW() - INTERVAL '1 hour'
+		) < $5::integer)
+		AND ($6::integer = 0 OR (
+			SELECT COUNT(*)
+			FROM throttle_
+			WHERE created_at_ > NOW() - INTERVAL '1 day'
+		) < $6::integer);`
+	args := []any{
+		email,
+		ip,
+		limits.Cooldown.Seconds(),
+		limits.PerIP,
+		limits.Hourly,
+		limits.Daily,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return data.ErrThrottled
+	}
+
+	return nil
+}
+
+func (r *ThrottleRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	sql := `
+		DELETE FROM throttle_
+		WHERE id_ IN (
+			SELECT id_
+			FROM throttle_
+			WHERE created_at_ < NOW() - make_interval(secs => $1::double precision)
+			LIMIT $2
+		);`
+	args := []any{
+		data.ThrottleWindow.Seconds(),
+		limit,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
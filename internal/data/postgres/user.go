@@ -8,12 +8,11 @@ import (
 	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/micahco/mono/internal/data"
 )
 
 type UserRepository struct {
-	Pool *pgxpool.Pool
+	DB querier
 }
 
 func (r *UserRepository) New(ctx context.Context, email string, passwordHash []byte) (*data.User, error) {
@@ -25,15 +24,17 @@ func (r *UserRepository) New(ctx context.Context, email string, passwordHash []b
 	sql := `
 		INSERT INTO user_ (email_, password_hash_)
 		VALUES($1, $2)
-		RETURNING id_, version_, created_at_;`
+		RETURNING id_, version_, created_at_, active_, locale_;`
 	args := []any{
 		u.Email,
 		u.PasswordHash,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
 		&u.ID,
 		&u.Version,
 		&u.CreatedAt,
+		&u.Active,
+		&u.Locale,
 	)
 	if err != nil {
 		switch {
@@ -51,17 +52,23 @@ func (r *UserRepository) Get(ctx context.Context, id uuid.UUID) (*data.User, err
 	var u data.User
 
 	sql := `
-		SELECT id_, version_, created_at_, email_, password_hash_
+		SELECT id_, version_, created_at_, email_, password_hash_, locked_,
+			active_, display_name_, COALESCE(external_id_, ''), locale_
 		FROM user_ WHERE id_ = $1;`
 	args := []any{
 		id,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
 		&u.ID,
 		&u.Version,
 		&u.CreatedAt,
 		&u.Email,
 		&u.PasswordHash,
+		&u.Locked,
+		&u.Active,
+		&u.DisplayName,
+		&u.ExternalID,
+		&u.Locale,
 	)
 	if err != nil {
 		switch {
@@ -79,17 +86,23 @@ func (r *UserRepository) GetWithEmail(ctx context.Context, email string) (*data.
 	var u data.User
 
 	sql := `
-		SELECT id_, version_, created_at_, email_, password_hash_
+		SELECT id_, version_, created_at_, email_, password_hash_, locked_,
+			active_, display_name_, COALESCE(external_id_, ''), locale_
 		FROM user_ WHERE email_ = $1;`
 	args := []any{
 		email,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
 		&u.ID,
 		&u.Version,
 		&u.CreatedAt,
 		&u.Email,
 		&u.PasswordHash,
+		&u.Locked,
+		&u.Active,
+		&u.DisplayName,
+		&u.ExternalID,
+		&u.Locale,
 	)
 	if err != nil {
 		switch {
@@ -109,7 +122,8 @@ func (r *UserRepository) GetWithVerificationToken(ctx context.Context, scope str
 
 	sql := `
 		SELECT user_.id_, user_.version_, user_.created_at_, 
-			user_.email_, user_.password_hash_, verification_token_.expiry_
+			user_.email_, user_.password_hash_, user_.locked_,
+			user_.active_, user_.display_name_, COALESCE(user_.external_id_, ''), user_.locale_, verification_token_.expiry_
 		FROM user_
 		INNER JOIN verification_token_
 		ON user_.email_ = verification_token_.email_
@@ -119,12 +133,17 @@ func (r *UserRepository) GetWithVerificationToken(ctx context.Context, scope str
 		scope,
 		tokenHash,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
 		&u.ID,
 		&u.Version,
 		&u.CreatedAt,
 		&u.Email,
 		&u.PasswordHash,
+		&u.Locked,
+		&u.Active,
+		&u.DisplayName,
+		&u.ExternalID,
+		&u.Locale,
 		&expiry,
 	)
 	if err != nil {
@@ -149,7 +168,8 @@ func (r *UserRepository) GetWithAuthenticationToken(ctx context.Context, tokenHa
 
 	sql := `
 		SELECT user_.id_, user_.version_, user_.created_at_, 
-		user_.email_, user_.password_hash_, authentication_token_.expiry_
+		user_.email_, user_.password_hash_, user_.locked_,
+			user_.active_, user_.display_name_, COALESCE(user_.external_id_, ''), user_.locale_, authentication_token_.expiry_
 		FROM user_
 		INNER JOIN authentication_token_
 		ON user_.id_ = authentication_token_.user_id_
@@ -157,12 +177,17 @@ func (r *UserRepository) GetWithAuthenticationToken(ctx context.Context, tokenHa
 	args := []any{
 		tokenHash,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
 		&u.ID,
 		&u.Version,
 		&u.CreatedAt,
 		&u.Email,
 		&u.PasswordHash,
+		&u.Locked,
+		&u.Active,
+		&u.DisplayName,
+		&u.ExternalID,
+		&u.Locale,
 		&expiry,
 	)
 	if err != nil {
@@ -193,7 +218,7 @@ func (r *UserRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error)
 	args := []any{
 		id,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(&exists)
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&exists)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
@@ -218,7 +243,7 @@ func (r *UserRepository) ExistsWithEmail(ctx context.Context, email string) (boo
 	args := []any{
 		email,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(&exists)
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&exists)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
@@ -231,25 +256,98 @@ func (r *UserRepository) ExistsWithEmail(ctx context.Context, email string) (boo
 	return exists, nil
 }
 
+// List the users matching the filter ordered by creation. Also returns
+// the total number of matching users.
+func (r *UserRepository) List(ctx context.Context, filter data.UserFilter, offset, limit int) ([]*data.User, int, error) {
+	var total int
+
+	where := `
+		WHERE ($1::text = '' OR email_ = $1::citext)
+		AND ($2::text = '' OR external_id_ = $2)
+		AND ($3::text = '' OR display_name_ = $3)
+		AND ($4::boolean IS NULL OR active_ = $4)`
+	args := []any{
+		filter.Email,
+		filter.ExternalID,
+		filter.DisplayName,
+		filter.Active,
+	}
+
+	sql := `SELECT COUNT(*) FROM user_` + where + `;`
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sql = `
+		SELECT id_, version_, created_at_, email_, password_hash_, locked_,
+			active_, display_name_, COALESCE(external_id_, ''), locale_
+		FROM user_` + where + `
+		ORDER BY created_at_, id_
+		OFFSET $5 LIMIT $6;`
+	args = append(args, offset, limit)
+	rows, err := r.DB.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*data.User{}
+	for rows.Next() {
+		var u data.User
+
+		err := rows.Scan(
+			&u.ID,
+			&u.Version,
+			&u.CreatedAt,
+			&u.Email,
+			&u.PasswordHash,
+			&u.Locked,
+			&u.Active,
+			&u.DisplayName,
+			&u.ExternalID,
+			&u.Locale,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		users = append(users, &u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
 func (r *UserRepository) Update(ctx context.Context, u *data.User) error {
 	sql := `
 		UPDATE user_ 
-        SET email_ = $1, password_hash_ = $2, version_ = version_ + 1
-        WHERE id_ = $3 AND version_ = $4
+        SET email_ = $1, password_hash_ = $2, locked_ = $3, active_ = $4,
+			display_name_ = $5, external_id_ = NULLIF($6, ''), locale_ = $9, version_ = version_ + 1
+        WHERE id_ = $7 AND version_ = $8
         RETURNING version_;`
 	args := []any{
 		u.Email,
 		u.PasswordHash,
+		u.Locked,
+		u.Active,
+		u.DisplayName,
+		u.ExternalID,
 		u.ID,
 		u.Version,
+		u.Locale,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
 		&u.Version,
 	)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
 			return data.ErrEditConflict
+		case pgErrCode(err) == pgerrcode.UniqueViolation && pgErrConstraint(err) == "user__external_id__key":
+			return data.ErrDuplicateExternalID
 		case pgErrCode(err) == pgerrcode.UniqueViolation:
 			return data.ErrDuplicateEmail
 		default:
@@ -265,7 +363,7 @@ func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 		DELETE FROM user_
 		WHERE id_ = $1;`
 
-	res, err := r.Pool.Exec(ctx, sql, id)
+	res, err := r.DB.Exec(ctx, sql, id)
 	if err != nil {
 		return err
 	}
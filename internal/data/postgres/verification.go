@@ -5,16 +5,17 @@ import (
 	"errors"
 	"time"
 
+	"github.com/gofrs/uuid/v5"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/micahco/mono/internal/data"
 )
 
 type VerificationTokenRepository struct {
-	Pool *pgxpool.Pool
+	DB querier
 }
 
-// Create a verification token for an email without an associated User
+// Create a verification token for an email without an associated User.
+// Returns ErrSuppressedAddress if mail to the email is suppressed.
 func (r *VerificationTokenRepository) New(ctx context.Context, tokenHash []byte, expiry time.Time, scope, email string) error {
 	vt := &data.VerificationToken{
 		Hash:   tokenHash,
@@ -25,18 +26,65 @@ func (r *VerificationTokenRepository) New(ctx context.Context, tokenHash []byte,
 
 	sql := `
 		INSERT INTO verification_token_ (hash_, expiry_, scope_, email_)
-		VALUES($1, $2, $3, $4);`
+		SELECT $1::bytea, $2::timestamptz, $3::text, $4::citext
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM suppression_
+			WHERE email_ = $4::citext AND suppressed_
+		);`
 	args := []any{
 		vt.Hash,
 		vt.Expiry,
 		vt.Scope,
 		vt.Email,
 	}
-	_, err := r.Pool.Exec(ctx, sql, args...)
+	res, err := r.DB.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
 
+	if res.RowsAffected() == 0 {
+		return data.ErrSuppressedAddress
+	}
+
+	return nil
+}
+
+// Create a verification token for an email that belongs to an existing User.
+// Returns ErrSuppressedAddress if mail to the email is suppressed.
+func (r *VerificationTokenRepository) NewForUser(ctx context.Context, tokenHash []byte, expiry time.Time, scope, email string, userID uuid.UUID) error {
+	vt := &data.VerificationToken{
+		Hash:   tokenHash,
+		Expiry: expiry,
+		Scope:  scope,
+		Email:  email,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+
+	sql := `
+		INSERT INTO verification_token_ (hash_, expiry_, scope_, email_, user_id_)
+		SELECT $1::bytea, $2::timestamptz, $3::text, $4::citext, $5::uuid
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM suppression_
+			WHERE email_ = $4::citext AND suppressed_
+		);`
+	args := []any{
+		vt.Hash,
+		vt.Expiry,
+		vt.Scope,
+		vt.Email,
+		vt.UserID,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return data.ErrSuppressedAddress
+	}
+
 	return nil
 }
 
@@ -44,16 +92,17 @@ func (r *VerificationTokenRepository) Get(ctx context.Context, tokenHash []byte)
 	var vt data.VerificationToken
 
 	sql := `
-		SELECT hash_, expiry_, scope_, email_
+		SELECT hash_, expiry_, scope_, email_, user_id_
 		FROM verification_token_ WHERE hash_ = $1;`
 	args := []any{
 		tokenHash,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(
 		&vt.Hash,
 		&vt.Expiry,
 		&vt.Scope,
 		&vt.Email,
+		&vt.UserID,
 	)
 	if err != nil {
 		switch {
@@ -76,12 +125,13 @@ func (r *VerificationTokenRepository) Exists(ctx context.Context, scope, email s
 			FROM verification_token_
 			WHERE scope_ = $1
 			AND email_ = $2
+			AND expiry_ > NOW()
 		);`
 	args := []any{
 		scope,
 		email,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(&exists)
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&exists)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
@@ -101,7 +151,7 @@ func (r *VerificationTokenRepository) Purge(ctx context.Context, email string) e
 	args := []any{
 		email,
 	}
-	_, err := r.Pool.Exec(ctx, sql, args...)
+	_, err := r.DB.Exec(ctx, sql, args...)
 	if err != nil {
 		return err
 	}
@@ -109,21 +159,21 @@ func (r *VerificationTokenRepository) Purge(ctx context.Context, email string) e
 	return nil
 }
 
-func (r *VerificationTokenRepository) Verify(ctx context.Context, tokenHash []byte, scope, email string) error {
+func (r *VerificationTokenRepository) Consume(ctx context.Context, tokenHash []byte, scope, email string) error {
 	var expiry time.Time
 
 	sql := `
-		SELECT expiry_
-		FROM verification_token_
+		DELETE FROM verification_token_
 		WHERE hash_ = $1
 		AND scope_ = $2
-		AND email_ = $3;`
+		AND email_ = $3
+		RETURNING expiry_;`
 	args := []any{
 		tokenHash,
 		scope,
 		email,
 	}
-	err := r.Pool.QueryRow(ctx, sql, args...).Scan(&expiry)
+	err := r.DB.QueryRow(ctx, sql, args...).Scan(&expiry)
 	if err != nil {
 		switch {
 		case errors.Is(err, pgx.ErrNoRows):
@@ -139,3 +189,23 @@ func (r *VerificationTokenRepository) Verify(ctx context.Context, tokenHash []by
 
 	return nil
 }
+
+func (r *VerificationTokenRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	sql := `
+		DELETE FROM verification_token_
+		WHERE hash_ IN (
+			SELECT hash_
+			FROM verification_token_
+			WHERE expiry_ < NOW()
+			LIMIT $1
+		);`
+	args := []any{
+		limit,
+	}
+	res, err := r.DB.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(res.RowsAffected()), nil
+}
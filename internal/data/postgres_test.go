@@ -64,3 +64,66 @@ func TestPostgresVerificationTokenRepository(t *testing.T) {
 
 	runVerificationTokenRepositoryTests(t, pg.DB)
 }
+
+func TestPostgresDeviceRepository(t *testing.T) {
+	t.Parallel()
+
+	pg := newPostgresDB(t)
+	defer pg.Close()
+
+	runDeviceRepositoryTests(t, pg.DB)
+}
+
+func TestPostgresSAMLRepository(t *testing.T) {
+	t.Parallel()
+
+	pg := newPostgresDB(t)
+	defer pg.Close()
+
+	runSAMLRepositoryTests(t, pg.DB)
+}
+
+func TestPostgresOutboxRepository(t *testing.T) {
+	t.Parallel()
+
+	pg := newPostgresDB(t)
+	defer pg.Close()
+
+	runOutboxRepositoryTests(t, pg.DB)
+}
+
+func TestPostgresSuppressionRepository(t *testing.T) {
+	t.Parallel()
+
+	pg := newPostgresDB(t)
+	defer pg.Close()
+
+	runSuppressionRepositoryTests(t, pg.DB)
+}
+
+func TestPostgresThrottleRepository(t *testing.T) {
+	t.Parallel()
+
+	pg := newPostgresDB(t)
+	defer pg.Close()
+
+	runThrottleRepositoryTests(t, pg.DB)
+}
+
+func TestPostgresStatsRepository(t *testing.T) {
+	t.Parallel()
+
+	pg := newPostgresDB(t)
+	defer pg.Close()
+
+	runStatsRepositoryTests(t, pg.DB)
+}
+
+func TestPostgresTx(t *testing.T) {
+	t.Parallel()
+
+	pg := newPostgresDB(t)
+	defer pg.Close()
+
+	runTxTests(t, pg.DB)
+}
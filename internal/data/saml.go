@@ -0,0 +1,61 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Pending SP-initiated logins expire after this duration
+const SAMLRequestTTL = 10 * time.Minute
+
+// SAML identity providers of organizations, along with the state needed
+// to validate their responses.
+type SAMLRepository interface {
+	NewProvider(ctx context.Context, provider *SAMLProvider) error
+	GetProvider(ctx context.Context, slug string) (*SAMLProvider, error)
+	GetProviderWithDomain(ctx context.Context, domain string) (*SAMLProvider, error)
+	// Get the provider the user signs in with. Returns ErrRecordNotFound
+	// if the user isn't linked to a provider.
+	GetProviderWithUser(ctx context.Context, userID uuid.UUID) (*SAMLProvider, error)
+	// Link the user to the provider they sign in with, replacing any
+	// previous link.
+	LinkUser(ctx context.Context, userID, providerID uuid.UUID) error
+	// Record the ID of an authentication request sent to the provider,
+	// identified by the hash of the relay state, and the page to return
+	// to after signing in.
+	NewRequest(ctx context.Context, stateHash []byte, expiry time.Time, providerID uuid.UUID, requestID, returnTo string) error
+	// Get and delete the request identified by the relay state. Returns
+	// ErrRecordNotFound if it doesn't exist or has expired.
+	ConsumeRequest(ctx context.Context, stateHash []byte) (*SAMLRequest, error)
+	// Record an assertion until it expires. Returns ErrReplayedAssertion
+	// if it has already been used.
+	RememberAssertion(ctx context.Context, assertionID string, expiry time.Time) error
+	// Delete up to limit expired requests and up to limit expired
+	// assertions. Returns the number deleted.
+	DeleteExpired(ctx context.Context, limit int) (int, error)
+}
+
+type SAMLProvider struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	// Users with emails in this domain sign in with the provider
+	Domain string `json:"domain"`
+	// IdP metadata XML
+	Metadata          string `json:"-"`
+	AllowIDPInitiated bool   `json:"allow_idp_initiated"`
+	// Link existing users with the asserted email to the provider. Off by
+	// default so the provider can't take over local accounts.
+	LinkByEmail bool `json:"link_by_email"`
+}
+
+type SAMLRequest struct {
+	Hash       []byte
+	Expiry     time.Time
+	ProviderID uuid.UUID
+	RequestID  string
+	ReturnTo   string
+}
@@ -0,0 +1,137 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func runSAMLRepositoryTests(t *testing.T, db *data.DB) {
+	ctx := context.Background()
+
+	testProvider := &data.SAMLProvider{
+		Slug:     "example",
+		Name:     "Example Inc.",
+		Domain:   "example.com",
+		Metadata: "<EntityDescriptor/>",
+	}
+
+	t.Run("TestNewProvider", func(t *testing.T) {
+		err := db.SAML.NewProvider(ctx, testProvider)
+		assert.NoError(t, err)
+		assert.False(t, testProvider.ID.IsNil())
+		assert.False(t, testProvider.CreatedAt.IsZero())
+	})
+
+	t.Run("TestGetProvider", func(t *testing.T) {
+		p, err := db.SAML.GetProvider(ctx, testProvider.Slug)
+		assert.NoError(t, err)
+		assert.Equal(t, testProvider.ID, p.ID)
+		assert.Equal(t, testProvider.Metadata, p.Metadata)
+
+		// Domains are case insensitive
+		p, err = db.SAML.GetProviderWithDomain(ctx, "EXAMPLE.com")
+		assert.NoError(t, err)
+		assert.Equal(t, testProvider.ID, p.ID)
+
+		_, err = db.SAML.GetProvider(ctx, "unknown")
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("TestLinkUser", func(t *testing.T) {
+		assert.False(t, testProvider.LinkByEmail)
+
+		user, err := db.Users.New(ctx, "saml_user@example.com", []byte("password"))
+		assert.NoError(t, err)
+
+		_, err = db.SAML.GetProviderWithUser(ctx, user.ID)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		err = db.SAML.LinkUser(ctx, user.ID, testProvider.ID)
+		assert.NoError(t, err)
+
+		p, err := db.SAML.GetProviderWithUser(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, testProvider.ID, p.ID)
+
+		// Users are linked to one provider at a time
+		other := &data.SAMLProvider{
+			Slug:        "other",
+			Name:        "Other Inc.",
+			Domain:      "other.example.com",
+			Metadata:    "<EntityDescriptor/>",
+			LinkByEmail: true,
+		}
+		err = db.SAML.NewProvider(ctx, other)
+		assert.NoError(t, err)
+
+		err = db.SAML.LinkUser(ctx, user.ID, other.ID)
+		assert.NoError(t, err)
+
+		p, err = db.SAML.GetProviderWithUser(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, other.ID, p.ID)
+		assert.True(t, p.LinkByEmail)
+
+		// Links are deleted with the user
+		err = db.Users.Delete(ctx, user.ID)
+		assert.NoError(t, err)
+
+		_, err = db.SAML.GetProviderWithUser(ctx, user.ID)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("TestConsumeRequest", func(t *testing.T) {
+		hash := crypto.TokenHash("relay_state")
+		err := db.SAML.NewRequest(ctx, hash, time.Now().Add(data.SAMLRequestTTL), testProvider.ID, "id-1234", "/account/email")
+		assert.NoError(t, err)
+
+		req, err := db.SAML.ConsumeRequest(ctx, hash)
+		assert.NoError(t, err)
+		assert.Equal(t, testProvider.ID, req.ProviderID)
+		assert.Equal(t, "id-1234", req.RequestID)
+		assert.Equal(t, "/account/email", req.ReturnTo)
+
+		// Requests can only be used once
+		_, err = db.SAML.ConsumeRequest(ctx, hash)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		// Expired request
+		hash = crypto.TokenHash("expired_relay_state")
+		err = db.SAML.NewRequest(ctx, hash, time.Now().Add(-time.Minute), testProvider.ID, "id-5678", "")
+		assert.NoError(t, err)
+
+		_, err = db.SAML.ConsumeRequest(ctx, hash)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("TestRememberAssertion", func(t *testing.T) {
+		err := db.SAML.RememberAssertion(ctx, "assertion-1", time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+
+		err = db.SAML.RememberAssertion(ctx, "assertion-1", time.Now().Add(time.Hour))
+		assert.ErrorIs(t, err, data.ErrReplayedAssertion)
+	})
+
+	t.Run("TestDeleteExpired", func(t *testing.T) {
+		err := db.SAML.NewRequest(ctx, crypto.TokenHash("stale_relay_state"), time.Now().Add(-time.Minute), testProvider.ID, "id-9012", "")
+		assert.NoError(t, err)
+		err = db.SAML.RememberAssertion(ctx, "assertion-2", time.Now().Add(-time.Minute))
+		assert.NoError(t, err)
+
+		n, err := db.SAML.DeleteExpired(ctx, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		// Unexpired assertions are still remembered
+		err = db.SAML.RememberAssertion(ctx, "assertion-1", time.Now().Add(time.Hour))
+		assert.ErrorIs(t, err, data.ErrReplayedAssertion)
+
+		err = db.SAML.RememberAssertion(ctx, "assertion-2", time.Now().Add(time.Hour))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,172 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type AuthenticationTokenRepository struct {
+	DB querier
+}
+
+func (r *AuthenticationTokenRepository) New(ctx context.Context, tokenHash []byte, expiry time.Time, userID uuid.UUID) error {
+	query := `
+		INSERT INTO authentication_token_ (hash_, expiry_, user_id_, authenticated_at_)
+		VALUES(?1, ?2, ?3, ?4);`
+	args := []any{
+		tokenHash,
+		timestamp(expiry),
+		userID,
+		now(),
+	}
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AuthenticationTokenRepository) Get(ctx context.Context, tokenHash []byte) (*data.AuthenticationToken, error) {
+	var at data.AuthenticationToken
+
+	query := `
+		SELECT hash_, expiry_, user_id_, authenticated_at_
+		FROM authentication_token_ WHERE hash_ = ?1;`
+	args := []any{
+		tokenHash,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&at.Hash,
+		&at.Expiry,
+		&at.UserID,
+		&at.AuthenticatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &at, nil
+}
+
+// Mark the token as freshly authenticated after the user re-entered
+// their password.
+func (r *AuthenticationTokenRepository) Reauthenticate(ctx context.Context, tokenHash []byte) error {
+	query := `
+		UPDATE authentication_token_
+		SET authenticated_at_ = ?1
+		WHERE hash_ = ?2;`
+	args := []any{
+		now(),
+		tokenHash,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(res)
+}
+
+func (r *AuthenticationTokenRepository) Delete(ctx context.Context, tokenHash []byte) error {
+	query := `
+		DELETE FROM authentication_token_
+		WHERE hash_ = ?1;`
+	args := []any{
+		tokenHash,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(res)
+}
+
+func (r *AuthenticationTokenRepository) Purge(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		DELETE FROM authentication_token_
+		WHERE user_id_ = ?1;`
+	args := []any{
+		userID,
+	}
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *AuthenticationTokenRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]*data.AuthenticationToken, error) {
+	query := `
+		SELECT hash_, expiry_, user_id_, authenticated_at_
+		FROM authentication_token_
+		WHERE user_id_ = ?1
+		AND expiry_ > ?2
+		ORDER BY expiry_ DESC;`
+	args := []any{
+		userID,
+		now(),
+	}
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*data.AuthenticationToken{}
+	for rows.Next() {
+		var at data.AuthenticationToken
+
+		err := rows.Scan(
+			&at.Hash,
+			&at.Expiry,
+			&at.UserID,
+			&at.AuthenticatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, &at)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *AuthenticationTokenRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	query := `
+		DELETE FROM authentication_token_
+		WHERE hash_ IN (
+			SELECT hash_
+			FROM authentication_token_
+			WHERE expiry_ < ?1
+			LIMIT ?2
+		);`
+	args := []any{
+		now(),
+		limit,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
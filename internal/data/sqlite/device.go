@@ -0,0 +1,107 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type DeviceRepository struct {
+	DB querier
+}
+
+func (r *DeviceRepository) Remember(ctx context.Context, userID uuid.UUID, ip, userAgent string) (bool, error) {
+	var known bool
+
+	// Both timestamps are the same on insert, so they only differ when
+	// the device was already known.
+	t := now()
+	query := `
+		INSERT INTO device_ (user_id_, ip_, user_agent_, created_at_, last_seen_at_)
+		VALUES(?1, ?2, ?3, ?4, ?4)
+		ON CONFLICT (user_id_, ip_, user_agent_)
+		DO UPDATE SET last_seen_at_ = excluded.last_seen_at_
+		RETURNING created_at_ <> last_seen_at_;`
+	args := []any{
+		userID,
+		ip,
+		userAgent,
+		t,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&known)
+	if err != nil {
+		return false, err
+	}
+
+	return known, nil
+}
+
+func (r *DeviceRepository) NewSession(ctx context.Context, sessionHash []byte, session string, expiry time.Time, userID uuid.UUID) error {
+	query := `
+		INSERT INTO device_session_ (hash_, session_, expiry_, user_id_)
+		VALUES(?1, ?2, ?3, ?4);`
+	args := []any{
+		sessionHash,
+		session,
+		timestamp(expiry),
+		userID,
+	}
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *DeviceRepository) PopSession(ctx context.Context, sessionHash []byte) (string, error) {
+	var session string
+
+	query := `
+		DELETE FROM device_session_
+		WHERE hash_ = ?1
+		AND expiry_ > ?2
+		RETURNING session_;`
+	args := []any{
+		sessionHash,
+		now(),
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&session)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return "", data.ErrRecordNotFound
+		default:
+			return "", err
+		}
+	}
+
+	return session, nil
+}
+
+func (r *DeviceRepository) DeleteExpiredSessions(ctx context.Context, limit int) (int, error) {
+	query := `
+		DELETE FROM device_session_
+		WHERE hash_ IN (
+			SELECT hash_
+			FROM device_session_
+			WHERE expiry_ < ?1
+			LIMIT ?2
+		);`
+	args := []any{
+		now(),
+		limit,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
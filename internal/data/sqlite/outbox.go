@@ -0,0 +1,194 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type OutboxRepository struct {
+	DB querier
+}
+
+func (r *OutboxRepository) New(ctx context.Context, msg *data.OutboxMessage) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	createdAt := now()
+
+	query := `
+		INSERT INTO outbox_ (id_, created_at_, sender_, recipient_, subject_, body_, next_attempt_at_)
+		VALUES(?1, ?2, ?3, ?4, ?5, ?6, ?2);`
+	args := []any{
+		id,
+		createdAt,
+		msg.Sender,
+		msg.Recipient,
+		msg.Subject,
+		msg.Body,
+	}
+	_, err = r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	msg.ID = id
+	msg.CreatedAt = createdAt
+	msg.Status = data.OutboxStatusPending
+	msg.Attempts = 0
+	msg.NextAttemptAt = createdAt
+
+	return nil
+}
+
+func (r *OutboxRepository) Claim(ctx context.Context, limit int, leaseExpiry time.Time) ([]*data.OutboxMessage, error) {
+	query := `
+		UPDATE outbox_
+		SET next_attempt_at_ = ?2
+		WHERE id_ IN (
+			SELECT id_
+			FROM outbox_
+			WHERE status_ = 'pending'
+			AND next_attempt_at_ <= ?3
+			ORDER BY next_attempt_at_
+			LIMIT ?1
+		)
+		RETURNING id_, created_at_, sender_, recipient_, subject_, body_, status_, attempts_, next_attempt_at_, last_error_;`
+	args := []any{
+		limit,
+		timestamp(leaseExpiry),
+		now(),
+	}
+
+	return r.getMessages(ctx, query, args...)
+}
+
+func (r *OutboxRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `
+		DELETE FROM outbox_
+		WHERE id_ = ?1;`
+	args := []any{
+		id,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(res)
+}
+
+func (r *OutboxRepository) Fail(ctx context.Context, id uuid.UUID, lastError string, nextAttempt time.Time, dead bool) error {
+	status := data.OutboxStatusPending
+	if dead {
+		status = data.OutboxStatusDead
+	}
+
+	query := `
+		UPDATE outbox_
+		SET attempts_ = attempts_ + 1, last_error_ = ?2, next_attempt_at_ = ?3, status_ = ?4
+		WHERE id_ = ?1;`
+	args := []any{
+		id,
+		lastError,
+		timestamp(nextAttempt),
+		status,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(res)
+}
+
+func (r *OutboxRepository) GetDead(ctx context.Context) ([]*data.OutboxMessage, error) {
+	query := `
+		SELECT id_, created_at_, sender_, recipient_, subject_, body_, status_, attempts_, next_attempt_at_, last_error_
+		FROM outbox_
+		WHERE status_ = 'dead'
+		ORDER BY created_at_;`
+
+	return r.getMessages(ctx, query)
+}
+
+func (r *OutboxRepository) Retry(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE outbox_
+		SET status_ = 'pending', attempts_ = 0, next_attempt_at_ = ?2
+		WHERE id_ = ?1
+		AND status_ = 'dead';`
+	args := []any{
+		id,
+		now(),
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(res)
+}
+
+func (r *OutboxRepository) DeleteDead(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := `
+		DELETE FROM outbox_
+		WHERE id_ IN (
+			SELECT id_
+			FROM outbox_
+			WHERE status_ = 'dead'
+			AND created_at_ < ?1
+			LIMIT ?2
+		);`
+	args := []any{
+		timestamp(before),
+		limit,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
+
+func (r *OutboxRepository) getMessages(ctx context.Context, query string, args ...any) ([]*data.OutboxMessage, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	msgs := []*data.OutboxMessage{}
+	for rows.Next() {
+		var msg data.OutboxMessage
+
+		err := rows.Scan(
+			&msg.ID,
+			&msg.CreatedAt,
+			&msg.Sender,
+			&msg.Recipient,
+			&msg.Subject,
+			&msg.Body,
+			&msg.Status,
+			&msg.Attempts,
+			&msg.NextAttemptAt,
+			&msg.LastError,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		msgs = append(msgs, &msg)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
@@ -0,0 +1,229 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type SAMLRepository struct {
+	DB querier
+}
+
+func (r *SAMLRepository) NewProvider(ctx context.Context, p *data.SAMLProvider) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return err
+	}
+	createdAt := now()
+
+	query := `
+		INSERT INTO saml_provider_ (id_, created_at_, slug_, name_, domain_, metadata_, allow_idp_initiated_, link_by_email_)
+		VALUES(?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8);`
+	args := []any{
+		id,
+		createdAt,
+		p.Slug,
+		p.Name,
+		p.Domain,
+		p.Metadata,
+		p.AllowIDPInitiated,
+		p.LinkByEmail,
+	}
+	_, err = r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	p.ID = id
+	p.CreatedAt = createdAt
+
+	return nil
+}
+
+func (r *SAMLRepository) GetProvider(ctx context.Context, slug string) (*data.SAMLProvider, error) {
+	query := `
+		SELECT id_, created_at_, slug_, name_, domain_, metadata_, allow_idp_initiated_, link_by_email_
+		FROM saml_provider_
+		WHERE slug_ = ?1;`
+
+	return r.getProvider(ctx, query, slug)
+}
+
+func (r *SAMLRepository) GetProviderWithDomain(ctx context.Context, domain string) (*data.SAMLProvider, error) {
+	query := `
+		SELECT id_, created_at_, slug_, name_, domain_, metadata_, allow_idp_initiated_, link_by_email_
+		FROM saml_provider_
+		WHERE domain_ = ?1;`
+
+	return r.getProvider(ctx, query, domain)
+}
+
+func (r *SAMLRepository) GetProviderWithUser(ctx context.Context, userID uuid.UUID) (*data.SAMLProvider, error) {
+	query := `
+		SELECT saml_provider_.id_, saml_provider_.created_at_, saml_provider_.slug_, saml_provider_.name_,
+			saml_provider_.domain_, saml_provider_.metadata_, saml_provider_.allow_idp_initiated_, saml_provider_.link_by_email_
+		FROM saml_provider_
+		INNER JOIN saml_user_
+		ON saml_provider_.id_ = saml_user_.provider_id_
+		WHERE saml_user_.user_id_ = ?1;`
+
+	return r.getProvider(ctx, query, userID)
+}
+
+func (r *SAMLRepository) getProvider(ctx context.Context, query string, args ...any) (*data.SAMLProvider, error) {
+	var p data.SAMLProvider
+
+	dest := []any{
+		&p.ID,
+		&p.CreatedAt,
+		&p.Slug,
+		&p.Name,
+		&p.Domain,
+		&p.Metadata,
+		&p.AllowIDPInitiated,
+		&p.LinkByEmail,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(dest...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &p, nil
+}
+
+func (r *SAMLRepository) LinkUser(ctx context.Context, userID, providerID uuid.UUID) error {
+	query := `
+		INSERT INTO saml_user_ (user_id_, provider_id_)
+		VALUES(?1, ?2)
+		ON CONFLICT (user_id_) DO UPDATE
+		SET provider_id_ = excluded.provider_id_;`
+	args := []any{
+		userID,
+		providerID,
+	}
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SAMLRepository) NewRequest(ctx context.Context, stateHash []byte, expiry time.Time, providerID uuid.UUID, requestID, returnTo string) error {
+	query := `
+		INSERT INTO saml_request_ (hash_, expiry_, provider_id_, request_id_, return_to_)
+		VALUES(?1, ?2, ?3, ?4, ?5);`
+	args := []any{
+		stateHash,
+		timestamp(expiry),
+		providerID,
+		requestID,
+		returnTo,
+	}
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SAMLRepository) ConsumeRequest(ctx context.Context, stateHash []byte) (*data.SAMLRequest, error) {
+	var req data.SAMLRequest
+
+	query := `
+		DELETE FROM saml_request_
+		WHERE hash_ = ?1
+		RETURNING hash_, expiry_, provider_id_, request_id_, return_to_;`
+	args := []any{
+		stateHash,
+	}
+	dest := []any{
+		&req.Hash,
+		&req.Expiry,
+		&req.ProviderID,
+		&req.RequestID,
+		&req.ReturnTo,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(dest...)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if time.Now().After(req.Expiry) {
+		return nil, data.ErrRecordNotFound
+	}
+
+	return &req, nil
+}
+
+func (r *SAMLRepository) RememberAssertion(ctx context.Context, assertionID string, expiry time.Time) error {
+	query := `
+		INSERT INTO saml_assertion_ (id_, expiry_)
+		VALUES(?1, ?2);`
+	args := []any{
+		assertionID,
+		timestamp(expiry),
+	}
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return data.ErrReplayedAssertion
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *SAMLRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	var total int
+
+	for _, query := range []string{
+		`DELETE FROM saml_request_
+		WHERE hash_ IN (
+			SELECT hash_
+			FROM saml_request_
+			WHERE expiry_ < ?1
+			LIMIT ?2
+		);`,
+		`DELETE FROM saml_assertion_
+		WHERE id_ IN (
+			SELECT id_
+			FROM saml_assertion_
+			WHERE expiry_ < ?1
+			LIMIT ?2
+		);`,
+	} {
+		res, err := r.DB.ExecContext(ctx, query, now(), limit)
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		total += int(n)
+	}
+
+	return total, nil
+}
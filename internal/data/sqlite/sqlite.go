@@ -0,0 +1,200 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/migrations"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Connection pragmas. Foreign keys are off by default in SQLite, and
+// immediate transactions wait for the write lock instead of failing.
+const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+type SQLiteDB struct {
+	*data.DB
+	SQL *sql.DB
+}
+
+// Open the database file and apply pending migrations. Don't forget to
+// Close()
+func NewSQLiteDB(path string) (*SQLiteDB, error) {
+	db, err := openDB(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := migrations.NewSQLiteMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	err = m.Up()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	s := &SQLiteDB{
+		DB:  newDB(db),
+		SQL: db,
+	}
+	s.DB.Transactor = &transactor{db}
+
+	return s, nil
+}
+
+// Database or transaction that repositories run queries on
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func newDB(q querier) *data.DB {
+	return &data.DB{
+		Users:                &UserRepository{q},
+		VerificationTokens:   &VerificationTokenRepository{q},
+		AuthenticationTokens: &AuthenticationTokenRepository{q},
+		Devices:              &DeviceRepository{q},
+		SAML:                 &SAMLRepository{q},
+		Outbox:               &OutboxRepository{q},
+		Suppressions:         &SuppressionRepository{q},
+		Throttle:             &ThrottleRepository{q},
+		Stats:                &StatsRepository{q},
+	}
+}
+
+// Begins transactions on the database. Transactions started within a
+// transaction are savepoints, like on Postgres.
+type transactor struct {
+	db *sql.DB
+}
+
+func (t *transactor) WithTx(ctx context.Context, fn func(tx *data.DB) error) error {
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// No-op after a commit
+	defer tx.Rollback()
+
+	err = fn(newTxDB(tx, 0))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Repositories of the transaction, which begin savepoints nested depth
+// deep
+func newTxDB(tx *sql.Tx, depth int) *data.DB {
+	db := newDB(tx)
+	db.Transactor = &savepointer{tx, depth}
+
+	return db
+}
+
+type savepointer struct {
+	tx    *sql.Tx
+	depth int
+}
+
+func (s *savepointer) WithTx(ctx context.Context, fn func(tx *data.DB) error) error {
+	name := fmt.Sprintf("sp_%d", s.depth)
+
+	_, err := s.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	if err != nil {
+		return err
+	}
+
+	err = fn(newTxDB(s.tx, s.depth+1))
+	if err != nil {
+		// Rolling back to a savepoint keeps it, so it's still released
+		_, rbErr := s.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		if rbErr == nil {
+			_, rbErr = s.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+		}
+
+		return errors.Join(err, rbErr)
+	}
+
+	_, err = s.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+
+	return err
+}
+
+// Close closes all connections to the database
+func (s *SQLiteDB) Close() {
+	s.SQL.Close()
+}
+
+func openDB(path string) (*sql.DB, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dsn := path + "?" + pragmas
+	if strings.Contains(path, "?") {
+		dsn = path + "&" + pragmas
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Timestamps are stored as text, so they are kept in UTC to sort and
+// compare correctly. Precision matches a Postgres TIMESTAMPTZ.
+func timestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+func now() time.Time {
+	return timestamp(time.Now())
+}
+
+func sqliteErrCode(err error) int {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code()
+	}
+
+	return 0
+}
+
+func isUniqueViolation(err error) bool {
+	code := sqliteErrCode(err)
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+// Returns ErrRecordNotFound if the statement didn't change any rows
+func requireRowsAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return data.ErrRecordNotFound
+	}
+
+	return nil
+}
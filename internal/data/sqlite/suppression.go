@@ -0,0 +1,109 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+type SuppressionRepository struct {
+	DB querier
+}
+
+func (r *SuppressionRepository) Record(ctx context.Context, email, kind, diagnostic string) error {
+	softBounces := 0
+	if kind == data.BounceSoft {
+		softBounces = 1
+	}
+
+	query := `
+		INSERT INTO suppression_ (email_, kind_, diagnostic_, soft_bounces_, suppressed_, created_at_, updated_at_)
+		VALUES(?1, ?2, ?3, ?4, ?2 <> 'soft' OR ?4 >= ?5, ?6, ?6)
+		ON CONFLICT (email_) DO UPDATE
+		SET kind_ = excluded.kind_, diagnostic_ = excluded.diagnostic_,
+			soft_bounces_ = suppression_.soft_bounces_ + excluded.soft_bounces_,
+			suppressed_ = suppression_.suppressed_ OR excluded.suppressed_
+				OR suppression_.soft_bounces_ + excluded.soft_bounces_ >= ?5,
+			updated_at_ = excluded.updated_at_;`
+	args := []any{
+		email,
+		kind,
+		diagnostic,
+		softBounces,
+		data.SoftBounceLimit,
+		now(),
+	}
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *SuppressionRepository) Get(ctx context.Context, email string) (*data.Suppression, error) {
+	var s data.Suppression
+
+	query := `
+		SELECT email_, kind_, diagnostic_, soft_bounces_, suppressed_, created_at_, updated_at_
+		FROM suppression_ WHERE email_ = ?1;`
+	args := []any{
+		email,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&s.Email,
+		&s.Kind,
+		&s.Diagnostic,
+		&s.SoftBounces,
+		&s.Suppressed,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &s, nil
+}
+
+func (r *SuppressionRepository) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	var suppressed bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM suppression_
+			WHERE email_ = ?1 AND suppressed_
+		);`
+	args := []any{
+		email,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&suppressed)
+	if err != nil {
+		return false, err
+	}
+
+	return suppressed, nil
+}
+
+func (r *SuppressionRepository) Delete(ctx context.Context, email string) error {
+	query := `
+		DELETE FROM suppression_
+		WHERE email_ = ?1;`
+	args := []any{
+		email,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(res)
+}
@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"context"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+type ThrottleRepository struct {
+	DB querier
+}
+
+func (r *ThrottleRepository) Acquire(ctx context.Context, email, ip string, limits data.ThrottleLimits) error {
+	t := now()
+
+	query := `
+		INSERT INTO throttle_ (email_, ip_, created_at_)
+		SELECT ?1, ?2, ?3
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM throttle_
+			WHERE email_ = ?1
+			AND created_at_ > ?4
+		)
+		AND (?5 = 0 OR (
+			SELECT COUNT(*)
+			FROM throttle_
+			WHERE ip_ = ?2
+			AND created_at_ > ?6
+		) < ?5)
+		AND (?7 = 0 OR (
+			SELECT COUNT(*)
+			FROM throttle_
+			WHERE created_at_ > ?6
+		) < ?7)
+		AND (?8 = 0 OR (
+			SELECT COUNT(*)
+			FROM throttle_
+			WHERE created_at_ > ?9
+		) < ?8);`
+	args := []any{
+		email,
+		ip,
+		t,
+		t.Add(-limits.Cooldown),
+		limits.PerIP,
+		t.Add(-time.Hour),
+		limits.Hourly,
+		limits.Daily,
+		t.Add(-24 * time.Hour),
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return data.ErrThrottled
+	}
+
+	return nil
+}
+
+func (r *ThrottleRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	query := `
+		DELETE FROM throttle_
+		WHERE id_ IN (
+			SELECT id_
+			FROM throttle_
+			WHERE created_at_ < ?1
+			LIMIT ?2
+		);`
+	args := []any{
+		now().Add(-data.ThrottleWindow),
+		limit,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
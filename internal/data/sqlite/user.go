@@ -0,0 +1,369 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type UserRepository struct {
+	DB querier
+}
+
+func (r *UserRepository) New(ctx context.Context, email string, passwordHash []byte) (*data.User, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	u := data.User{
+		ID:           id,
+		CreatedAt:    now(),
+		Email:        email,
+		PasswordHash: passwordHash,
+	}
+
+	query := `
+		INSERT INTO user_ (id_, created_at_, email_, password_hash_)
+		VALUES(?1, ?2, ?3, ?4)
+		RETURNING version_, active_, locale_;`
+	args := []any{
+		u.ID,
+		u.CreatedAt,
+		u.Email,
+		u.PasswordHash,
+	}
+	err = r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&u.Version,
+		&u.Active,
+		&u.Locale,
+	)
+	if err != nil {
+		switch {
+		case isUniqueViolation(err):
+			return nil, data.ErrDuplicateEmail
+		default:
+			return nil, err
+		}
+	}
+
+	return &u, nil
+}
+
+func (r *UserRepository) Get(ctx context.Context, id uuid.UUID) (*data.User, error) {
+	var u data.User
+
+	query := `
+		SELECT id_, version_, created_at_, email_, password_hash_, locked_,
+			active_, display_name_, COALESCE(external_id_, ''), locale_
+		FROM user_ WHERE id_ = ?1;`
+	args := []any{
+		id,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&u.ID,
+		&u.Version,
+		&u.CreatedAt,
+		&u.Email,
+		&u.PasswordHash,
+		&u.Locked,
+		&u.Active,
+		&u.DisplayName,
+		&u.ExternalID,
+		&u.Locale,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &u, nil
+}
+
+func (r *UserRepository) GetWithEmail(ctx context.Context, email string) (*data.User, error) {
+	var u data.User
+
+	query := `
+		SELECT id_, version_, created_at_, email_, password_hash_, locked_,
+			active_, display_name_, COALESCE(external_id_, ''), locale_
+		FROM user_ WHERE email_ = ?1;`
+	args := []any{
+		email,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&u.ID,
+		&u.Version,
+		&u.CreatedAt,
+		&u.Email,
+		&u.PasswordHash,
+		&u.Locked,
+		&u.Active,
+		&u.DisplayName,
+		&u.ExternalID,
+		&u.Locale,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &u, nil
+}
+
+func (r *UserRepository) GetWithVerificationToken(ctx context.Context, scope string, tokenHash []byte) (*data.User, error) {
+	var u data.User
+	var expiry time.Time
+
+	query := `
+		SELECT user_.id_, user_.version_, user_.created_at_,
+			user_.email_, user_.password_hash_, user_.locked_,
+			user_.active_, user_.display_name_, COALESCE(user_.external_id_, ''), user_.locale_, verification_token_.expiry_
+		FROM user_
+		INNER JOIN verification_token_
+		ON user_.email_ = verification_token_.email_
+		WHERE verification_token_.scope_ = ?1
+		AND verification_token_.hash_ = ?2;`
+	args := []any{
+		scope,
+		tokenHash,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&u.ID,
+		&u.Version,
+		&u.CreatedAt,
+		&u.Email,
+		&u.PasswordHash,
+		&u.Locked,
+		&u.Active,
+		&u.DisplayName,
+		&u.ExternalID,
+		&u.Locale,
+		&expiry,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if time.Now().After(expiry) {
+		return nil, data.ErrExpiredToken
+	}
+
+	return &u, nil
+}
+
+func (r *UserRepository) GetWithAuthenticationToken(ctx context.Context, tokenHash []byte) (*data.User, error) {
+	var u data.User
+	var expiry time.Time
+
+	query := `
+		SELECT user_.id_, user_.version_, user_.created_at_,
+			user_.email_, user_.password_hash_, user_.locked_,
+			user_.active_, user_.display_name_, COALESCE(user_.external_id_, ''), user_.locale_, authentication_token_.expiry_
+		FROM user_
+		INNER JOIN authentication_token_
+		ON user_.id_ = authentication_token_.user_id_
+		WHERE authentication_token_.hash_ = ?1;`
+	args := []any{
+		tokenHash,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&u.ID,
+		&u.Version,
+		&u.CreatedAt,
+		&u.Email,
+		&u.PasswordHash,
+		&u.Locked,
+		&u.Active,
+		&u.DisplayName,
+		&u.ExternalID,
+		&u.Locale,
+		&expiry,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	if time.Now().After(expiry) {
+		return nil, data.ErrExpiredToken
+	}
+
+	return &u, nil
+}
+
+func (r *UserRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var exists bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_
+			WHERE id_ = ?1
+		);`
+	args := []any{
+		id,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *UserRepository) ExistsWithEmail(ctx context.Context, email string) (bool, error) {
+	var exists bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_
+			WHERE email_ = ?1
+		);`
+	args := []any{
+		email,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// List the users matching the filter ordered by creation. Also returns
+// the total number of matching users.
+func (r *UserRepository) List(ctx context.Context, filter data.UserFilter, offset, limit int) ([]*data.User, int, error) {
+	var total int
+
+	where := `
+		WHERE (?1 = '' OR email_ = ?1)
+		AND (?2 = '' OR external_id_ = ?2)
+		AND (?3 = '' OR display_name_ = ?3)
+		AND (?4 IS NULL OR active_ = ?4)`
+	args := []any{
+		filter.Email,
+		filter.ExternalID,
+		filter.DisplayName,
+		filter.Active,
+	}
+
+	query := `SELECT COUNT(*) FROM user_` + where + `;`
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query = `
+		SELECT id_, version_, created_at_, email_, password_hash_, locked_,
+			active_, display_name_, COALESCE(external_id_, ''), locale_
+		FROM user_` + where + `
+		ORDER BY created_at_, id_
+		LIMIT ?6 OFFSET ?5;`
+	args = append(args, offset, limit)
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*data.User{}
+	for rows.Next() {
+		var u data.User
+
+		err := rows.Scan(
+			&u.ID,
+			&u.Version,
+			&u.CreatedAt,
+			&u.Email,
+			&u.PasswordHash,
+			&u.Locked,
+			&u.Active,
+			&u.DisplayName,
+			&u.ExternalID,
+			&u.Locale,
+		)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		users = append(users, &u)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *UserRepository) Update(ctx context.Context, u *data.User) error {
+	query := `
+		UPDATE user_
+		SET email_ = ?1, password_hash_ = ?2, locked_ = ?3, active_ = ?4,
+			display_name_ = ?5, external_id_ = NULLIF(?6, ''), locale_ = ?9, version_ = version_ + 1
+		WHERE id_ = ?7 AND version_ = ?8
+		RETURNING version_;`
+	args := []any{
+		u.Email,
+		u.PasswordHash,
+		u.Locked,
+		u.Active,
+		u.DisplayName,
+		u.ExternalID,
+		u.ID,
+		u.Version,
+		u.Locale,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&u.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.ErrEditConflict
+		case isUniqueViolation(err) && strings.Contains(err.Error(), "user_.external_id_"):
+			return data.ErrDuplicateExternalID
+		case isUniqueViolation(err):
+			return data.ErrDuplicateEmail
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `
+		DELETE FROM user_
+		WHERE id_ = ?1;`
+
+	res, err := r.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	return requireRowsAffected(res)
+}
@@ -0,0 +1,217 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+)
+
+type VerificationTokenRepository struct {
+	DB querier
+}
+
+// Create a verification token for an email without an associated User.
+// Returns ErrSuppressedAddress if mail to the email is suppressed.
+func (r *VerificationTokenRepository) New(ctx context.Context, tokenHash []byte, expiry time.Time, scope, email string) error {
+	vt := &data.VerificationToken{
+		Hash:   tokenHash,
+		Expiry: timestamp(expiry),
+		Scope:  scope,
+		Email:  email,
+	}
+
+	query := `
+		INSERT INTO verification_token_ (hash_, expiry_, scope_, email_)
+		SELECT ?1, ?2, ?3, ?4
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM suppression_
+			WHERE email_ = ?4 AND suppressed_
+		);`
+	args := []any{
+		vt.Hash,
+		vt.Expiry,
+		vt.Scope,
+		vt.Email,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return suppressedIfNoRows(res)
+}
+
+// Create a verification token for an email that belongs to an existing User.
+// Returns ErrSuppressedAddress if mail to the email is suppressed.
+func (r *VerificationTokenRepository) NewForUser(ctx context.Context, tokenHash []byte, expiry time.Time, scope, email string, userID uuid.UUID) error {
+	vt := &data.VerificationToken{
+		Hash:   tokenHash,
+		Expiry: timestamp(expiry),
+		Scope:  scope,
+		Email:  email,
+		UserID: uuid.NullUUID{UUID: userID, Valid: true},
+	}
+
+	query := `
+		INSERT INTO verification_token_ (hash_, expiry_, scope_, email_, user_id_)
+		SELECT ?1, ?2, ?3, ?4, ?5
+		WHERE NOT EXISTS (
+			SELECT 1
+			FROM suppression_
+			WHERE email_ = ?4 AND suppressed_
+		);`
+	args := []any{
+		vt.Hash,
+		vt.Expiry,
+		vt.Scope,
+		vt.Email,
+		vt.UserID,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return suppressedIfNoRows(res)
+}
+
+func (r *VerificationTokenRepository) Get(ctx context.Context, tokenHash []byte) (*data.VerificationToken, error) {
+	var vt data.VerificationToken
+
+	query := `
+		SELECT hash_, expiry_, scope_, email_, user_id_
+		FROM verification_token_ WHERE hash_ = ?1;`
+	args := []any{
+		tokenHash,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(
+		&vt.Hash,
+		&vt.Expiry,
+		&vt.Scope,
+		&vt.Email,
+		&vt.UserID,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, data.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &vt, nil
+}
+
+func (r *VerificationTokenRepository) Exists(ctx context.Context, scope, email string) (bool, error) {
+	var exists bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM verification_token_
+			WHERE scope_ = ?1
+			AND email_ = ?2
+			AND expiry_ > ?3
+		);`
+	args := []any{
+		scope,
+		email,
+		now(),
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *VerificationTokenRepository) Purge(ctx context.Context, email string) error {
+	query := `
+		DELETE FROM verification_token_
+		WHERE email_ = ?1;`
+	args := []any{
+		email,
+	}
+	_, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *VerificationTokenRepository) Consume(ctx context.Context, tokenHash []byte, scope, email string) error {
+	var expiry time.Time
+
+	query := `
+		DELETE FROM verification_token_
+		WHERE hash_ = ?1
+		AND scope_ = ?2
+		AND email_ = ?3
+		RETURNING expiry_;`
+	args := []any{
+		tokenHash,
+		scope,
+		email,
+	}
+	err := r.DB.QueryRowContext(ctx, query, args...).Scan(&expiry)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return data.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	if time.Now().After(expiry) {
+		return data.ErrExpiredToken
+	}
+
+	return nil
+}
+
+func (r *VerificationTokenRepository) DeleteExpired(ctx context.Context, limit int) (int, error) {
+	query := `
+		DELETE FROM verification_token_
+		WHERE hash_ IN (
+			SELECT hash_
+			FROM verification_token_
+			WHERE expiry_ < ?1
+			LIMIT ?2
+		);`
+	args := []any{
+		now(),
+		limit,
+	}
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+
+	return int(n), err
+}
+
+// Returns ErrSuppressedAddress if a token insert was skipped because mail
+// to the email is suppressed
+func suppressedIfNoRows(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return data.ErrSuppressedAddress
+	}
+
+	return nil
+}
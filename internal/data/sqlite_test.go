@@ -0,0 +1,109 @@
+package data_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/micahco/mono/internal/data/sqlite"
+	"github.com/stretchr/testify/require"
+)
+
+// Create an ephemeral, migrated sqlite db for testing
+func newSQLiteDB(t *testing.T) *sqlite.SQLiteDB {
+	t.Helper()
+
+	db, err := sqlite.NewSQLiteDB(filepath.Join(t.TempDir(), "mono.db"))
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runUserRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteAuthenticationTokenRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runAuthenticationTokenRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteVerificationTokenRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runVerificationTokenRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteDeviceRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runDeviceRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteSAMLRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runSAMLRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteOutboxRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runOutboxRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteSuppressionRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runSuppressionRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteThrottleRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runThrottleRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteStatsRepository(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runStatsRepositoryTests(t, db.DB)
+}
+
+func TestSQLiteTx(t *testing.T) {
+	t.Parallel()
+
+	db := newSQLiteDB(t)
+	defer db.Close()
+
+	runTxTests(t, db.DB)
+}
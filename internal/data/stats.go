@@ -0,0 +1,20 @@
+package data
+
+import "context"
+
+type StatsRepository interface {
+	Get(ctx context.Context) (*Stats, error)
+}
+
+// Counts of users and tokens
+type Stats struct {
+	Users       int `json:"users"`
+	ActiveUsers int `json:"active_users"`
+	LockedUsers int `json:"locked_users"`
+	// Authentication tokens that haven't expired
+	AuthenticationTokens        int `json:"authentication_tokens"`
+	ExpiredAuthenticationTokens int `json:"expired_authentication_tokens"`
+	// Verification tokens that haven't expired, by scope
+	VerificationTokens        map[string]int `json:"verification_tokens"`
+	ExpiredVerificationTokens int            `json:"expired_verification_tokens"`
+}
@@ -0,0 +1,45 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runStatsRepositoryTests(t *testing.T, db *data.DB) {
+	ctx := context.Background()
+
+	before, err := db.Stats.Get(ctx)
+	require.NoError(t, err)
+
+	active, err := db.Users.New(ctx, "stats_active@example.com", []byte("password"))
+	require.NoError(t, err)
+
+	locked, err := db.Users.New(ctx, "stats_locked@example.com", []byte("password"))
+	require.NoError(t, err)
+	locked.Locked = true
+	locked.Active = false
+	require.NoError(t, db.Users.Update(ctx, locked))
+
+	expiry := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, db.AuthenticationTokens.New(ctx, []byte("stats_token"), expiry, active.ID))
+	require.NoError(t, db.AuthenticationTokens.New(ctx, []byte("stats_expired_token"), expired, active.ID))
+	require.NoError(t, db.VerificationTokens.New(ctx, []byte("stats_token"), expiry, data.ScopeRegistration, "stats_new@example.com"))
+	require.NoError(t, db.VerificationTokens.New(ctx, []byte("stats_expired_token"), expired, data.ScopePasswordReset, active.Email))
+
+	stats, err := db.Stats.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, before.Users+2, stats.Users)
+	assert.Equal(t, before.ActiveUsers+1, stats.ActiveUsers)
+	assert.Equal(t, before.LockedUsers+1, stats.LockedUsers)
+	assert.Equal(t, before.AuthenticationTokens+1, stats.AuthenticationTokens)
+	assert.Equal(t, before.ExpiredAuthenticationTokens+1, stats.ExpiredAuthenticationTokens)
+	assert.Equal(t, before.VerificationTokens[data.ScopeRegistration]+1, stats.VerificationTokens[data.ScopeRegistration])
+	assert.Equal(t, before.VerificationTokens[data.ScopePasswordReset], stats.VerificationTokens[data.ScopePasswordReset])
+	assert.Equal(t, before.ExpiredVerificationTokens+1, stats.ExpiredVerificationTokens)
+}
@@ -0,0 +1,39 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	BounceHard      = "hard"
+	BounceSoft      = "soft"
+	BounceComplaint = "complaint"
+
+	// Soft bounces that suppress an address
+	SoftBounceLimit = 3
+)
+
+// Addresses that bounced or complained. Hard bounces and complaints
+// suppress an address at once, soft bounces only once there are
+// SoftBounceLimit of them. Mail isn't sent to suppressed addresses.
+type SuppressionRepository interface {
+	// Record a bounce of the kind for the address
+	Record(ctx context.Context, email, kind, diagnostic string) error
+	Get(ctx context.Context, email string) (*Suppression, error)
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+	// Clear the bounces of the address. Returns ErrRecordNotFound if it
+	// has none.
+	Delete(ctx context.Context, email string) error
+}
+
+type Suppression struct {
+	Email string `json:"email"`
+	// Kind of the latest bounce
+	Kind        string    `json:"kind"`
+	Diagnostic  string    `json:"diagnostic"`
+	SoftBounces int       `json:"soft_bounces"`
+	Suppressed  bool      `json:"suppressed"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,124 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runSuppressionRepositoryTests(t *testing.T, db *data.DB) {
+	ctx := context.Background()
+	hardEmail := "hard@example.com"
+	softEmail := "soft@example.com"
+	complaintEmail := "complaint@example.com"
+	nonExistantEmail := "unknown@example.com"
+
+	t.Run("TestRecordHard", func(t *testing.T) {
+		err := db.Suppressions.Record(ctx, hardEmail, data.BounceHard, "550 5.1.1 user unknown")
+		require.NoError(t, err)
+
+		s, err := db.Suppressions.Get(ctx, hardEmail)
+		require.NoError(t, err)
+		assert.Equal(t, hardEmail, s.Email)
+		assert.Equal(t, data.BounceHard, s.Kind)
+		assert.Equal(t, "550 5.1.1 user unknown", s.Diagnostic)
+		assert.True(t, s.Suppressed)
+		assert.False(t, s.CreatedAt.IsZero())
+
+		// Case-insensitive email
+		suppressed, err := db.Suppressions.IsSuppressed(ctx, "HARD@example.com")
+		assert.NoError(t, err)
+		assert.True(t, suppressed)
+
+		// Later soft bounces don't lift the suppression
+		err = db.Suppressions.Record(ctx, hardEmail, data.BounceSoft, "452 4.2.2 mailbox full")
+		require.NoError(t, err)
+
+		s, err = db.Suppressions.Get(ctx, hardEmail)
+		require.NoError(t, err)
+		assert.Equal(t, data.BounceSoft, s.Kind)
+		assert.True(t, s.Suppressed)
+	})
+
+	t.Run("TestRecordSoft", func(t *testing.T) {
+		for i := 1; i < data.SoftBounceLimit; i++ {
+			err := db.Suppressions.Record(ctx, softEmail, data.BounceSoft, "452 4.2.2 mailbox full")
+			require.NoError(t, err)
+		}
+
+		s, err := db.Suppressions.Get(ctx, softEmail)
+		require.NoError(t, err)
+		assert.Equal(t, data.SoftBounceLimit-1, s.SoftBounces)
+		assert.False(t, s.Suppressed)
+
+		suppressed, err := db.Suppressions.IsSuppressed(ctx, softEmail)
+		assert.NoError(t, err)
+		assert.False(t, suppressed)
+
+		err = db.Suppressions.Record(ctx, softEmail, data.BounceSoft, "452 4.2.2 mailbox full")
+		require.NoError(t, err)
+
+		s, err = db.Suppressions.Get(ctx, softEmail)
+		require.NoError(t, err)
+		assert.Equal(t, data.SoftBounceLimit, s.SoftBounces)
+		assert.True(t, s.Suppressed)
+	})
+
+	t.Run("TestRecordComplaint", func(t *testing.T) {
+		err := db.Suppressions.Record(ctx, complaintEmail, data.BounceComplaint, "abuse")
+		require.NoError(t, err)
+
+		suppressed, err := db.Suppressions.IsSuppressed(ctx, complaintEmail)
+		assert.NoError(t, err)
+		assert.True(t, suppressed)
+	})
+
+	t.Run("TestGet", func(t *testing.T) {
+		_, err := db.Suppressions.Get(ctx, nonExistantEmail)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		suppressed, err := db.Suppressions.IsSuppressed(ctx, nonExistantEmail)
+		assert.NoError(t, err)
+		assert.False(t, suppressed)
+	})
+
+	t.Run("TestVerificationTokens", func(t *testing.T) {
+		expiry := time.Now().Add(time.Hour)
+
+		err := db.VerificationTokens.New(ctx, []byte("suppressed_token"), expiry, data.ScopeRegistration, hardEmail)
+		assert.ErrorIs(t, err, data.ErrSuppressedAddress)
+
+		exists, err := db.VerificationTokens.Exists(ctx, data.ScopeRegistration, hardEmail)
+		assert.NoError(t, err)
+		assert.False(t, exists)
+
+		user, err := db.Users.New(ctx, complaintEmail, []byte("password"))
+		require.NoError(t, err)
+
+		err = db.VerificationTokens.NewForUser(ctx, []byte("suppressed_token"), expiry, data.ScopeEmailRevert, complaintEmail, user.ID)
+		assert.ErrorIs(t, err, data.ErrSuppressedAddress)
+
+		// Addresses under the limit still receive mail
+		err = db.VerificationTokens.New(ctx, []byte("soft_token"), expiry, data.ScopeRegistration, nonExistantEmail)
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestDelete", func(t *testing.T) {
+		err := db.Suppressions.Delete(ctx, hardEmail)
+		assert.NoError(t, err)
+
+		suppressed, err := db.Suppressions.IsSuppressed(ctx, hardEmail)
+		assert.NoError(t, err)
+		assert.False(t, suppressed)
+
+		err = db.VerificationTokens.New(ctx, []byte("cleared_token"), time.Now().Add(time.Hour), data.ScopeRegistration, hardEmail)
+		assert.NoError(t, err)
+
+		err = db.Suppressions.Delete(ctx, nonExistantEmail)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+}
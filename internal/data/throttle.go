@@ -0,0 +1,41 @@
+package data
+
+import (
+	"context"
+	"time"
+)
+
+// How long sent verification emails are counted
+const ThrottleWindow = 24 * time.Hour
+
+var DefaultThrottleLimits = ThrottleLimits{
+	Cooldown: time.Minute,
+	PerIP:    10,
+	Hourly:   500,
+	Daily:    5000,
+}
+
+// Limits of the verification emails that may be sent. A zero value
+// disables the limit.
+type ThrottleLimits struct {
+	// Time between emails to a recipient
+	Cooldown time.Duration
+	// Emails requested by an IP address per hour
+	PerIP int
+	// Emails to anyone per hour
+	Hourly int
+	// Emails to anyone per day
+	Daily int
+}
+
+// Verification emails that were sent recently, shared by every instance
+// of the servers
+type ThrottleRepository interface {
+	// Count an email to the recipient, requested by the IP address.
+	// Returns ErrThrottled without counting it if it would exceed one of
+	// the limits.
+	Acquire(ctx context.Context, email, ip string, limits ThrottleLimits) error
+	// Delete up to limit emails that are older than ThrottleWindow.
+	// Returns the number deleted.
+	DeleteExpired(ctx context.Context, limit int) (int, error)
+}
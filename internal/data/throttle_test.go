@@ -0,0 +1,63 @@
+package data_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runThrottleRepositoryTests(t *testing.T, db *data.DB) {
+	ctx := context.Background()
+
+	t.Run("TestCooldown", func(t *testing.T) {
+		limits := data.ThrottleLimits{Cooldown: time.Hour}
+
+		err := db.Throttle.Acquire(ctx, "cooldown@example.com", "192.0.2.1", limits)
+		require.NoError(t, err)
+
+		// Case-insensitive email, from any IP
+		err = db.Throttle.Acquire(ctx, "COOLDOWN@example.com", "192.0.2.2", limits)
+		assert.ErrorIs(t, err, data.ErrThrottled)
+
+		err = db.Throttle.Acquire(ctx, "other@example.com", "192.0.2.1", limits)
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestPerIP", func(t *testing.T) {
+		limits := data.ThrottleLimits{PerIP: 2}
+
+		err := db.Throttle.Acquire(ctx, "ip1@example.com", "198.51.100.1", limits)
+		require.NoError(t, err)
+		err = db.Throttle.Acquire(ctx, "ip2@example.com", "198.51.100.1", limits)
+		require.NoError(t, err)
+
+		err = db.Throttle.Acquire(ctx, "ip3@example.com", "198.51.100.1", limits)
+		assert.ErrorIs(t, err, data.ErrThrottled)
+
+		err = db.Throttle.Acquire(ctx, "ip3@example.com", "198.51.100.2", limits)
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestGlobal", func(t *testing.T) {
+		// Every email so far counts towards the caps
+		err := db.Throttle.Acquire(ctx, "hourly@example.com", "203.0.113.1", data.ThrottleLimits{Hourly: 5})
+		assert.ErrorIs(t, err, data.ErrThrottled)
+
+		err = db.Throttle.Acquire(ctx, "daily@example.com", "203.0.113.1", data.ThrottleLimits{Daily: 5})
+		assert.ErrorIs(t, err, data.ErrThrottled)
+
+		err = db.Throttle.Acquire(ctx, "daily@example.com", "203.0.113.1", data.ThrottleLimits{Hourly: 6, Daily: 6})
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestDeleteExpired", func(t *testing.T) {
+		// Nothing is older than the window yet
+		n, err := db.Throttle.DeleteExpired(ctx, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+	})
+}
@@ -0,0 +1,192 @@
+package data_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Fail instead of hanging if fn deadlocks
+func withinTimeout(t *testing.T, fn func()) {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("deadlock")
+	}
+}
+
+func TestWithTxWithoutTransactor(t *testing.T) {
+	called := false
+	err := (&data.DB{}).WithTx(context.Background(), func(tx *data.DB) error {
+		called = true
+		return nil
+	})
+	assert.ErrorIs(t, err, data.ErrNoTransactor)
+	assert.False(t, called)
+}
+
+func runTxTests(t *testing.T, db *data.DB) {
+	ctx := context.Background()
+	errRollback := errors.New("rollback")
+
+	t.Run("TestCommit", func(t *testing.T) {
+		var user *data.User
+		err := db.WithTx(ctx, func(tx *data.DB) error {
+			var err error
+			user, err = tx.Users.New(ctx, "commit@email.com", []byte("password"))
+			if err != nil {
+				return err
+			}
+
+			return tx.AuthenticationTokens.New(ctx, []byte("commit_token"), time.Now().Add(time.Hour), user.ID)
+		})
+		require.NoError(t, err)
+
+		exists, err := db.Users.Exists(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		_, err = db.AuthenticationTokens.Get(ctx, []byte("commit_token"))
+		assert.NoError(t, err)
+	})
+
+	t.Run("TestRollback", func(t *testing.T) {
+		err := db.WithTx(ctx, func(tx *data.DB) error {
+			_, err := tx.Users.New(ctx, "rollback@email.com", []byte("password"))
+			if err != nil {
+				return err
+			}
+
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+
+		exists, err := db.Users.ExistsWithEmail(ctx, "rollback@email.com")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("TestRollbackUpdate", func(t *testing.T) {
+		user, err := db.Users.New(ctx, "update@email.com", []byte("password"))
+		require.NoError(t, err)
+
+		err = db.WithTx(ctx, func(tx *data.DB) error {
+			u := *user
+			u.DisplayName = "Updated"
+			err := tx.Users.Update(ctx, &u)
+			if err != nil {
+				return err
+			}
+
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+
+		readUser, err := db.Users.Get(ctx, user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, user, readUser)
+	})
+
+	t.Run("TestNested", func(t *testing.T) {
+		err := db.WithTx(ctx, func(tx *data.DB) error {
+			_, err := tx.Users.New(ctx, "outer@email.com", []byte("password"))
+			if err != nil {
+				return err
+			}
+
+			err = tx.WithTx(ctx, func(tx *data.DB) error {
+				_, err := tx.Users.New(ctx, "inner@email.com", []byte("password"))
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			return errRollback
+		})
+		assert.ErrorIs(t, err, errRollback)
+
+		for _, email := range []string{"outer@email.com", "inner@email.com"} {
+			exists, err := db.Users.ExistsWithEmail(ctx, email)
+			assert.NoError(t, err)
+			assert.False(t, exists, email)
+		}
+	})
+
+	t.Run("TestNestedRollback", func(t *testing.T) {
+		withinTimeout(t, func() {
+			err := db.WithTx(ctx, func(tx *data.DB) error {
+				_, err := tx.Users.New(ctx, "savepoint_outer@email.com", []byte("password"))
+				if err != nil {
+					return err
+				}
+
+				err = tx.WithTx(ctx, func(tx *data.DB) error {
+					_, err := tx.Users.New(ctx, "savepoint_inner@email.com", []byte("password"))
+					if err != nil {
+						return err
+					}
+
+					return errRollback
+				})
+				assert.ErrorIs(t, err, errRollback)
+
+				// The outer transaction goes on after the savepoint
+				exists, err := tx.Users.ExistsWithEmail(ctx, "savepoint_inner@email.com")
+				if err != nil {
+					return err
+				}
+				assert.False(t, exists)
+
+				return nil
+			})
+			assert.NoError(t, err)
+		})
+
+		exists, err := db.Users.ExistsWithEmail(ctx, "savepoint_outer@email.com")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		exists, err = db.Users.ExistsWithEmail(ctx, "savepoint_inner@email.com")
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("TestConcurrent", func(t *testing.T) {
+		began := make(chan struct{})
+		release := make(chan struct{})
+
+		withinTimeout(t, func() {
+			go func() {
+				<-began
+				// Runs on another connection, or waits for the transaction
+				_, err := db.Users.ExistsWithEmail(ctx, "concurrent@email.com")
+				assert.NoError(t, err)
+				close(release)
+			}()
+
+			err := db.WithTx(ctx, func(tx *data.DB) error {
+				_, err := tx.Users.New(ctx, "concurrent@email.com", []byte("password"))
+				close(began)
+
+				return err
+			})
+			assert.NoError(t, err)
+
+			<-release
+		})
+	})
+}
@@ -17,6 +17,7 @@ type UserRepository interface {
 	GetWithAuthenticationToken(ctx context.Context, tokenHash []byte) (*User, error)
 	Exists(ctx context.Context, id uuid.UUID) (bool, error)
 	ExistsWithEmail(ctx context.Context, email string) (bool, error)
+	List(ctx context.Context, filter UserFilter, offset, limit int) ([]*User, int, error)
 	Update(ctx context.Context, user *User) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -27,6 +28,19 @@ type User struct {
 	CreatedAt    time.Time `json:"created_at"`
 	Email        string    `json:"email"`
 	PasswordHash []byte    `json:"-"`
+	Locked       bool      `json:"locked"`
+	Active       bool      `json:"active"`
+	DisplayName  string    `json:"display_name"`
+	ExternalID   string    `json:"-"`
+	Locale       string    `json:"locale"`
+}
+
+// Matches users on every non-zero field
+type UserFilter struct {
+	Email       string
+	ExternalID  string
+	DisplayName string
+	Active      *bool
 }
 
 var AnonymousUser = &User{}
@@ -2,6 +2,7 @@ package data_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -28,6 +29,8 @@ func runUserRepositoryTests(t *testing.T, db *data.DB) {
 		assert.NotNil(t, testUser)
 		assert.Equal(t, int32(1), testUser.Version)
 		assert.Equal(t, testEmail, testUser.Email)
+		assert.True(t, testUser.Active)
+		assert.Equal(t, "en", testUser.Locale)
 
 		// Duplicate email
 		_, err = db.Users.New(ctx, testEmail, []byte(testPassword))
@@ -105,6 +108,7 @@ func runUserRepositoryTests(t *testing.T, db *data.DB) {
 
 	t.Run("TestUpdate", func(t *testing.T) {
 		testUser.Email = updatedEmail
+		testUser.Locale = "de"
 		currentVersion := testUser.Version
 		err := db.Users.Update(ctx, testUser)
 		assert.NoError(t, err)
@@ -132,6 +136,58 @@ func runUserRepositoryTests(t *testing.T, db *data.DB) {
 		assert.ErrorIs(t, err, data.ErrEditConflict)
 	})
 
+	t.Run("TestUpdateProvisioning", func(t *testing.T) {
+		readUser, err := db.Users.Get(ctx, testUser.ID)
+		assert.NoError(t, err)
+
+		readUser.Active = false
+		readUser.DisplayName = "Test User"
+		readUser.ExternalID = "ext-1"
+		err = db.Users.Update(ctx, readUser)
+		assert.NoError(t, err)
+
+		testUser, err = db.Users.Get(ctx, testUser.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, readUser, testUser)
+
+		// Duplicate external id
+		newUser, err := db.Users.GetWithEmail(ctx, newEmail)
+		assert.NoError(t, err)
+
+		newUser.ExternalID = "ext-1"
+		err = db.Users.Update(ctx, newUser)
+		assert.ErrorIs(t, err, data.ErrDuplicateExternalID)
+	})
+
+	t.Run("TestList", func(t *testing.T) {
+		users, total, err := db.Users.List(ctx, data.UserFilter{}, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Len(t, users, 2)
+
+		// Case-insensitive email
+		users, total, err = db.Users.List(ctx, data.UserFilter{Email: strings.ToUpper(updatedEmail)}, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, []*data.User{testUser}, users)
+
+		users, _, err = db.Users.List(ctx, data.UserFilter{ExternalID: "ext-1"}, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []*data.User{testUser}, users)
+
+		active := true
+		users, total, err = db.Users.List(ctx, data.UserFilter{Active: &active}, 0, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, total)
+		assert.Equal(t, newEmail, users[0].Email)
+
+		// Pagination still reports the total
+		users, total, err = db.Users.List(ctx, data.UserFilter{}, 1, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, total)
+		assert.Len(t, users, 1)
+	})
+
 	t.Run("TestDelete", func(t *testing.T) {
 		err := db.Users.Delete(ctx, testUser.ID)
 		assert.NoError(t, err)
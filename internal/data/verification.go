@@ -3,27 +3,38 @@ package data
 import (
 	"context"
 	"time"
+
+	"github.com/gofrs/uuid/v5"
 )
 
 const (
 	VerificationTokenTTL = time.Hour * 36
+	EmailRevertTokenTTL  = time.Hour * 24 * 7
 	ScopeRegistration    = "registration"
 	ScopeAccountDeletion = "account-deletion"
 	ScopeEmailChange     = "email-change"
 	ScopePasswordReset   = "password-reset"
+	ScopeEmailRevert     = "email-revert"
 )
 
 type VerificationTokenRepository interface {
 	New(ctx context.Context, tokenHash []byte, expiry time.Time, scope, email string) error
+	NewForUser(ctx context.Context, tokenHash []byte, expiry time.Time, scope, email string, userID uuid.UUID) error
 	Get(ctx context.Context, tokenHash []byte) (*VerificationToken, error)
 	Exists(ctx context.Context, scope, email string) (bool, error)
 	Purge(ctx context.Context, email string) error
-	Verify(ctx context.Context, tokenHash []byte, scope, email string) error
+	// Check and delete the token in one step, so that it can only be
+	// used once. Returns ErrRecordNotFound if no token matches and
+	// ErrExpiredToken if it has expired.
+	Consume(ctx context.Context, tokenHash []byte, scope, email string) error
+	// Delete up to limit expired tokens. Returns the number deleted.
+	DeleteExpired(ctx context.Context, limit int) (int, error)
 }
 
 type VerificationToken struct {
-	Hash   []byte    `json:"-"`
-	Expiry time.Time `json:"expiry"`
-	Scope  string    `json:"-"`
-	Email  string    `json:"-"`
+	Hash   []byte        `json:"-"`
+	Expiry time.Time     `json:"expiry"`
+	Scope  string        `json:"-"`
+	Email  string        `json:"-"`
+	UserID uuid.NullUUID `json:"-"`
 }
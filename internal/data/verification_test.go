@@ -2,6 +2,10 @@ package data_test
 
 import (
 	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -30,6 +34,7 @@ func runVerificationTokenRepositoryTests(t *testing.T, db *data.DB) {
 		assert.WithinDuration(t, time.Now(), vt.Expiry, data.VerificationTokenTTL)
 		assert.Equal(t, data.ScopeRegistration, vt.Scope)
 		assert.Equal(t, testEmail, vt.Email)
+		assert.False(t, vt.UserID.Valid)
 	})
 
 	t.Run("TestExists", func(t *testing.T) {
@@ -46,6 +51,14 @@ func runVerificationTokenRepositoryTests(t *testing.T, db *data.DB) {
 		exists, err = db.VerificationTokens.Exists(ctx, data.ScopeRegistration, nonExistantEmail)
 		assert.NoError(t, err)
 		assert.False(t, exists)
+
+		// Expired token
+		err = db.VerificationTokens.New(ctx, []byte("exists_expired_hash"), time.Now().Add(-time.Minute), data.ScopePasswordReset, testEmail)
+		assert.NoError(t, err)
+
+		exists, err = db.VerificationTokens.Exists(ctx, data.ScopePasswordReset, testEmail)
+		assert.NoError(t, err)
+		assert.False(t, exists)
 	})
 
 	t.Run("TestPurge", func(t *testing.T) {
@@ -57,26 +70,105 @@ func runVerificationTokenRepositoryTests(t *testing.T, db *data.DB) {
 		assert.False(t, exists)
 	})
 
-	t.Run("TestVerify", func(t *testing.T) {
-		err := db.VerificationTokens.New(ctx, tokenHash, expiry, data.ScopeRegistration, testEmail)
+	t.Run("TestConsume", func(t *testing.T) {
+		consumeHash := []byte("consume_hash")
+		err := db.VerificationTokens.New(ctx, consumeHash, expiry, data.ScopePasswordReset, testEmail)
 		assert.NoError(t, err)
 
-		// Valid token
-		err = db.VerificationTokens.Verify(ctx, tokenHash, data.ScopeRegistration, testEmail)
+		// Incorrect scope and email don't consume the token
+		err = db.VerificationTokens.Consume(ctx, consumeHash, data.ScopeRegistration, testEmail)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		err = db.VerificationTokens.Consume(ctx, consumeHash, data.ScopePasswordReset, "other@email.com")
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+
+		// Valid token, with a case insensitive email
+		err = db.VerificationTokens.Consume(ctx, consumeHash, data.ScopePasswordReset, strings.ToUpper(testEmail))
 		assert.NoError(t, err)
 
-		// Incorrect scope
-		err = db.VerificationTokens.Verify(ctx, tokenHash, data.ScopeEmailChange, testEmail)
+		// Only once
+		err = db.VerificationTokens.Consume(ctx, consumeHash, data.ScopePasswordReset, testEmail)
 		assert.ErrorIs(t, err, data.ErrRecordNotFound)
 
 		// Expired token
-		expiredHash := []byte("expired_hash")
-		expiredExpiry := time.Now().Add(-1 * time.Minute)
-
-		err = db.VerificationTokens.New(ctx, expiredHash, expiredExpiry, data.ScopeRegistration, testEmail)
+		expiredHash := []byte("consume_expired_hash")
+		err = db.VerificationTokens.New(ctx, expiredHash, time.Now().Add(-time.Minute), data.ScopePasswordReset, testEmail)
 		assert.NoError(t, err)
 
-		err = db.VerificationTokens.Verify(ctx, expiredHash, data.ScopeRegistration, testEmail)
+		err = db.VerificationTokens.Consume(ctx, expiredHash, data.ScopePasswordReset, testEmail)
 		assert.ErrorIs(t, err, data.ErrExpiredToken)
 	})
+
+	t.Run("TestConsumeConcurrent", func(t *testing.T) {
+		concurrentHash := []byte("concurrent_hash")
+		err := db.VerificationTokens.New(ctx, concurrentHash, expiry, data.ScopeRegistration, testEmail)
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+		var consumed, notFound atomic.Int32
+		for range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				err := db.VerificationTokens.Consume(ctx, concurrentHash, data.ScopeRegistration, testEmail)
+				switch {
+				case err == nil:
+					consumed.Add(1)
+				case errors.Is(err, data.ErrRecordNotFound):
+					notFound.Add(1)
+				default:
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), consumed.Load())
+		assert.Equal(t, int32(49), notFound.Load())
+	})
+
+	t.Run("TestNewForUser", func(t *testing.T) {
+		testUser, err := db.Users.New(ctx, testEmail, []byte("super_secret_password"))
+		assert.NoError(t, err)
+
+		userHash := []byte("user_hash")
+		err = db.VerificationTokens.NewForUser(ctx, userHash, expiry, data.ScopeEmailRevert, testEmail, testUser.ID)
+		assert.NoError(t, err)
+
+		vt, err := db.VerificationTokens.Get(ctx, userHash)
+		assert.NoError(t, err)
+		assert.True(t, vt.UserID.Valid)
+		assert.Equal(t, testUser.ID, vt.UserID.UUID)
+		assert.Equal(t, data.ScopeEmailRevert, vt.Scope)
+	})
+
+	t.Run("TestDeleteExpired", func(t *testing.T) {
+		err := db.VerificationTokens.Purge(ctx, testEmail)
+		assert.NoError(t, err)
+
+		for _, hash := range []string{"expired_1", "expired_2", "expired_3"} {
+			err = db.VerificationTokens.New(ctx, []byte(hash), time.Now().Add(-time.Minute), data.ScopeRegistration, testEmail)
+			assert.NoError(t, err)
+		}
+		validHash := []byte("valid_hash")
+		err = db.VerificationTokens.New(ctx, validHash, expiry, data.ScopeRegistration, testEmail)
+		assert.NoError(t, err)
+
+		// In batches
+		n, err := db.VerificationTokens.DeleteExpired(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		n, err = db.VerificationTokens.DeleteExpired(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		n, err = db.VerificationTokens.DeleteExpired(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		_, err = db.VerificationTokens.Get(ctx, validHash)
+		assert.NoError(t, err)
+	})
 }
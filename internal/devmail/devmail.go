@@ -0,0 +1,149 @@
+// Package devmail previews every email template with sample data and
+// lists the messages captured by a mail sink. It is only mounted in
+// development mode.
+package devmail
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/micahco/mono/ui/emails"
+)
+
+// Where the apps mount the handler
+const Path = "/dev/mail"
+
+const (
+	sampleURL       = "http://localhost:5000"
+	sampleRecipient = "test@example.com"
+	sampleToken     = "MFELQNRXBDWCMXTYBEABLURIMU"
+)
+
+type preview struct {
+	name      string
+	subject   string
+	component templ.Component
+}
+
+var previews = []preview{
+	{
+		name:      "registration",
+		subject:   "Registration",
+		component: emails.Registration(sampleURL + "/auth/register?email=test%40example.com&token=" + sampleToken),
+	},
+	{
+		name:      "email-change",
+		subject:   "Email Verification",
+		component: emails.EmailChange(sampleURL + "/account/email/update?email=new%40example.com&token=" + sampleToken),
+	},
+	{
+		name:      "email-revert",
+		subject:   "Email Changed",
+		component: emails.EmailRevert("new@example.com", sampleURL+"/auth/email/revert?token="+sampleToken),
+	},
+	{
+		name:      "password-reset",
+		subject:   "Password Reset",
+		component: emails.PasswordReset(sampleURL + "/auth/reset/update?email=test%40example.com&token=" + sampleToken),
+	},
+	{
+		name:    "new-device",
+		subject: "New Login",
+		component: emails.NewDevice(
+			time.Date(2025, time.January, 2, 15, 4, 5, 0, time.UTC).Format(time.RFC1123),
+			"Portland, United States (203.0.113.7)",
+			"Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0",
+			sampleURL+"/auth/sessions/revoke?session="+sampleToken,
+		),
+	},
+	{
+		name:      "account-deletion",
+		subject:   "Account Deletion",
+		component: emails.AccountDeletion(sampleURL + "/account/delete?token=" + sampleToken),
+	},
+}
+
+type handler struct {
+	mailer *mailer.Mailer
+	sink   *mailer.Sink
+}
+
+// Create the handler. Captured messages are only listed if sink isn't nil.
+func New(m *mailer.Mailer, sink *mailer.Sink) http.Handler {
+	h := &handler{
+		mailer: m,
+		sink:   sink,
+	}
+
+	r := chi.NewRouter()
+	r.Get("/", h.index)
+	r.Get("/previews/{name}", h.preview)
+	r.Get("/messages/{id}", h.message)
+
+	return r
+}
+
+func (h *handler) index(w http.ResponseWriter, r *http.Request) {
+	var messages []*mailer.SinkMessage
+	if h.sink != nil {
+		messages = h.sink.Messages()
+	}
+
+	h.render(w, r, index(previews, i18n.Locales(), h.sink != nil, messages))
+}
+
+// Render the email in the locale of the query
+func (h *handler) preview(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+
+	for _, p := range previews {
+		if p.name != name {
+			continue
+		}
+
+		ctx := i18n.WithLocale(r.Context(), r.URL.Query().Get("locale"))
+		msg, err := h.mailer.Render(ctx, sampleRecipient, p.subject, p.component)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		h.render(w, r, message(msg, nil))
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+func (h *handler) message(w http.ResponseWriter, r *http.Request) {
+	if h.sink == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	msg, ok := h.sink.Get(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	h.render(w, r, message(&msg.Message, msg.Raw))
+}
+
+func (h *handler) render(w http.ResponseWriter, r *http.Request, component templ.Component) {
+	err := component.Render(r.Context(), w)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
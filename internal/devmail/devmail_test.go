@@ -0,0 +1,76 @@
+package devmail
+
+import (
+	"context"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"testing"
+
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/micahco/mono/ui/emails"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSender = &mail.Address{Name: "Do Not Reply", Address: "no-reply@example.com"}
+
+func get(t *testing.T, h http.Handler, target string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+	return w
+}
+
+func TestPreviews(t *testing.T) {
+	h := New(mailer.New(mailer.NewMemory(), testSender, nil), nil)
+
+	w := get(t, h, "/")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "-mail-transport sink")
+
+	for _, p := range previews {
+		for _, locale := range i18n.Locales() {
+			w := get(t, h, "/previews/"+p.name+"?locale="+locale)
+			assert.Equal(t, http.StatusOK, w.Code, p.name)
+
+			ctx := i18n.WithLocale(context.Background(), locale)
+			assert.Contains(t, w.Body.String(), "<h1>"+html.EscapeString(i18n.T(ctx, p.subject))+"</h1>", p.name)
+		}
+	}
+
+	w = get(t, h, "/previews/unknown")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = get(t, h, "/messages/1")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestMessages(t *testing.T) {
+	sink, err := mailer.ListenSink("localhost:0")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	m := mailer.New(sink.SMTP(), testSender, nil)
+	h := New(m, sink)
+
+	w := get(t, h, "/")
+	assert.Contains(t, w.Body.String(), "No messages yet.")
+
+	err = m.Send(context.Background(), "test@example.com", "Registration", emails.Registration("http://localhost:5000/auth/register"))
+	require.NoError(t, err)
+
+	w = get(t, h, "/")
+	assert.Contains(t, w.Body.String(), `href="/dev/mail/messages/1"`)
+
+	w = get(t, h, "/messages/1")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "http://localhost:5000/auth/register")
+
+	w = get(t, h, "/messages/2")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
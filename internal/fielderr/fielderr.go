@@ -0,0 +1,89 @@
+// Package fielderr describes why fields of a request are invalid, in a
+// form that clients can act on without parsing messages. Codes are part of
+// the API: they may be added to, but never changed or removed.
+package fielderr
+
+import (
+	"sort"
+	"strings"
+)
+
+// Error codes
+const (
+	// Missing or empty value
+	CodeRequired = "required"
+	// Value that isn't an email address
+	CodeInvalidEmail = "invalid_email"
+	// Value shorter than the "min" param
+	CodeTooShort = "too_short"
+	// Value longer than the "max" param
+	CodeTooLong = "too_long"
+	// Value that isn't one of the "allowed" param
+	CodeNotAllowed = "not_allowed"
+	// Value that is wrong for any other reason
+	CodeInvalid = "invalid"
+
+	// Body that isn't valid JSON
+	CodeInvalidJSON = "invalid_json"
+	// Value of a different JSON type than the "expected" param
+	CodeInvalidType = "invalid_type"
+	// Missing body
+	CodeEmptyBody = "empty_body"
+	// Field that the request doesn't have
+	CodeUnknownField = "unknown_field"
+	// Body larger than the "max" param, in bytes
+	CodeTooLarge = "too_large"
+	// Body of more than one JSON value
+	CodeMultipleValues = "multiple_values"
+)
+
+// Field of errors about the request body as a whole, rather than one of
+// its fields
+const Body = "body"
+
+type Error struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Params  map[string]any `json:"params,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Errors of each field, by field name
+type Errors map[string][]*Error
+
+// Add an error to the field
+func (e Errors) Add(field, code, message string, params map[string]any) {
+	e[field] = append(e[field], &Error{
+		Code:    code,
+		Message: message,
+		Params:  params,
+	})
+}
+
+// Messages of every field, sorted by field name, e.g.
+// "email: invalid email; password: too short"
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var b strings.Builder
+	for i, field := range fields {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+
+		messages := make([]string, len(e[field]))
+		for j, err := range e[field] {
+			messages[j] = err.Message
+		}
+		b.WriteString(field + ": " + strings.Join(messages, ", "))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,30 @@
+package fielderr
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrors(t *testing.T) {
+	errs := Errors{}
+	errs.Add("password", CodeTooShort, "the length must be between 8 and 72", map[string]any{"min": 8})
+	errs.Add("email", CodeRequired, "cannot be blank", nil)
+	errs.Add("email", CodeInvalidEmail, "must be a valid email address", nil)
+
+	assert.Equal(t, "email: cannot be blank, must be a valid email address; password: the length must be between 8 and 72", errs.Error())
+
+	js, err := json.Marshal(errs)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"email": [
+			{"code": "required", "message": "cannot be blank"},
+			{"code": "invalid_email", "message": "must be a valid email address"}
+		],
+		"password": [
+			{"code": "too_short", "message": "the length must be between 8 and 72", "params": {"min": 8}}
+		]
+	}`, string(js))
+}
@@ -0,0 +1,70 @@
+package geoip
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+const unknownLocation = "Unknown location"
+
+// Resolves IP addresses to a coarse location using a local MaxMind
+// GeoIP2 or GeoLite2 City database file.
+type Locator struct {
+	reader *geoip2.Reader
+}
+
+// Open the database file. An empty path returns a Locator that reports
+// every address as an unknown location.
+func Open(path string) (*Locator, error) {
+	if path == "" {
+		return &Locator{}, nil
+	}
+
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Locator{reader}, nil
+}
+
+// Returns "City, Country" for the address, or as much of it as is known.
+func (l *Locator) Locate(ip string) string {
+	if l.reader == nil {
+		return unknownLocation
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return unknownLocation
+	}
+
+	record, err := l.reader.City(addr)
+	if err != nil {
+		return unknownLocation
+	}
+
+	var parts []string
+	if city := record.City.Names["en"]; city != "" {
+		parts = append(parts, city)
+	}
+	if country := record.Country.Names["en"]; country != "" {
+		parts = append(parts, country)
+	}
+	if len(parts) == 0 {
+		return unknownLocation
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Close the database file
+func (l *Locator) Close() error {
+	if l.reader == nil {
+		return nil
+	}
+
+	return l.reader.Close()
+}
@@ -0,0 +1,137 @@
+// Package i18n translates the user facing strings of pages and emails.
+// Messages are keyed by their English text, which is also the fallback
+// when a catalog has no translation.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+const DefaultLocale = "en"
+
+// Supported locales, the first is the default
+var supported = []language.Tag{
+	language.English,
+	language.German,
+	language.French,
+}
+
+var matcher = language.NewMatcher(supported)
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var cat = mustLoad()
+
+// Build the catalog from the embedded JSON files, one per locale,
+// mapping English messages to their translation
+func mustLoad() catalog.Catalog {
+	b := catalog.NewBuilder(catalog.Fallback(language.English))
+
+	entries, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	for _, e := range entries {
+		tag := language.MustParse(strings.TrimSuffix(e.Name(), ".json"))
+
+		buf, err := locales.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			panic(err)
+		}
+
+		var messages map[string]string
+		err = json.Unmarshal(buf, &messages)
+		if err != nil {
+			panic(err)
+		}
+
+		for key, msg := range messages {
+			err = b.SetString(tag, key, msg)
+			if err != nil {
+				panic(err)
+			}
+		}
+	}
+
+	return b
+}
+
+// Supported locales
+func Locales() []string {
+	locales := make([]string, len(supported))
+	for i, tag := range supported {
+		locales[i] = tag.String()
+	}
+
+	return locales
+}
+
+// Name of the locale in its own language
+func Name(locale string) string {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return locale
+	}
+
+	return display.Self.Name(tag)
+}
+
+func IsSupported(locale string) bool {
+	for _, tag := range supported {
+		if tag.String() == locale {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Negotiate the supported locale that best matches the preferences, in
+// the format of an Accept-Language header
+func Match(accept string) string {
+	prefs, _, err := language.ParseAcceptLanguage(accept)
+	if err != nil {
+		return DefaultLocale
+	}
+
+	_, i, _ := matcher.Match(prefs...)
+
+	return supported[i].String()
+}
+
+type contextKey struct{}
+
+// Returns a copy of the context that translates into the locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// Locale of the context, or the default locale if it has none
+func Locale(ctx context.Context) string {
+	locale, ok := ctx.Value(contextKey{}).(string)
+	if !ok || !IsSupported(locale) {
+		return DefaultLocale
+	}
+
+	return locale
+}
+
+// Translate the message into the locale of the context. Arguments are
+// formatted like fmt.Sprintf.
+func T(ctx context.Context, key string, args ...any) string {
+	tag := language.MustParse(Locale(ctx))
+	p := message.NewPrinter(tag, message.Catalog(cat))
+
+	return p.Sprintf(key, args...)
+}
@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "en"},
+		{"de", "de"},
+		{"de-AT,de;q=0.9,en;q=0.8", "de"},
+		{"fr-CH, fr;q=0.9, en;q=0.8", "fr"},
+		{"es,fr;q=0.5", "fr"},
+		{"ja", "en"},
+		{"not a language", "en"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Match(tt.accept), tt.accept)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, DefaultLocale, Locale(ctx))
+	assert.Equal(t, "fr", Locale(WithLocale(ctx, "fr")))
+
+	// Unsupported locales fall back to the default
+	assert.Equal(t, DefaultLocale, Locale(WithLocale(ctx, "es")))
+}
+
+func TestT(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "Password Reset", T(ctx, "Password Reset"))
+	assert.Equal(t, "Passwort zurücksetzen", T(WithLocale(ctx, "de"), "Password Reset"))
+	assert.Equal(t, "Réinitialisation du mot de passe", T(WithLocale(ctx, "fr"), "Password Reset"))
+
+	assert.Equal(t, "Ihre E-Mail-Adresse in a@b.c ändern?", T(WithLocale(ctx, "de"), "Change your email to %s?", "a@b.c"))
+
+	// Untranslated messages are returned as is
+	assert.Equal(t, "Unknown", T(WithLocale(ctx, "de"), "Unknown"))
+}
+
+// Every catalog translates the same messages with the same arguments
+func TestCatalogs(t *testing.T) {
+	catalogs := map[string]map[string]string{}
+	for _, locale := range Locales()[1:] {
+		buf, err := locales.ReadFile("locales/" + locale + ".json")
+		require.NoError(t, err)
+
+		var messages map[string]string
+		require.NoError(t, json.Unmarshal(buf, &messages))
+		catalogs[locale] = messages
+	}
+
+	for locale, messages := range catalogs {
+		for key, msg := range messages {
+			assert.Equal(t, strings.Count(key, "%"), strings.Count(msg, "%"), "%s: %s", locale, key)
+
+			for other, otherMessages := range catalogs {
+				_, ok := otherMessages[key]
+				assert.True(t, ok, "%s is missing %q", other, key)
+			}
+		}
+	}
+}
+
+func TestName(t *testing.T) {
+	assert.Equal(t, "English", Name("en"))
+	assert.Equal(t, "Deutsch", Name("de"))
+	assert.Equal(t, "français", Name("fr"))
+}
@@ -0,0 +1,112 @@
+// Package janitor periodically deletes expired rows that are otherwise
+// only rejected when they are read, and old emails that were never
+// delivered. Sessions are not included, since the session stores clean up
+// after themselves.
+package janitor
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+)
+
+const (
+	DefaultInterval  = time.Hour
+	DefaultBatchSize = 1000
+)
+
+// Published at /debug/vars
+var metrics = expvar.NewMap("janitor")
+
+type Janitor struct {
+	db        *data.DB
+	logger    *slog.Logger
+	interval  time.Duration
+	batchSize int
+}
+
+func New(db *data.DB, logger *slog.Logger, interval time.Duration) *Janitor {
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	return &Janitor{
+		db:        db,
+		logger:    logger,
+		interval:  interval,
+		batchSize: DefaultBatchSize,
+	}
+}
+
+// Sweep on every interval until the context is canceled
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		err := j.Sweep(ctx)
+		if err != nil && ctx.Err() == nil {
+			metrics.Add("errors", 1)
+			j.logger.Error("janitor: sweep failed", slog.Any("err", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Delete every expired row, one batch at a time so that the tables
+// aren't locked for long.
+func (j *Janitor) Sweep(ctx context.Context) error {
+	start := time.Now()
+
+	tables := []struct {
+		name          string
+		deleteExpired func(ctx context.Context, limit int) (int, error)
+	}{
+		{"verification_tokens", j.db.VerificationTokens.DeleteExpired},
+		{"authentication_tokens", j.db.AuthenticationTokens.DeleteExpired},
+		{"device_sessions", j.db.Devices.DeleteExpiredSessions},
+		{"saml", j.db.SAML.DeleteExpired},
+		{"throttle", j.db.Throttle.DeleteExpired},
+		{"outbox", j.deleteDeadOutbox},
+	}
+
+	for _, t := range tables {
+		var total int
+		for {
+			n, err := t.deleteExpired(ctx, j.batchSize)
+			if err != nil {
+				return err
+			}
+
+			total += n
+			metrics.Add(t.name+"_deleted", int64(n))
+
+			if n < j.batchSize {
+				break
+			}
+		}
+
+		if total > 0 {
+			j.logger.Info("janitor: deleted expired rows", slog.String("table", t.name), slog.Int("count", total))
+		}
+	}
+
+	metrics.Add("sweeps", 1)
+	metrics.Add("sweep_time_μs", time.Since(start).Microseconds())
+
+	return nil
+}
+
+// Delete dead emails after the retention period, since their bodies hold
+// plaintext tokens
+func (j *Janitor) deleteDeadOutbox(ctx context.Context, limit int) (int, error) {
+	return j.db.Outbox.DeleteDead(ctx, time.Now().Add(-data.OutboxRetention), limit)
+}
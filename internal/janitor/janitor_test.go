@@ -0,0 +1,52 @@
+package janitor
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/data/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSweep(t *testing.T) {
+	ctx := context.Background()
+	db := memory.NewMemoryDB().DB
+
+	user, err := db.Users.New(ctx, "test@email.com", []byte("password"))
+	require.NoError(t, err)
+
+	expired := time.Now().Add(-time.Minute)
+	for _, hash := range []string{"expired_1", "expired_2", "expired_3", "expired_4", "expired_5"} {
+		err = db.VerificationTokens.New(ctx, []byte(hash), expired, data.ScopeRegistration, user.Email)
+		require.NoError(t, err)
+	}
+	err = db.VerificationTokens.New(ctx, []byte("valid"), time.Now().Add(time.Hour), data.ScopeRegistration, user.Email)
+	require.NoError(t, err)
+	err = db.AuthenticationTokens.New(ctx, []byte("expired"), expired, user.ID)
+	require.NoError(t, err)
+	err = db.Devices.NewSession(ctx, []byte("expired"), "session", expired, user.ID)
+	require.NoError(t, err)
+
+	j := New(db, slog.New(slog.NewTextHandler(io.Discard, nil)), 0)
+	j.batchSize = 2
+
+	err = j.Sweep(ctx)
+	require.NoError(t, err)
+
+	_, err = db.VerificationTokens.Get(ctx, []byte("expired_1"))
+	assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	_, err = db.VerificationTokens.Get(ctx, []byte("expired_5"))
+	assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	_, err = db.VerificationTokens.Get(ctx, []byte("valid"))
+	assert.NoError(t, err)
+	_, err = db.AuthenticationTokens.Get(ctx, []byte("expired"))
+	assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	assert.Equal(t, "1", metrics.Get("device_sessions_deleted").String())
+
+	assert.Equal(t, "5", metrics.Get("verification_tokens_deleted").String())
+}
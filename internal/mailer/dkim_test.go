@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/emersion/go-msgauth/dkim"
+	"github.com/micahco/mono/ui/emails"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Verify the message against the public key of d, as if it was
+// published in DNS
+func verifyDKIM(t *testing.T, d *DKIM, raw []byte) error {
+	t.Helper()
+
+	record, err := d.Record()
+	require.NoError(t, err)
+
+	options := &dkim.VerifyOptions{
+		LookupTXT: func(domain string) ([]string, error) {
+			if domain != d.RecordName() {
+				return nil, fmt.Errorf("no TXT record for %s", domain)
+			}
+
+			return []string{record}, nil
+		},
+	}
+
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(raw), options)
+	require.NoError(t, err)
+	require.Len(t, verifications, 1)
+	assert.Equal(t, "example.com", verifications[0].Domain)
+
+	return verifications[0].Err
+}
+
+func TestDKIM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		key  crypto.Signer
+	}{
+		{"RSA", rsaKey},
+		{"Ed25519", edKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDKIM("example.com", "mono", tt.key)
+			require.NoError(t, err)
+
+			transport := NewMemory()
+			m := New(transport, testSender, d)
+
+			err = m.Send(context.Background(), "test@example.com", "Registration", emails.Registration(testHref))
+			require.NoError(t, err)
+
+			msgs := transport.Messages()
+			require.Len(t, msgs, 1)
+			raw, err := msgs[0].Bytes()
+			require.NoError(t, err)
+			assert.True(t, bytes.HasPrefix(raw, []byte("DKIM-Signature:")))
+
+			err = verifyDKIM(t, d, raw)
+			assert.NoError(t, err)
+
+			// Relaxed canonicalization ignores whitespace and header case
+			relaxed := bytes.Replace(raw, []byte("Subject: Registration"), []byte("subject:   Registration "), 1)
+			err = verifyDKIM(t, d, relaxed)
+			assert.NoError(t, err)
+
+			// But not changes to the content
+			tampered := bytes.Replace(raw, []byte("Subject: Registration"), []byte("Subject: Password Reset"), 1)
+			err = verifyDKIM(t, d, tampered)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadDKIM(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+
+	files := map[string]*pem.Block{
+		"rsa.pem":     {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)},
+		"ed25519.pem": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	}
+	for name, block := range files {
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600)
+		require.NoError(t, err)
+
+		_, err = LoadDKIM("example.com", "mono", path)
+		assert.NoError(t, err, name)
+	}
+
+	_, err = LoadDKIM("example.com", "mono", filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package mailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Writes messages to a maildir as .eml files, for reading with a mail
+// client during development. Files are written to tmp and then moved to
+// new, so readers never see partial messages.
+type File struct {
+	dir string
+}
+
+// Create the maildir if it doesn't exist
+func NewFile(dir string) (*File, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		err := os.MkdirAll(filepath.Join(dir, sub), 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &File{dir: dir}, nil
+}
+
+func (f *File) SendMessage(msg *Message) error {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 8)
+	_, err = rand.Read(b)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.%s.eml", time.Now().UnixNano(), hex.EncodeToString(b))
+
+	tmp := filepath.Join(f.dir, "tmp", name)
+	err = os.WriteFile(tmp, raw, 0o644)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return os.Rename(tmp, filepath.Join(f.dir, "new", name))
+}
@@ -3,50 +3,120 @@ package mailer
 import (
 	"bytes"
 	"context"
+	"html"
 	"net/mail"
+	"regexp"
 
 	"github.com/a-h/templ"
 	"github.com/k3a/html2text"
+	"github.com/micahco/mono/internal/i18n"
 	"gopkg.in/gomail.v2"
 )
 
+// Delivers rendered messages
+type Sender interface {
+	SendMessage(msg *Message) error
+}
+
+// Renders emails from the sender address and delivers them with a
+// transport
 type Mailer struct {
-	dialer *gomail.Dialer
-	sender *mail.Address
+	transport Sender
+	sender    *mail.Address
+	dkim      *DKIM
+}
+
+// Rendered email, ready to be delivered
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+
+	// Signs the encoded message, if set
+	dkim *DKIM
 }
 
-// Create new mailer with SMTP credentials
-func New(host string, port int, username string, password string, sender *mail.Address) (*Mailer, error) {
-	m := &Mailer{
-		dialer: gomail.NewDialer(host, port, username, password),
-		sender: sender,
+// Create a mailer. Messages are signed with dkim unless it's nil.
+func New(transport Sender, sender *mail.Address, dkim *DKIM) *Mailer {
+	return &Mailer{
+		transport: transport,
+		sender:    sender,
+		dkim:      dkim,
 	}
+}
 
-	// Ping the SMTP server to verify authentication
-	s, err := m.dialer.Dial()
+func (m *Mailer) Send(ctx context.Context, recepient, subject string, component templ.Component) error {
+	msg, err := m.Render(ctx, recepient, subject, component)
+	if err != nil {
+		return err
+	}
+
+	return m.SendMessage(msg)
+}
+
+// Render the component into a message from the mailer's sender. The
+// subject and body are translated into the locale of the context.
+func (m *Mailer) Render(ctx context.Context, recepient, subject string, component templ.Component) (*Message, error) {
+	var buf bytes.Buffer
+	err := component.Render(ctx, &buf)
 	if err != nil {
 		return nil, err
 	}
-	defer s.Close()
 
-	return m, nil
+	msg := &Message{
+		From:    m.sender.String(),
+		To:      recepient,
+		Subject: i18n.T(ctx, subject),
+		HTML:    buf.String(),
+	}
+
+	return msg, nil
+}
+
+func (m *Mailer) SendMessage(msg *Message) error {
+	c := *msg
+	c.dkim = m.dkim
+
+	return m.transport.SendMessage(&c)
 }
 
-func (m *Mailer) Send(recepient, subject string, component templ.Component) error {
-	ctx := context.Background()
+// Plain text alternative of the HTML body
+func (msg *Message) Text() string {
+	return html2text.HTML2Text(msg.HTML)
+}
+
+var hrefRX = regexp.MustCompile(`href="([^"]*)"`)
+
+// Targets of the links in the HTML body, in order
+func (msg *Message) Links() []string {
+	links := []string{}
+	for _, m := range hrefRX.FindAllStringSubmatch(msg.HTML, -1) {
+		links = append(links, html.UnescapeString(m[1]))
+	}
+
+	return links
+}
+
+// Encode the message in the Internet Message Format as a multipart
+// message with the HTML body and its plain text alternative
+func (msg *Message) Bytes() ([]byte, error) {
+	gm := gomail.NewMessage()
+	gm.SetHeader("To", msg.To)
+	gm.SetHeader("From", msg.From)
+	gm.SetHeader("Subject", msg.Subject)
+	gm.SetBody("text/plain", msg.Text())
+	gm.AddAlternative("text/html", msg.HTML)
 
 	var buf bytes.Buffer
-	err := component.Render(ctx, &buf)
+	_, err := gm.WriteTo(&buf)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	msg := gomail.NewMessage()
-	msg.SetHeader("To", recepient)
-	msg.SetHeader("From", m.sender.String())
-	msg.SetHeader("Subject", subject)
-	msg.SetBody("text/plain", html2text.HTML2Text(buf.String()))
-	msg.AddAlternative("text/html", buf.String())
+	if msg.dkim == nil {
+		return buf.Bytes(), nil
+	}
 
-	return m.dialer.DialAndSend(msg)
+	return msg.dkim.Sign(buf.Bytes())
 }
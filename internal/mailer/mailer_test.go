@@ -0,0 +1,91 @@
+package mailer
+
+import (
+	"context"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/ui/emails"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSender = &mail.Address{Name: "Do Not Reply", Address: "no-reply@example.com"}
+
+const testHref = "http://localhost:5000/auth/register?email=test%40example.com&token=MFELQNRXBDWCMXTYBEABLURIMU"
+
+func TestMemory(t *testing.T) {
+	transport := NewMemory()
+	m := New(transport, testSender, nil)
+
+	err := m.Send(context.Background(), "test@example.com", "Registration", emails.Registration(testHref))
+	require.NoError(t, err)
+	err = m.Send(context.Background(), "other@example.com", "Password Reset", emails.PasswordReset(testHref))
+	require.NoError(t, err)
+
+	assert.Len(t, transport.Messages(), 2)
+
+	msgs := transport.MessagesTo("test@example.com")
+	require.Len(t, msgs, 1)
+	assert.Equal(t, testSender.String(), msgs[0].From)
+	assert.Equal(t, "Registration", msgs[0].Subject)
+	assert.Contains(t, msgs[0].Text(), "Create Account")
+
+	links := msgs[0].Links()
+	require.Len(t, links, 1)
+	u, err := url.Parse(links[0])
+	require.NoError(t, err)
+	assert.Equal(t, "MFELQNRXBDWCMXTYBEABLURIMU", u.Query().Get("token"))
+	assert.Equal(t, "test@example.com", u.Query().Get("email"))
+
+	transport.Reset()
+	assert.Empty(t, transport.Messages())
+}
+
+func TestLocalized(t *testing.T) {
+	transport := NewMemory()
+	m := New(transport, testSender, nil)
+
+	ctx := i18n.WithLocale(context.Background(), "de")
+	err := m.Send(ctx, "test@example.com", "Registration", emails.Registration(testHref))
+	require.NoError(t, err)
+
+	msgs := transport.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "Registrierung", msgs[0].Subject)
+	assert.Contains(t, msgs[0].Text(), "Konto erstellen")
+}
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+
+	transport, err := NewFile(dir)
+	require.NoError(t, err)
+	m := New(transport, testSender, nil)
+
+	err = m.Send(context.Background(), "test@example.com", "Registration", emails.Registration(testHref))
+	require.NoError(t, err)
+
+	tmp, err := os.ReadDir(filepath.Join(dir, "tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, tmp)
+
+	entries, err := os.ReadDir(filepath.Join(dir, "new"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ".eml", filepath.Ext(entries[0].Name()))
+
+	f, err := os.Open(filepath.Join(dir, "new", entries[0].Name()))
+	require.NoError(t, err)
+	defer f.Close()
+
+	msg, err := mail.ReadMessage(f)
+	require.NoError(t, err)
+	assert.Equal(t, "test@example.com", msg.Header.Get("To"))
+	assert.Equal(t, "Registration", msg.Header.Get("Subject"))
+	assert.Contains(t, msg.Header.Get("Content-Type"), "multipart/alternative")
+}
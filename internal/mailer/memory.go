@@ -0,0 +1,57 @@
+package mailer
+
+import "sync"
+
+// Keeps sent messages in memory so that tests can inspect them
+type Memory struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) SendMessage(msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c := *msg
+	m.messages = append(m.messages, &c)
+
+	return nil
+}
+
+// Messages sent so far, oldest first
+func (m *Memory) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	msgs := make([]*Message, len(m.messages))
+	for i, msg := range m.messages {
+		c := *msg
+		msgs[i] = &c
+	}
+
+	return msgs
+}
+
+// Messages sent to the recipient, oldest first
+func (m *Memory) MessagesTo(recipient string) []*Message {
+	msgs := []*Message{}
+	for _, msg := range m.Messages() {
+		if msg.To == recipient {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	return msgs
+}
+
+// Forget every sent message
+func (m *Memory) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
@@ -0,0 +1,253 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maximum number of messages kept by a sink, older messages are dropped
+const sinkCapacity = 100
+
+// Minimal SMTP server that keeps the messages it receives in memory, so
+// that development doesn't need an external mail catcher. Only the
+// commands used by the SMTP transport are implemented.
+type Sink struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	messages []*SinkMessage
+	nextID   int
+}
+
+// Message received by a sink
+type SinkMessage struct {
+	Message
+	ID         int
+	ReceivedAt time.Time
+	Raw        []byte
+}
+
+// Start a sink listening on the address, e.g. localhost:0 for any free
+// port
+func ListenSink(addr string) (*Sink, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Sink{
+		listener: l,
+		nextID:   1,
+	}
+	go s.serve()
+
+	return s, nil
+}
+
+// SMTP transport that delivers to the sink
+func (s *Sink) SMTP() *SMTP {
+	addr := s.listener.Addr().(*net.TCPAddr)
+
+	return NewSMTP(addr.IP.String(), addr.Port, "", "")
+}
+
+func (s *Sink) Close() error {
+	return s.listener.Close()
+}
+
+// Messages received so far, newest first
+func (s *Sink) Messages() []*SinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgs := make([]*SinkMessage, len(s.messages))
+	for i, msg := range s.messages {
+		c := *msg
+		msgs[len(msgs)-1-i] = &c
+	}
+
+	return msgs
+}
+
+// Message with the ID, if it is still kept
+func (s *Sink) Get(id int) (*SinkMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, msg := range s.messages {
+		if msg.ID == id {
+			c := *msg
+			return &c, true
+		}
+	}
+
+	return nil, false
+}
+
+func (s *Sink) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(conn)
+	}
+}
+
+func (s *Sink) handle(conn net.Conn) {
+	defer conn.Close()
+
+	tc := textproto.NewConn(conn)
+	tc.PrintfLine("220 localhost ESMTP sink")
+
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, _, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "HELO", "EHLO":
+			tc.PrintfLine("250 localhost")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			tc.PrintfLine("250 OK")
+		case "DATA":
+			tc.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+
+			raw, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			err = s.receive(raw)
+			if err != nil {
+				tc.PrintfLine("554 %s", err)
+				continue
+			}
+
+			tc.PrintfLine("250 OK")
+		case "QUIT":
+			tc.PrintfLine("221 Bye")
+			return
+		default:
+			tc.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+func (s *Sink) receive(raw []byte) error {
+	msg, err := parseMessage(raw)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, &SinkMessage{
+		Message:    *msg,
+		ID:         s.nextID,
+		ReceivedAt: time.Now(),
+		Raw:        raw,
+	})
+	s.nextID++
+
+	if len(s.messages) > sinkCapacity {
+		s.messages = s.messages[len(s.messages)-sinkCapacity:]
+	}
+
+	return nil
+}
+
+// Decode the headers and the HTML body of an encoded message. Plain text
+// bodies are escaped into HTML.
+func parseMessage(raw []byte) (*Message, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+
+	dec := new(mime.WordDecoder)
+	header := func(key string) string {
+		v, err := dec.DecodeHeader(m.Header.Get(key))
+		if err != nil {
+			return m.Header.Get(key)
+		}
+		return v
+	}
+
+	msg := &Message{
+		From:    header("From"),
+		To:      header("To"),
+		Subject: header("Subject"),
+	}
+
+	msg.HTML, err = parseBody(textproto.MIMEHeader(m.Header), m.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return msg, nil
+}
+
+func parseBody(header textproto.MIMEHeader, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+
+		// Prefer the HTML alternative
+		var text string
+		for {
+			p, err := mr.NextPart()
+			if errors.Is(err, io.EOF) {
+				return text, nil
+			}
+			if err != nil {
+				return "", err
+			}
+
+			s, err := parseBody(p.Header, p)
+			if err != nil {
+				return "", err
+			}
+
+			if strings.HasPrefix(p.Header.Get("Content-Type"), "text/html") {
+				return s, nil
+			}
+			text = s
+		}
+	}
+
+	// Multipart readers decode quoted-printable parts already
+	if strings.EqualFold(header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	if mediaType == "text/html" {
+		return string(b), nil
+	}
+
+	return "<pre>" + html.EscapeString(string(b)) + "</pre>", nil
+}
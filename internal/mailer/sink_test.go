@@ -0,0 +1,50 @@
+package mailer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/ui/emails"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSink(t *testing.T) {
+	sink, err := ListenSink("localhost:0")
+	require.NoError(t, err)
+	defer sink.Close()
+
+	transport := sink.SMTP()
+	require.NoError(t, transport.Ping())
+
+	m := New(transport, testSender, nil)
+
+	err = m.Send(context.Background(), "test@example.com", "Registration", emails.Registration(testHref))
+	require.NoError(t, err)
+
+	// Non-ASCII subjects are encoded
+	ctx := i18n.WithLocale(context.Background(), "fr")
+	err = m.Send(ctx, "other@example.com", "Password Reset", emails.PasswordReset(testHref))
+	require.NoError(t, err)
+
+	msgs := sink.Messages()
+	require.Len(t, msgs, 2)
+
+	// Newest first
+	assert.Equal(t, 2, msgs[0].ID)
+	assert.Equal(t, "other@example.com", msgs[0].To)
+	assert.Equal(t, "Réinitialisation du mot de passe", msgs[0].Subject)
+
+	msg, ok := sink.Get(1)
+	require.True(t, ok)
+	assert.Equal(t, testSender.String(), msg.From)
+	assert.Equal(t, "test@example.com", msg.To)
+	assert.Equal(t, "Registration", msg.Subject)
+	assert.Contains(t, msg.HTML, "<h1>Create Account</h1>")
+	assert.Equal(t, []string{testHref}, msg.Links())
+	assert.Contains(t, string(msg.Raw), "Subject: Registration")
+
+	_, ok = sink.Get(3)
+	assert.False(t, ok)
+}
@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"bytes"
+	"net/mail"
+
+	"gopkg.in/gomail.v2"
+)
+
+// Delivers messages to an SMTP server. A connection is opened for every
+// message.
+type SMTP struct {
+	dialer *gomail.Dialer
+}
+
+func NewSMTP(host string, port int, username, password string) *SMTP {
+	return &SMTP{
+		dialer: gomail.NewDialer(host, port, username, password),
+	}
+}
+
+// Connect to the server to verify the address and credentials
+func (s *SMTP) Ping() error {
+	sc, err := s.dialer.Dial()
+	if err != nil {
+		return err
+	}
+
+	return sc.Close()
+}
+
+func (s *SMTP) SendMessage(msg *Message) error {
+	from, err := mail.ParseAddress(msg.From)
+	if err != nil {
+		return err
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	sc, err := s.dialer.Dial()
+	if err != nil {
+		return err
+	}
+	defer sc.Close()
+
+	return sc.Send(from.Address, []string{to.Address}, bytes.NewReader(raw))
+}
@@ -1,8 +1,14 @@
 package middleware
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"expvar"
+	"net"
 	"net/http"
+	"net/netip"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -11,7 +17,55 @@ import (
 )
 
 // Handles user facing messages. Mimics http.Error()
-type ErrorResponseFunc func(w http.ResponseWriter, message string, statusCode int)
+type ErrorResponseFunc func(w http.ResponseWriter, r *http.Request, message string, statusCode int)
+
+// Header that identifies a request in logs and error responses
+const RequestIDHeader = "X-Request-Id"
+
+type contextKey string
+
+const requestIDContextKey = contextKey("requestID")
+
+// Identifies every request by the ID of its X-Request-Id header, if it
+// has a sane one, or else by a random ID. The ID is echoed in the
+// response header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Returns the ID of the request, or an empty string outside of RequestID
+func GetRequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+
+	return id
+}
+
+// IDs of proxies and clients are kept if they are short and printable
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
 
 func WithTimeout(timeout time.Duration) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -27,11 +81,13 @@ func Profiler() http.Handler {
 	return middleware.Profiler()
 }
 
-func Metrics(next http.Handler) http.Handler {
-	totalRequestsReceived := expvar.NewInt("total_requests_received")
-	totalResponsesSent := expvar.NewInt("total_responses_sent")
-	totalProcessingTimeMicroseconds := expvar.NewInt("total_processing_time_μs")
+var (
+	totalRequestsReceived           = expvar.NewInt("total_requests_received")
+	totalResponsesSent              = expvar.NewInt("total_responses_sent")
+	totalProcessingTimeMicroseconds = expvar.NewInt("total_processing_time_μs")
+)
 
+func Metrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -56,6 +112,7 @@ func EnableCORS(trustedOrigins []string) func(next http.Handler) http.Handler {
 				for i := range trustedOrigins {
 					if origin == trustedOrigins[i] {
 						w.Header().Set("Access-Control-Allow-Origin", origin)
+						w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader+", Deprecation")
 
 						// Respond to preflight request
 						if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
@@ -78,9 +135,11 @@ func RateLimit(rps int, errResponse ErrorResponseFunc) func(next http.Handler) h
 	return httprate.Limit(
 		rps,
 		time.Second,
-		httprate.WithKeyByRealIP(),
+		httprate.WithKeyFuncs(func(r *http.Request) (string, error) {
+			return ClientKey(r), nil
+		}),
 		httprate.WithErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
-			errResponse(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			errResponse(w, r, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
 		}),
 	)
 }
@@ -113,3 +172,128 @@ func NoSurf(failureHandler http.Handler) func(next http.Handler) http.Handler {
 		return csrfHandler
 	}
 }
+
+const clientIPContextKey = contextKey("clientIP")
+
+// Resolves the IP address of the client that made the request. Requests
+// from the trusted proxies, which are IP addresses or prefixes, are from
+// the client in their forwarding headers. Other clients could set these
+// headers to anything, so they are ignored. Panics if a trusted proxy is
+// invalid.
+func RealIP(trustedProxies []string) func(next http.Handler) http.Handler {
+	prefixes := make([]netip.Prefix, 0, len(trustedProxies))
+	for _, proxy := range trustedProxies {
+		prefix, err := ParseProxy(proxy)
+		if err != nil {
+			panic(err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	trusted := func(ip string) bool {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return false
+		}
+		for _, prefix := range prefixes {
+			if prefix.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if trusted(ip) {
+				ip = forwardedIP(r, trusted, ip)
+			}
+
+			ctx := context.WithValue(r.Context(), clientIPContextKey, ip)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Parse the IP address or prefix of a trusted proxy
+func ParseProxy(proxy string) (netip.Prefix, error) {
+	if strings.Contains(proxy, "/") {
+		prefix, err := netip.ParsePrefix(proxy)
+		return prefix.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(proxy)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Client in the headers set by a trusted proxy. In X-Forwarded-For, each
+// proxy appends the address it received the request from, so the client
+// is the last address that isn't a trusted proxy.
+func forwardedIP(r *http.Request, trusted func(ip string) bool, remote string) string {
+	for _, header := range []string{"True-Client-IP", "X-Real-IP"} {
+		if ip := strings.TrimSpace(r.Header.Get(header)); isIP(ip) {
+			return ip
+		}
+	}
+
+	var ips []string
+	for _, xff := range r.Header.Values("X-Forwarded-For") {
+		for ip := range strings.SplitSeq(xff, ",") {
+			ips = append(ips, strings.TrimSpace(ip))
+		}
+	}
+	for i := len(ips) - 1; i >= 0; i-- {
+		if !isIP(ips[i]) {
+			break
+		}
+		if !trusted(ips[i]) || i == 0 {
+			return ips[i]
+		}
+	}
+
+	return remote
+}
+
+func isIP(s string) bool {
+	_, err := netip.ParseAddr(s)
+	return err == nil
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// Returns the IP address of the client that made the request, as resolved
+// by RealIP, or else the remote address of the request.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok {
+		return ip
+	}
+
+	return remoteIP(r)
+}
+
+// Returns the key of the client that made the request for rate limits.
+// IPv6 clients are keyed by their /64 prefix, since a client usually has
+// the whole prefix.
+func ClientKey(r *http.Request) string {
+	ip := ClientIP(r)
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is6() || addr.Is4In6() {
+		return ip
+	}
+
+	return netip.PrefixFrom(addr, 64).Masked().Addr().String()
+}
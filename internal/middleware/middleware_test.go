@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	trustedProxies := []string{"10.0.0.0/8", "2001:db8:ffff::1"}
+
+	tests := []struct {
+		name    string
+		remote  string
+		headers map[string]string
+		ip      string
+		key     string
+	}{
+		{
+			name:   "remote address",
+			remote: "203.0.113.7:51234",
+			ip:     "203.0.113.7",
+			key:    "203.0.113.7",
+		},
+		{
+			name:    "spoofed headers",
+			remote:  "203.0.113.7:51234",
+			headers: map[string]string{"True-Client-IP": "198.51.100.1", "X-Real-IP": "198.51.100.2", "X-Forwarded-For": "198.51.100.3"},
+			ip:      "203.0.113.7",
+			key:     "203.0.113.7",
+		},
+		{
+			name:    "true client ip",
+			remote:  "10.0.0.1:51234",
+			headers: map[string]string{"True-Client-IP": "203.0.113.7", "X-Real-IP": "198.51.100.1"},
+			ip:      "203.0.113.7",
+			key:     "203.0.113.7",
+		},
+		{
+			name:    "real ip",
+			remote:  "10.0.0.1:51234",
+			headers: map[string]string{"X-Real-IP": "203.0.113.7", "X-Forwarded-For": "198.51.100.1"},
+			ip:      "203.0.113.7",
+			key:     "203.0.113.7",
+		},
+		{
+			name:    "invalid real ip",
+			remote:  "10.0.0.1:51234",
+			headers: map[string]string{"X-Real-IP": "unknown", "X-Forwarded-For": "203.0.113.7"},
+			ip:      "203.0.113.7",
+			key:     "203.0.113.7",
+		},
+		{
+			name:    "forwarded for",
+			remote:  "10.0.0.1:51234",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			ip:      "203.0.113.7",
+			key:     "203.0.113.7",
+		},
+		{
+			name:    "forwarded for spoofed by client",
+			remote:  "10.0.0.1:51234",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.0.0.2"},
+			ip:      "203.0.113.7",
+			key:     "203.0.113.7",
+		},
+		{
+			name:    "forwarded for by trusted proxies",
+			remote:  "10.0.0.1:51234",
+			headers: map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			ip:      "10.0.0.3",
+			key:     "10.0.0.3",
+		},
+		{
+			name:    "invalid forwarded for",
+			remote:  "10.0.0.1:51234",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7, unknown"},
+			ip:      "10.0.0.1",
+			key:     "10.0.0.1",
+		},
+		{
+			name:   "ipv6 prefix",
+			remote: "[2001:db8:1:2:3:4:5:6]:51234",
+			ip:     "2001:db8:1:2:3:4:5:6",
+			key:    "2001:db8:1:2::",
+		},
+		{
+			name:    "ipv6 proxy",
+			remote:  "[2001:db8:ffff::1]:51234",
+			headers: map[string]string{"X-Forwarded-For": "2001:db8:1:2:3:4:5:6"},
+			ip:      "2001:db8:1:2:3:4:5:6",
+			key:     "2001:db8:1:2::",
+		},
+		{
+			name:    "ipv4-mapped proxy",
+			remote:  "[::ffff:10.0.0.1]:51234",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			ip:      "203.0.113.7",
+			key:     "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			var ip, key string
+			RealIP(trustedProxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip, key = ClientIP(r), ClientKey(r)
+			})).ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, tt.ip, ip)
+			assert.Equal(t, tt.key, key)
+		})
+	}
+
+	t.Run("without RealIP", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.1:51234"
+		r.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+		assert.Equal(t, "10.0.0.1", ClientIP(r))
+	})
+}
+
+func TestParseProxy(t *testing.T) {
+	for proxy, want := range map[string]string{
+		"10.0.0.1":    "10.0.0.1/32",
+		"10.0.0.1/8":  "10.0.0.0/8",
+		"2001:db8::1": "2001:db8::1/128",
+	} {
+		prefix, err := ParseProxy(proxy)
+		assert.NoError(t, err)
+		assert.Equal(t, want, prefix.String())
+	}
+
+	for _, proxy := range []string{"", "proxy.example", "10.0.0.0/33", "10.0.0.1:80"} {
+		_, err := ParseProxy(proxy)
+		assert.Error(t, err, proxy)
+	}
+}
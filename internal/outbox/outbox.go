@@ -0,0 +1,176 @@
+// Package outbox delivers emails that were queued in the database. A
+// message is stored in the same transaction as the token it carries, so
+// it survives SMTP outages and restarts, and failed deliveries are
+// retried with exponential back-off until they are marked dead. Messages
+// to suppressed addresses are dropped.
+package outbox
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/mailer"
+)
+
+const (
+	DefaultInterval    = 5 * time.Second
+	DefaultMaxAttempts = 10
+	DefaultBatchSize   = 10
+
+	// How long a worker has to deliver a claimed message before other
+	// workers may claim it again
+	leaseDuration = 5 * time.Minute
+
+	minBackoff = 30 * time.Second
+	maxBackoff = 6 * time.Hour
+)
+
+// Published at /debug/vars
+var metrics = expvar.NewMap("outbox")
+
+// Queue the message for delivery. Pass the DB of a transaction to
+// deliver it only if the transaction commits. Messages to suppressed
+// addresses are dropped.
+func Enqueue(ctx context.Context, db *data.DB, msg *mailer.Message) error {
+	suppressed, err := db.Suppressions.IsSuppressed(ctx, msg.To)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		metrics.Add("suppressed", 1)
+		return nil
+	}
+
+	err = db.Outbox.New(ctx, &data.OutboxMessage{
+		Sender:    msg.From,
+		Recipient: msg.To,
+		Subject:   msg.Subject,
+		Body:      msg.HTML,
+	})
+	if err != nil {
+		return err
+	}
+
+	metrics.Add("queued", 1)
+
+	return nil
+}
+
+type Worker struct {
+	db          *data.DB
+	sender      mailer.Sender
+	logger      *slog.Logger
+	interval    time.Duration
+	maxAttempts int
+	batchSize   int
+}
+
+func New(db *data.DB, sender mailer.Sender, logger *slog.Logger) *Worker {
+	return &Worker{
+		db:          db,
+		sender:      sender,
+		logger:      logger,
+		interval:    DefaultInterval,
+		maxAttempts: DefaultMaxAttempts,
+		batchSize:   DefaultBatchSize,
+	}
+}
+
+// Deliver due messages on every interval until the context is canceled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		err := w.Deliver(ctx)
+		if err != nil && ctx.Err() == nil {
+			metrics.Add("errors", 1)
+			w.logger.Error("outbox: delivery failed", slog.Any("err", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Attempt every message that is due, one batch at a time
+func (w *Worker) Deliver(ctx context.Context) error {
+	for {
+		msgs, err := w.db.Outbox.Claim(ctx, w.batchSize, time.Now().Add(leaseDuration))
+		if err != nil {
+			return err
+		}
+
+		for _, msg := range msgs {
+			err := w.deliver(ctx, msg)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(msgs) < w.batchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// Send the message and record the result. Only errors of the database are
+// returned; failed sends are scheduled for another attempt.
+func (w *Worker) deliver(ctx context.Context, msg *data.OutboxMessage) error {
+	// The address may have bounced since the message was queued
+	suppressed, err := w.db.Suppressions.IsSuppressed(ctx, msg.Recipient)
+	if err != nil {
+		return err
+	}
+	if suppressed {
+		metrics.Add("suppressed", 1)
+		w.logger.Info("outbox: dropped message to suppressed address", slog.String("id", msg.ID.String()))
+		return w.db.Outbox.Delete(ctx, msg.ID)
+	}
+
+	sendErr := w.sender.SendMessage(&mailer.Message{
+		From:    msg.Sender,
+		To:      msg.Recipient,
+		Subject: msg.Subject,
+		HTML:    msg.Body,
+	})
+	if sendErr == nil {
+		metrics.Add("sent", 1)
+		return w.db.Outbox.Delete(ctx, msg.ID)
+	}
+
+	attempts := msg.Attempts + 1
+	dead := attempts >= w.maxAttempts
+
+	log := w.logger.With(
+		slog.String("id", msg.ID.String()),
+		slog.Int("attempts", attempts),
+		slog.Any("err", sendErr),
+	)
+	if dead {
+		metrics.Add("dead", 1)
+		log.Error("outbox: giving up on message")
+	} else {
+		metrics.Add("failed", 1)
+		log.Warn("outbox: failed to send message")
+	}
+
+	return w.db.Outbox.Fail(ctx, msg.ID, sendErr.Error(), time.Now().Add(backoff(attempts)), dead)
+}
+
+// Delay after the given number of failed attempts, which doubles with
+// every attempt
+func backoff(attempts int) time.Duration {
+	d := minBackoff
+	for i := 1; i < attempts && d < maxBackoff; i++ {
+		d *= 2
+	}
+
+	return min(d, maxBackoff)
+}
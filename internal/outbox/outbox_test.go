@@ -0,0 +1,144 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/data/memory"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Fails until up is set
+type testSender struct {
+	up   bool
+	sent []*mailer.Message
+}
+
+func (s *testSender) SendMessage(msg *mailer.Message) error {
+	if !s.up {
+		return errors.New("connection refused")
+	}
+
+	s.sent = append(s.sent, msg)
+
+	return nil
+}
+
+func TestDeliver(t *testing.T) {
+	ctx := context.Background()
+	db := memory.NewMemoryDB().DB
+
+	// Messages of rolled back transactions aren't queued
+	err := db.WithTx(ctx, func(tx *data.DB) error {
+		err := Enqueue(ctx, tx, &mailer.Message{To: "rollback@email.com"})
+		require.NoError(t, err)
+
+		return errors.New("rollback")
+	})
+	require.Error(t, err)
+
+	testMessage := &mailer.Message{
+		From:    "no-reply@email.com",
+		To:      "test@email.com",
+		Subject: "Registration",
+		HTML:    "<p>Hello</p>",
+	}
+	err = Enqueue(ctx, db, testMessage)
+	require.NoError(t, err)
+
+	sender := &testSender{}
+	w := New(db, sender, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.maxAttempts = 1
+
+	// The only attempt fails
+	err = w.Deliver(ctx)
+	require.NoError(t, err)
+
+	dead, err := db.Outbox.GetDead(ctx)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "connection refused", dead[0].LastError)
+
+	// Retried once the server is back
+	err = db.Outbox.Retry(ctx, dead[0].ID)
+	require.NoError(t, err)
+	sender.up = true
+
+	err = w.Deliver(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, testMessage, sender.sent[0])
+
+	// Delivered messages are deleted
+	err = db.Outbox.Delete(ctx, dead[0].ID)
+	assert.ErrorIs(t, err, data.ErrRecordNotFound)
+}
+
+func TestDeliverBackoff(t *testing.T) {
+	ctx := context.Background()
+	db := memory.NewMemoryDB().DB
+
+	err := Enqueue(ctx, db, &mailer.Message{To: "test@email.com"})
+	require.NoError(t, err)
+
+	w := New(db, &testSender{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	err = w.Deliver(ctx)
+	require.NoError(t, err)
+
+	// Not dead, but not due until after the back-off
+	dead, err := db.Outbox.GetDead(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+
+	msgs, err := db.Outbox.Claim(ctx, 10, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
+
+func TestDeliverSuppressed(t *testing.T) {
+	ctx := context.Background()
+	db := memory.NewMemoryDB().DB
+
+	err := db.Suppressions.Record(ctx, "hard@email.com", data.BounceHard, "550 5.1.1 user unknown")
+	require.NoError(t, err)
+
+	// Not queued
+	err = Enqueue(ctx, db, &mailer.Message{To: "hard@email.com"})
+	require.NoError(t, err)
+
+	// Queued before the address bounced
+	err = Enqueue(ctx, db, &mailer.Message{To: "soft@email.com"})
+	require.NoError(t, err)
+	for range data.SoftBounceLimit {
+		err = db.Suppressions.Record(ctx, "soft@email.com", data.BounceSoft, "452 4.2.2 mailbox full")
+		require.NoError(t, err)
+	}
+
+	sender := &testSender{up: true}
+	w := New(db, sender, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	err = w.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, sender.sent)
+
+	// Dropped rather than retried
+	msgs, err := db.Outbox.Claim(ctx, 10, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 30*time.Second, backoff(1))
+	assert.Equal(t, time.Minute, backoff(2))
+	assert.Equal(t, 4*time.Minute, backoff(4))
+	assert.Equal(t, 6*time.Hour, backoff(20))
+}
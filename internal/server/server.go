@@ -0,0 +1,140 @@
+// Package server runs the HTTP servers and background workers of a mono
+// process, and sets up the services that they share.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+// Servers and background workers that start and stop together
+type Runtime struct {
+	logger  *slog.Logger
+	servers []*namedServer
+	workers []func(ctx context.Context)
+}
+
+type namedServer struct {
+	name string
+	*http.Server
+}
+
+func NewRuntime(logger *slog.Logger) *Runtime {
+	return &Runtime{logger: logger}
+}
+
+// Serve the handler on the address, e.g. ":4000"
+func (rt *Runtime) Serve(name, addr string, h http.Handler) {
+	rt.servers = append(rt.servers, &namedServer{
+		name: name,
+		Server: &http.Server{
+			Addr:         addr,
+			Handler:      h,
+			ErrorLog:     slog.NewLogLogger(rt.logger.Handler(), slog.LevelError),
+			IdleTimeout:  time.Minute,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		},
+	})
+}
+
+// Run the worker in the background until shutdown
+func (rt *Runtime) Go(worker func(ctx context.Context)) {
+	rt.workers = append(rt.workers, worker)
+}
+
+// Start the servers and workers, then shut them down gracefully on SIGINT
+// or SIGTERM, when the context is canceled or when a server fails
+func (rt *Runtime) Run(ctx context.Context) error {
+	listeners := make([]net.Listener, 0, len(rt.servers))
+	for _, srv := range rt.servers {
+		ln, err := net.Listen("tcp", srv.Addr)
+		if err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return fmt.Errorf("%s: %w", srv.name, err)
+		}
+		listeners = append(listeners, ln)
+	}
+
+	// Stops background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var wg sync.WaitGroup
+	for _, worker := range rt.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			defer func() {
+				if err := recover(); err != nil {
+					rt.logger.Error("background process recovered from panic", slog.Any("err", err))
+				}
+			}()
+
+			worker(workerCtx)
+		}()
+	}
+
+	serveErr := make(chan error, len(rt.servers))
+	for i, srv := range rt.servers {
+		rt.logger.Info("starting server", slog.String("server", srv.name), slog.String("addr", listeners[i].Addr().String()))
+
+		go func() {
+			err := srv.Serve(listeners[i])
+			// http.ErrServerClosed is expected from srv.Shutdown()
+			if !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- fmt.Errorf("%s: %w", srv.name, err)
+			}
+		}()
+	}
+
+	// Intercept signals
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	var err error
+	select {
+	case s := <-quit:
+		rt.logger.Info("shutting down", slog.String("signal", s.String()))
+	case <-ctx.Done():
+		rt.logger.Info("shutting down", slog.Any("err", context.Cause(ctx)))
+	case err = <-serveErr:
+		rt.logger.Error("shutting down", slog.Any("err", err))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	errs := []error{err}
+	for _, srv := range rt.servers {
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", srv.name, err))
+		}
+	}
+
+	stopWorkers()
+	// Block until the workers return
+	wg.Wait()
+
+	for _, srv := range rt.servers {
+		rt.logger.Info("stopped server", slog.String("server", srv.name))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRuntime() *Runtime {
+	return NewRuntime(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRun(t *testing.T) {
+	rt := newTestRuntime()
+	rt.Serve("test", "localhost:0", http.NotFoundHandler())
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	rt.Go(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+	})
+	rt.Go(func(ctx context.Context) {
+		panic("worker")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- rt.Run(ctx)
+	}()
+
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(shutdownTimeout):
+		t.Fatal("Run did not return")
+	}
+
+	select {
+	case <-stopped:
+	default:
+		t.Error("worker was not stopped")
+	}
+}
+
+func TestRunAddressInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	stopped := false
+	rt := newTestRuntime()
+	rt.Serve("test", ln.Addr().String(), http.NotFoundHandler())
+	rt.Go(func(ctx context.Context) {
+		stopped = true
+	})
+
+	err = rt.Run(context.Background())
+	assert.ErrorContains(t, err, "test:")
+	assert.False(t, stopped, "worker started before the servers listened")
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/pgxstore"
+	"github.com/alexedwards/scs/sqlite3store"
+	"github.com/alexedwards/scs/v2"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lmittmann/tint"
+	"github.com/micahco/mono/internal/authn"
+	"github.com/micahco/mono/internal/config"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/data/postgres"
+	"github.com/micahco/mono/internal/data/sqlite"
+	"github.com/micahco/mono/internal/geoip"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/micahco/mono/migrations"
+)
+
+// Services that the servers of a process share. Don't forget to Close()
+type Services struct {
+	Logger *slog.Logger
+	DB     *Database
+	// Delivers the mail of every server
+	Transport mailer.Sender
+	DKIM      *mailer.DKIM
+	// Captures mail in development mode, with the sink transport
+	Sink    *mailer.Sink
+	Locator *geoip.Locator
+	Authn   authn.Authenticator
+
+	closers []func()
+}
+
+func NewServices(cfg *config.Config, logger *slog.Logger) (*Services, error) {
+	s := &Services{Logger: logger}
+
+	err := s.open(cfg)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Services) open(cfg *config.Config) error {
+	var err error
+
+	s.DB, err = OpenDB(cfg.DB.DSN)
+	if err != nil {
+		return err
+	}
+	s.closers = append(s.closers, s.DB.Close)
+
+	s.Locator, err = geoip.Open(cfg.GeoIP)
+	if err != nil {
+		return err
+	}
+	s.closers = append(s.closers, func() { s.Locator.Close() })
+
+	if cfg.Dev && cfg.Mail.Transport == "sink" {
+		s.Sink, err = mailer.ListenSink("localhost:0")
+		if err != nil {
+			return err
+		}
+		s.closers = append(s.closers, func() { s.Sink.Close() })
+	}
+
+	s.Transport, err = NewMailTransport(cfg.Mail, s.Sink)
+	if err != nil {
+		return err
+	}
+
+	s.DKIM, err = LoadDKIM(cfg.Mail)
+	if err != nil {
+		return err
+	}
+
+	s.Authn = NewAuthenticator(cfg.LDAP, s.DB.DB, s.Logger)
+
+	return nil
+}
+
+// Mailer of the emails from the sender address
+func (s *Services) Mailer(sender string) *mailer.Mailer {
+	return mailer.New(s.Transport, &mail.Address{Name: "Do Not Reply", Address: sender}, s.DKIM)
+}
+
+// Close the services in the reverse order that they were opened
+func (s *Services) Close() {
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		s.closers[i]()
+	}
+	s.closers = nil
+}
+
+// Database of the DSN, with a session store backed by it
+type Database struct {
+	*data.DB
+	Sessions scs.Store
+	sql      *sql.DB
+	// Migrator of the schema of the backend
+	newMigrator func(db *sql.DB) (*migrations.Migrator, error)
+	close       func()
+}
+
+// Open the database selected by the DSN scheme. Don't forget to Close()
+func OpenDB(dsn string) (*Database, error) {
+	if path, ok := strings.CutPrefix(dsn, "sqlite:"); ok {
+		db, err := sqlite.NewSQLiteDB(strings.TrimPrefix(path, "//"))
+		if err != nil {
+			return nil, err
+		}
+
+		return &Database{
+			DB:          db.DB,
+			Sessions:    sqlite3store.New(db.SQL),
+			sql:         db.SQL,
+			newMigrator: migrations.NewSQLiteMigrator,
+			close:       db.Close,
+		}, nil
+	}
+
+	pg, err := postgres.NewPostgresDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Database{
+		DB:          pg.DB,
+		Sessions:    pgxstore.New(pg.Pool),
+		sql:         stdlib.OpenDBFromPool(pg.Pool),
+		newMigrator: migrations.NewMigrator,
+		close:       pg.Close,
+	}, nil
+}
+
+// Migrator of the schema of the database
+func (db *Database) Migrator() (*migrations.Migrator, error) {
+	return db.newMigrator(db.sql)
+}
+
+func (db *Database) Close() {
+	db.close()
+}
+
+// Select the mail transport. SMTP credentials are verified up front. The
+// sink is only started in development mode.
+func NewMailTransport(cfg config.Mail, sink *mailer.Sink) (mailer.Sender, error) {
+	switch cfg.Transport {
+	case "", "smtp":
+		s := mailer.NewSMTP(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password)
+		err := s.Ping()
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	case "file":
+		if cfg.Dir == "" {
+			return nil, errors.New("mail-dir is required by the file transport")
+		}
+		return mailer.NewFile(cfg.Dir)
+	case "memory":
+		return mailer.NewMemory(), nil
+	case "sink":
+		if sink == nil {
+			return nil, errors.New("the sink transport requires -dev")
+		}
+		return sink.SMTP(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %s", cfg.Transport)
+	}
+}
+
+// DKIM key that signs the emails, or nil if there is none
+func LoadDKIM(cfg config.Mail) (*mailer.DKIM, error) {
+	if cfg.DKIM.KeyFile == "" {
+		return nil, nil
+	}
+
+	return mailer.LoadDKIM(cfg.DKIM.Domain, cfg.DKIM.Selector, cfg.DKIM.KeyFile)
+}
+
+// Authenticate with the directory, when configured, before the local
+// password of users that aren't in the directory.
+func NewAuthenticator(cfg authn.LDAPConfig, db *data.DB, logger *slog.Logger) authn.Authenticator {
+	local := &authn.Local{Users: db.Users}
+	if cfg.URL == "" {
+		return local
+	}
+
+	return &authn.Chain{
+		Authenticators: []authn.Authenticator{authn.NewLDAP(cfg, db), local},
+		Logger:         logger,
+	}
+}
+
+// Logger of text for development, and of JSON otherwise
+func NewLogger(dev bool) *slog.Logger {
+	if dev {
+		// Development text handler
+		return slog.New(tint.NewHandler(os.Stdout, &tint.Options{
+			AddSource:  true,
+			Level:      slog.LevelDebug,
+			TimeFormat: time.Kitchen,
+		}))
+	}
+
+	// Production use JSON handler with default opts
+	return slog.New(slog.NewJSONHandler(os.Stdout, nil))
+}
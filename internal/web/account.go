@@ -0,0 +1,354 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/justinas/nosurf"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/internal/middleware"
+	"github.com/micahco/mono/ui/emails"
+	"github.com/micahco/mono/ui/pages"
+)
+
+func (app *application) handleAccountEmailGet(w http.ResponseWriter, r *http.Request) error {
+	component := pages.ChangeEmail(nosurf.Token(r), app.popFormErrors(r))
+
+	return app.render(w, r, http.StatusOK, "Change Email", component)
+}
+
+func (app *application) handleAccountEmailPost(w http.ResponseWriter, r *http.Request) error {
+	var form struct {
+		Email string `form:"email" validate:"required,email,max=254"`
+	}
+
+	err := app.parseForm(r, &form)
+	if err != nil {
+		return err
+	}
+
+	// Check if user with email already exists
+	exists, err := app.db.Users.ExistsWithEmail(r.Context(), form.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// respond with consistent message email sent
+		app.refresh(w, r)
+
+		return nil
+	}
+
+	// Check if a verification token has already been created recently
+	exists, err = app.db.VerificationTokens.Exists(r.Context(), data.ScopeEmailChange, form.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// respond with consistent message email sent
+		app.refresh(w, r)
+
+		return nil
+	}
+
+	// Create verification token for user with new email address
+	token, err := crypto.NewToken(data.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	// Create link to confirm the new email with token and email
+	// as query parameters.
+	ref, err := url.Parse("/account/email/update")
+	if err != nil {
+		return err
+	}
+	q := ref.Query()
+	q.Set("email", form.Email)
+	q.Set("token", token.Plaintext)
+	ref.RawQuery = q.Encode()
+	href := app.baseURL.ResolveReference(ref)
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		// Limit the verification emails that anyone can have sent. Only
+		// the emails that are queued count towards the limits.
+		err := tx.Throttle.Acquire(r.Context(), form.Email, middleware.ClientKey(r), app.config.Throttle)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.New(r.Context(), token.Hash, token.Expiry, data.ScopeEmailChange, form.Email)
+		if err != nil {
+			return err
+		}
+
+		component := emails.EmailChange(href.String())
+		return app.sendMail(r.Context(), tx, i18n.Locale(r.Context()), form.Email, "Email Verification", component)
+	})
+	if errors.Is(err, data.ErrThrottled) {
+		return app.renderError(w, "too many verification emails. please try again later", http.StatusTooManyRequests)
+	}
+	// Mail isn't sent to addresses that bounced, but the response is the same
+	if err != nil && !errors.Is(err, data.ErrSuppressedAddress) {
+		return err
+	}
+
+	// respond with consistent message email sent
+	app.refresh(w, r)
+
+	return nil
+}
+
+// Change the language of the pages and emails of the user
+func (app *application) handleAccountLocalePost(w http.ResponseWriter, r *http.Request) error {
+	var form struct {
+		Locale string `form:"locale" validate:"required"`
+	}
+
+	err := app.parseForm(r, &form)
+	if err != nil {
+		return err
+	}
+
+	if !i18n.IsSupported(form.Locale) {
+		return app.renderError(w, "unsupported language", http.StatusBadRequest)
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.db.Users.Get(r.Context(), suid)
+	if err != nil {
+		return err
+	}
+
+	user.Locale = form.Locale
+
+	err = app.db.Users.Update(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, data.ErrEditConflict) {
+			return app.renderError(w, http.StatusText(http.StatusConflict), http.StatusConflict)
+		}
+
+		return err
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+
+	return nil
+}
+
+func (app *application) handleAccountEmailUpdateGet(w http.ResponseWriter, r *http.Request) error {
+	plaintextToken := r.URL.Query().Get("token")
+	if plaintextToken == "" {
+		return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+
+	component := pages.UpdateEmail(nosurf.Token(r), app.popFormErrors(r), plaintextToken, email)
+
+	return app.render(w, r, http.StatusOK, "Update Email", component)
+}
+
+func (app *application) handleAccountEmailUpdatePost(w http.ResponseWriter, r *http.Request) error {
+	var form struct {
+		PlaintextToken string `form:"token" validate:"required"`
+		Email          string `form:"email" validate:"required,email,max=254"`
+	}
+
+	err := app.parseForm(r, &form)
+	if err != nil {
+		return err
+	}
+
+	tokenHash := crypto.TokenHash(form.PlaintextToken)
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		return err
+	}
+
+	// Let the previous address undo a change it didn't make
+	token, err := crypto.NewToken(data.EmailRevertTokenTTL)
+	if err != nil {
+		return err
+	}
+
+	ref, err := url.Parse("/auth/email/revert")
+	if err != nil {
+		return err
+	}
+	q := ref.Query()
+	q.Set("token", token.Plaintext)
+	ref.RawQuery = q.Encode()
+	href := app.baseURL.ResolveReference(ref)
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.VerificationTokens.Consume(r.Context(), tokenHash, data.ScopeEmailChange, form.Email)
+		if err != nil {
+			return err
+		}
+
+		user, err := tx.Users.Get(r.Context(), suid)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.Purge(r.Context(), form.Email)
+		if err != nil {
+			return err
+		}
+
+		previousEmail := user.Email
+		user.Email = form.Email
+
+		err = tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.NewForUser(r.Context(), token.Hash, token.Expiry, data.ScopeEmailRevert, previousEmail, user.ID)
+		if errors.Is(err, data.ErrSuppressedAddress) {
+			// The previous address bounced, so there's no one to warn
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		component := emails.EmailRevert(form.Email, href.String())
+		return app.sendMail(r.Context(), tx, user.Locale, previousEmail, "Email Changed", component)
+	})
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+		if errors.Is(err, data.ErrExpiredToken) {
+			// TODO: flash expired token. please try again
+			http.Redirect(w, r, "/account/email", http.StatusSeeOther)
+
+			return nil
+		}
+		if errors.Is(err, data.ErrDuplicateEmail) {
+			return app.renderError(w, "email address is already in use", http.StatusConflict)
+		}
+
+		return err
+	}
+
+	// TODO: flash email updated success
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+
+	return nil
+}
+
+func (app *application) handleAuthEmailRevertGet(w http.ResponseWriter, r *http.Request) error {
+	plaintextToken := r.URL.Query().Get("token")
+	if plaintextToken == "" {
+		return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+
+	component := pages.RevertEmail(nosurf.Token(r), plaintextToken)
+
+	return app.render(w, r, http.StatusOK, "Restore Email", component)
+}
+
+// Restore the email address that was replaced by the last email
+// change and lock the account until the password is reset.
+func (app *application) handleAuthEmailRevertPost(w http.ResponseWriter, r *http.Request) error {
+	var form struct {
+		PlaintextToken string `form:"token" validate:"required"`
+	}
+
+	err := app.parseForm(r, &form)
+	if err != nil {
+		return err
+	}
+
+	tokenHash := crypto.TokenHash(form.PlaintextToken)
+
+	vt, err := app.db.VerificationTokens.Get(r.Context(), tokenHash)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+
+		return err
+	}
+	if vt.Scope != data.ScopeEmailRevert || !vt.UserID.Valid {
+		return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	}
+	if time.Now().After(vt.Expiry) {
+		return app.renderError(w, "expired token", http.StatusUnauthorized)
+	}
+
+	user, err := app.db.Users.Get(r.Context(), vt.UserID.UUID)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+
+		return err
+	}
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.VerificationTokens.Consume(r.Context(), tokenHash, data.ScopeEmailRevert, vt.Email)
+		if err != nil {
+			return err
+		}
+
+		// Tokens mailed to the replacement address can't be trusted
+		err = tx.VerificationTokens.Purge(r.Context(), user.Email)
+		if err != nil {
+			return err
+		}
+
+		user.Email = vt.Email
+		user.Locked = true
+
+		err = tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+
+		// Other tokens of the restored address were sent before the change
+		err = tx.VerificationTokens.Purge(r.Context(), user.Email)
+		if err != nil {
+			return err
+		}
+
+		return tx.AuthenticationTokens.Purge(r.Context(), user.ID)
+	})
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+		if errors.Is(err, data.ErrDuplicateEmail) {
+			return app.renderError(w, "email address is already in use", http.StatusConflict)
+		}
+
+		return err
+	}
+
+	// Locked users are rejected by the authenticate middleware, which
+	// signs out every other session.
+	err = app.logout(r)
+	if err != nil {
+		return err
+	}
+
+	// TODO: flash account locked. reset password to unlock
+	http.Redirect(w, r, "/auth/reset", http.StatusSeeOther)
+
+	return nil
+}
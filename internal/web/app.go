@@ -0,0 +1,98 @@
+// Package web is the server of the mono web pages.
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/gob"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/a-h/templ"
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/authn"
+	"github.com/micahco/mono/internal/config"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/geoip"
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/micahco/mono/internal/outbox"
+	"github.com/micahco/mono/internal/server"
+)
+
+type application struct {
+	config         config.Config
+	db             data.DB
+	logger         *slog.Logger
+	mailer         *mailer.Mailer
+	sink           *mailer.Sink
+	locator        *geoip.Locator
+	authn          authn.Authenticator
+	sessionManager *scs.SessionManager
+	formDecoder    *form.Decoder
+	validate       *validator.Validate
+	baseURL        *url.URL
+	samlKeyPair    *tls.Certificate
+}
+
+// Serve the web pages with the runtime
+func Register(rt *server.Runtime, cfg *config.Config, svc *server.Services) error {
+	// Session manager
+	sm := scs.New()
+	sm.Store = svc.DB.Sessions
+	sm.Lifetime = 12 * time.Hour
+	gob.Register(uuid.UUID{})
+	gob.Register(time.Time{})
+	gob.Register(FormErrors{})
+
+	// SAML service provider key pair
+	var samlKeyPair *tls.Certificate
+	if cfg.Web.SAML.CertFile != "" {
+		keyPair, err := tls.LoadX509KeyPair(cfg.Web.SAML.CertFile, cfg.Web.SAML.KeyFile)
+		if err != nil {
+			return err
+		}
+		samlKeyPair = &keyPair
+	}
+
+	// Base URL
+	baseURL, err := url.Parse(cfg.Web.URL)
+	if err != nil {
+		return err
+	}
+
+	app := &application{
+		config:         *cfg,
+		db:             *svc.DB.DB,
+		logger:         svc.Logger,
+		mailer:         svc.Mailer(cfg.Web.Sender),
+		sink:           svc.Sink,
+		locator:        svc.Locator,
+		authn:          svc.Authn,
+		sessionManager: sm,
+		formDecoder:    form.NewDecoder(),
+		validate:       validator.New(),
+		baseURL:        baseURL,
+		samlKeyPair:    samlKeyPair,
+	}
+
+	rt.Serve("web", fmt.Sprintf(":%d", cfg.Web.Port), app.routes())
+
+	return nil
+}
+
+// Queue an email in the outbox, translated into the locale. Pass the DB
+// of a transaction to send it only if the transaction commits.
+func (app *application) sendMail(ctx context.Context, db *data.DB, locale, recipient, subject string, component templ.Component) error {
+	msg, err := app.mailer.Render(i18n.WithLocale(ctx, locale), recipient, subject, component)
+	if err != nil {
+		return err
+	}
+
+	return outbox.Enqueue(ctx, db, msg)
+}
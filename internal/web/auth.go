@@ -1,15 +1,20 @@
-package main
+package web
 
 import (
 	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gofrs/uuid/v5"
 	"github.com/justinas/nosurf"
+	"github.com/micahco/mono/internal/authn"
 	"github.com/micahco/mono/internal/crypto"
 	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/fielderr"
+	"github.com/micahco/mono/internal/i18n"
+	"github.com/micahco/mono/internal/middleware"
 	"github.com/micahco/mono/ui/emails"
 	"github.com/micahco/mono/ui/pages"
 )
@@ -18,6 +23,8 @@ type contextKey string
 
 const (
 	authenticatedUserIDSessionKey = "authenticatedUserID"
+	authenticatedAtSessionKey     = "authenticatedAt"
+	returnToSessionKey            = "returnTo"
 	verificationEmailSessionKey   = "verificationEmail"
 	verificationTokenSessionKey   = "verificationToken"
 	resetEmailSessionKey          = "resetEmail"
@@ -32,6 +39,7 @@ func (app *application) login(r *http.Request, userID uuid.UUID) error {
 	}
 
 	app.sessionManager.Put(r.Context(), authenticatedUserIDSessionKey, userID)
+	app.sessionManager.Put(r.Context(), authenticatedAtSessionKey, time.Now())
 
 	return nil
 }
@@ -43,10 +51,19 @@ func (app *application) logout(r *http.Request) error {
 	}
 
 	app.sessionManager.Remove(r.Context(), authenticatedUserIDSessionKey)
+	app.sessionManager.Remove(r.Context(), authenticatedAtSessionKey)
 
 	return nil
 }
 
+// Reports whether the user entered their password recently enough to
+// perform a sensitive operation.
+func (app *application) isRecentlyAuthenticated(r *http.Request) bool {
+	authenticatedAt := app.sessionManager.GetTime(r.Context(), authenticatedAtSessionKey)
+
+	return time.Since(authenticatedAt) < data.FreshAuthenticationTTL
+}
+
 // Check the auth context set by the authenticate middleware
 func (app *application) isAuthenticated(r *http.Request) bool {
 	isAuthenticated, ok := r.Context().Value(isAuthenticatedContextKey).(bool)
@@ -80,24 +97,21 @@ func (app *application) handleAuthLoginPost(w http.ResponseWriter, r *http.Reque
 		return err
 	}
 
-	user, err := app.db.Users.GetWithEmail(r.Context(), form.Email)
+	user, err := app.authn.Authenticate(r.Context(), form.Email, form.Password)
 	if err != nil {
 		switch {
-		case errors.Is(err, data.ErrRecordNotFound):
-			// User with email does not exist
+		case errors.Is(err, authn.ErrInvalidCredentials):
 			return app.renderError(w, "invalid credentials", http.StatusUnauthorized)
 		default:
 			return err
 		}
 	}
 
-	match, err := crypto.ComparePasswordAndHash(form.Password, user.PasswordHash)
-	if err != nil {
-		return err
+	if !user.Active {
+		return app.renderError(w, "account disabled", http.StatusForbidden)
 	}
-	if !match {
-		// Incorrect password
-		return app.renderError(w, "invalid credentials", http.StatusUnauthorized)
+	if user.Locked {
+		return app.renderError(w, "account locked. reset your password to unlock it", http.StatusForbidden)
 	}
 
 	err = app.login(r, user.ID)
@@ -105,12 +119,105 @@ func (app *application) handleAuthLoginPost(w http.ResponseWriter, r *http.Reque
 		return err
 	}
 
+	err = app.rememberDevice(r, user)
+	if err != nil {
+		return err
+	}
+
 	// Redirect to homepage after authenticating the user.
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 
 	return nil
 }
 
+// Warn the user about logins from devices they haven't used before
+func (app *application) rememberDevice(r *http.Request, user *data.User) error {
+	ip := middleware.ClientIP(r)
+
+	return app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		known, err := tx.Devices.Remember(r.Context(), user.ID, ip, r.UserAgent())
+		if err != nil || known {
+			return err
+		}
+
+		return app.notifyNewDevice(r, tx, user, ip)
+	})
+}
+
+// Mail the user about a login from a new device with a link that revokes
+// the session of the request.
+func (app *application) notifyNewDevice(r *http.Request, db *data.DB, user *data.User, ip string) error {
+	session := app.sessionManager.Token(r.Context())
+	sessionHash := crypto.TokenHash(session)
+
+	err := db.Devices.NewSession(r.Context(), sessionHash, session, app.sessionManager.Deadline(r.Context()), user.ID)
+	if err != nil {
+		return err
+	}
+
+	ref, err := url.Parse("/auth/sessions/revoke")
+	if err != nil {
+		return err
+	}
+	q := ref.Query()
+	q.Set("session", crypto.EncodeTokenHash(sessionHash))
+	ref.RawQuery = q.Encode()
+	href := app.baseURL.ResolveReference(ref)
+
+	when := time.Now().UTC().Format(time.RFC1123)
+	where := fmt.Sprintf("%s (%s)", app.locator.Locate(ip), ip)
+	device := r.UserAgent()
+
+	component := emails.NewDevice(when, where, device, href.String())
+	return app.sendMail(r.Context(), db, user.Locale, user.Email, "New Login", component)
+}
+
+func (app *application) handleAuthSessionsRevokeGet(w http.ResponseWriter, r *http.Request) error {
+	session := r.URL.Query().Get("session")
+	if session == "" {
+		return app.renderError(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+
+	component := pages.RevokeSession(nosurf.Token(r), session)
+
+	return app.render(w, r, http.StatusOK, "Sign Out Session", component)
+}
+
+// Delete the session of a login from a new device, identified by the hash
+// of its token in the email about it
+func (app *application) handleAuthSessionsRevokePost(w http.ResponseWriter, r *http.Request) error {
+	var form struct {
+		Session string `form:"session" validate:"required"`
+	}
+
+	err := app.parseForm(r, &form)
+	if err != nil {
+		return err
+	}
+
+	sessionHash, err := crypto.DecodeTokenHash(form.Session)
+	if err != nil {
+		return app.renderError(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+
+	session, err := app.db.Devices.PopSession(r.Context(), sessionHash)
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		// Already signed out or expired
+	case err != nil:
+		return err
+	default:
+		err = app.sessionManager.Store.Delete(session)
+		if err != nil {
+			return err
+		}
+	}
+
+	http.Redirect(w, r, "/auth/reset", http.StatusSeeOther)
+
+	return nil
+}
+
 func (app *application) handleAuthLogoutPost(w http.ResponseWriter, r *http.Request) error {
 	err := app.logout(r)
 	if err != nil {
@@ -122,6 +229,77 @@ func (app *application) handleAuthLogoutPost(w http.ResponseWriter, r *http.Requ
 	return nil
 }
 
+func (app *application) handleAuthConfirmGet(w http.ResponseWriter, r *http.Request) error {
+	// Users of an identity provider confirm with it rather than with a
+	// password they may never have set
+	if app.samlEnabled() {
+		suid, err := app.getSessionUserID(r)
+		if err != nil {
+			return err
+		}
+
+		p, err := app.db.SAML.GetProviderWithUser(r.Context(), suid)
+		switch {
+		case err == nil:
+			http.Redirect(w, r, "/saml/"+p.Slug+"/login", http.StatusSeeOther)
+			return nil
+		case !errors.Is(err, data.ErrRecordNotFound):
+			return err
+		}
+	}
+
+	component := pages.ConfirmPassword(nosurf.Token(r), app.popFormErrors(r))
+
+	return app.render(w, r, http.StatusOK, "Confirm Password", component)
+}
+
+// Re-prompt for the password before a sensitive operation. Redirects back
+// to the page that required it.
+func (app *application) handleAuthConfirmPost(w http.ResponseWriter, r *http.Request) error {
+	var form struct {
+		Password string `form:"password" validate:"required"`
+	}
+
+	err := app.parseForm(r, &form)
+	if err != nil {
+		return err
+	}
+
+	suid, err := app.getSessionUserID(r)
+	if err != nil {
+		return err
+	}
+
+	user, err := app.db.Users.Get(r.Context(), suid)
+	if err != nil {
+		return err
+	}
+
+	confirmed, err := app.authn.Authenticate(r.Context(), user.Email, form.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, authn.ErrInvalidCredentials):
+			return FormErrors{"password": formError(fielderr.CodeInvalid, "incorrect password", nil)}
+		default:
+			return err
+		}
+	}
+	if confirmed.ID != user.ID {
+		return FormErrors{"password": formError(fielderr.CodeInvalid, "incorrect password", nil)}
+	}
+
+	app.sessionManager.Put(r.Context(), authenticatedAtSessionKey, time.Now())
+
+	returnTo := app.sessionManager.PopString(r.Context(), returnToSessionKey)
+	if returnTo == "" {
+		returnTo = "/"
+	}
+
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+
+	return nil
+}
+
 func (app *application) handleAuthSignupPost(w http.ResponseWriter, r *http.Request) error {
 	if app.isAuthenticated(r) {
 		return app.renderError(w, "already authenticated", http.StatusBadRequest)
@@ -166,11 +344,6 @@ func (app *application) handleAuthSignupPost(w http.ResponseWriter, r *http.Requ
 		return err
 	}
 
-	err = app.db.VerificationTokens.New(r.Context(), token.Hash, token.Expiry, data.ScopeRegistration, form.Email)
-	if err != nil {
-		return err
-	}
-
 	// Create link with token
 	ref, err := url.Parse("/auth/register")
 	if err != nil {
@@ -182,11 +355,29 @@ func (app *application) handleAuthSignupPost(w http.ResponseWriter, r *http.Requ
 	ref.RawQuery = q.Encode()
 	href := app.baseURL.ResolveReference(ref)
 
-	// Send mail in background routine
-	app.background(func() error {
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		// Limit the verification emails that anyone can have sent. Only
+		// the emails that are queued count towards the limits.
+		err := tx.Throttle.Acquire(r.Context(), form.Email, middleware.ClientKey(r), app.config.Throttle)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.New(r.Context(), token.Hash, token.Expiry, data.ScopeRegistration, form.Email)
+		if err != nil {
+			return err
+		}
+
 		component := emails.Registration(href.String())
-		return app.mailer.Send(form.Email, "Registration", component)
+		return app.sendMail(r.Context(), tx, i18n.Locale(r.Context()), form.Email, "Registration", component)
 	})
+	if errors.Is(err, data.ErrThrottled) {
+		return app.renderError(w, "too many verification emails. please try again later", http.StatusTooManyRequests)
+	}
+	// Mail isn't sent to addresses that bounced, but the response is the same
+	if err != nil && !errors.Is(err, data.ErrSuppressedAddress) {
+		return err
+	}
 
 	// TODO: respond with message
 	app.refresh(w, r)
@@ -239,37 +430,52 @@ func (app *application) handleAuthRegisterPost(w http.ResponseWriter, r *http.Re
 
 	tokenHash := crypto.TokenHash(plaintextToken)
 
-	err = app.db.VerificationTokens.Verify(r.Context(), tokenHash, data.ScopeRegistration, form.Email)
+	passwordHash, err := crypto.PasswordHash(form.Password)
 	if err != nil {
-		if errors.Is(err, data.ErrRecordNotFound) {
-			return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return err
+	}
+
+	var user *data.User
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.VerificationTokens.Consume(r.Context(), tokenHash, data.ScopeRegistration, form.Email)
+		if err != nil {
+			return err
 		}
-		if errors.Is(err, data.ErrExpiredToken) {
-			// TODO: flash message
-			http.Redirect(w, r, "/", http.StatusSeeOther)
 
-			return nil
+		// Upon registration, purge db of all verifications with email.
+		err = tx.VerificationTokens.Purge(r.Context(), form.Email)
+		if err != nil {
+			return err
 		}
 
-		return err
-	}
+		user, err = tx.Users.New(r.Context(), form.Email, passwordHash)
+		if err != nil {
+			return err
+		}
 
-	// Upon registration, purge db of all verifications with email.
-	err = app.db.VerificationTokens.Purge(r.Context(), form.Email)
-	if err != nil {
-		return err
-	}
+		// Keep the language the user registered in
+		if locale := i18n.Locale(r.Context()); locale != user.Locale {
+			user.Locale = locale
+			err = tx.Users.Update(r.Context(), user)
+			if err != nil {
+				return err
+			}
+		}
 
-	passwordHash, err := crypto.PasswordHash(form.Password)
-	if err != nil {
+		// The device used to register is known to the user
+		_, err = tx.Devices.Remember(r.Context(), user.ID, middleware.ClientIP(r), r.UserAgent())
 		return err
-	}
-
-	user, err := app.db.Users.New(r.Context(), form.Email, passwordHash)
+	})
 	if err != nil {
-		if errors.Is(err, data.ErrDuplicateEmail) {
+		if errors.Is(err, data.ErrRecordNotFound) || errors.Is(err, data.ErrDuplicateEmail) {
 			return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
 		}
+		if errors.Is(err, data.ErrExpiredToken) {
+			// TODO: flash message
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+
+			return nil
+		}
 
 		return err
 	}
@@ -320,18 +526,19 @@ func (app *application) handleAuthResetPost(w http.ResponseWriter, r *http.Reque
 		return err
 	}
 
-	exists, err := app.db.Users.ExistsWithEmail(r.Context(), form.Email)
+	user, err := app.db.Users.GetWithEmail(r.Context(), form.Email)
 	if err != nil {
-		return err
-	}
-	if !exists {
-		// respond with consistent message email sent
-		app.refresh(w, r)
+		if errors.Is(err, data.ErrRecordNotFound) {
+			// respond with consistent message email sent
+			app.refresh(w, r)
 
-		return nil
+			return nil
+		}
+
+		return err
 	}
 
-	exists, err = app.db.VerificationTokens.Exists(r.Context(), data.ScopePasswordReset, form.Email)
+	exists, err := app.db.VerificationTokens.Exists(r.Context(), data.ScopePasswordReset, form.Email)
 	if err != nil {
 		return err
 	}
@@ -347,11 +554,6 @@ func (app *application) handleAuthResetPost(w http.ResponseWriter, r *http.Reque
 		return err
 	}
 
-	err = app.db.VerificationTokens.New(r.Context(), token.Hash, token.Expiry, data.ScopePasswordReset, form.Email)
-	if err != nil {
-		return err
-	}
-
 	// Create link to reset password with token and email and token
 	// as query parameters.
 	ref, err := url.Parse("/auth/reset/update")
@@ -364,11 +566,29 @@ func (app *application) handleAuthResetPost(w http.ResponseWriter, r *http.Reque
 	ref.RawQuery = q.Encode()
 	href := app.baseURL.ResolveReference(ref)
 
-	// Send mail in background routine
-	app.background(func() error {
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		// Limit the verification emails that anyone can have sent. Only
+		// the emails that are queued count towards the limits.
+		err := tx.Throttle.Acquire(r.Context(), form.Email, middleware.ClientKey(r), app.config.Throttle)
+		if err != nil {
+			return err
+		}
+
+		err = tx.VerificationTokens.New(r.Context(), token.Hash, token.Expiry, data.ScopePasswordReset, form.Email)
+		if err != nil {
+			return err
+		}
+
 		component := emails.PasswordReset(href.String())
-		return app.mailer.Send(form.Email, "Password Reset", component)
+		return app.sendMail(r.Context(), tx, user.Locale, form.Email, "Password Reset", component)
 	})
+	if errors.Is(err, data.ErrThrottled) {
+		return app.renderError(w, "too many verification emails. please try again later", http.StatusTooManyRequests)
+	}
+	// Mail isn't sent to addresses that bounced, but the response is the same
+	if err != nil && !errors.Is(err, data.ErrSuppressedAddress) {
+		return err
+	}
 
 	// respond with consistent message email sent
 	app.refresh(w, r)
@@ -407,7 +627,34 @@ func (app *application) handleAuthResetUpdatePost(w http.ResponseWriter, r *http
 
 	tokenHash := crypto.TokenHash(form.PlaintextToken)
 
-	err = app.db.VerificationTokens.Verify(r.Context(), tokenHash, data.ScopePasswordReset, form.Email)
+	passwordHash, err := crypto.PasswordHash(form.Password)
+	if err != nil {
+		return err
+	}
+
+	err = app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		err := tx.VerificationTokens.Consume(r.Context(), tokenHash, data.ScopePasswordReset, form.Email)
+		if err != nil {
+			return err
+		}
+
+		user, err := tx.Users.GetWithEmail(r.Context(), form.Email)
+		if err != nil {
+			return err
+		}
+
+		// Resetting the password proves ownership of the email address,
+		// so it also unlocks the account.
+		user.PasswordHash = passwordHash
+		user.Locked = false
+
+		err = tx.Users.Update(r.Context(), user)
+		if err != nil {
+			return err
+		}
+
+		return tx.VerificationTokens.Purge(r.Context(), form.Email)
+	})
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			return app.renderError(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
@@ -422,26 +669,6 @@ func (app *application) handleAuthResetUpdatePost(w http.ResponseWriter, r *http
 		return err
 	}
 
-	user, err := app.db.Users.GetWithEmail(r.Context(), form.Email)
-	if err != nil {
-		return err
-	}
-
-	user.PasswordHash, err = crypto.PasswordHash(form.Password)
-	if err != nil {
-		return err
-	}
-
-	err = app.db.Users.Update(r.Context(), user)
-	if err != nil {
-		return err
-	}
-
-	err = app.db.VerificationTokens.Purge(r.Context(), form.Email)
-	if err != nil {
-		return err
-	}
-
 	app.sessionManager.Clear(r.Context())
 
 	// TODO: flash password updated success please login
@@ -1,44 +1,61 @@
-package main
+package web
 
 import (
 	"bytes"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-playground/form/v4"
 	"github.com/go-playground/validator/v10"
+	"github.com/micahco/mono/internal/fielderr"
 )
 
 const formErrorsSessionKey = "form-errors"
 
-type FormErrors map[string]string
+// Error of each form field, with the same codes as the errors of the API
+type FormErrors map[string]*fielderr.Error
 
 func (formErrors FormErrors) Error() string {
 	buff := bytes.NewBufferString("")
 
-	for name, msg := range formErrors {
-		buff.WriteString(name + ": " + msg)
+	for name, err := range formErrors {
+		buff.WriteString(name + ": " + err.Message)
 		buff.WriteString("\n")
 	}
 
 	return strings.TrimSpace(buff.String())
 }
 
+// Form error with a code from fielderr
+func formError(code, message string, params map[string]any) *fielderr.Error {
+	return &fielderr.Error{
+		Code:    code,
+		Message: message,
+		Params:  params,
+	}
+}
+
 func (app *application) putFormErrors(r *http.Request, formErrors FormErrors) {
 	app.sessionManager.Put(r.Context(), formErrorsSessionKey, formErrors)
 }
 
-func (app *application) popFormErrors(r *http.Request) FormErrors {
+// Messages of the form errors, for rendering next to their fields
+func (app *application) popFormErrors(r *http.Request) map[string]string {
+	messages := make(map[string]string)
+
 	exists := app.sessionManager.Exists(r.Context(), formErrorsSessionKey)
 	if exists {
 		formErrors, ok := app.sessionManager.Pop(r.Context(), formErrorsSessionKey).(FormErrors)
 		if ok {
-			return formErrors
+			for name, err := range formErrors {
+				messages[name] = err.Message
+			}
 		}
 	}
 
-	return FormErrors{}
+	return messages
 }
 
 func (app *application) parseForm(r *http.Request, dst any) error {
@@ -68,23 +85,26 @@ func (app *application) parseForm(r *http.Request, dst any) error {
 				tag := fieldErr.Tag()
 				param := fieldErr.Param()
 
-				var msg string
+				var err *fielderr.Error
 				switch tag {
+				case "required":
+					err = formError(fielderr.CodeRequired, "required", nil)
 				case "email":
-					msg = "invalid email"
+					err = formError(fielderr.CodeInvalidEmail, "invalid email", nil)
 				case "min":
-					msg = "minimum length: " + param
+					err = formError(fielderr.CodeTooShort, "minimum length: "+param, map[string]any{"min": intParam(param)})
 				case "max":
-					msg = "maximum length: " + param
+					err = formError(fielderr.CodeTooLong, "maximum length: "+param, map[string]any{"max": intParam(param)})
 				default:
-					msg = tag
+					msg := tag
 					if param != "" {
 						msg += ": " + param
 					}
+					err = formError(fielderr.CodeInvalid, msg, nil)
 				}
 
 				name := strings.ToLower(fieldErr.StructField())
-				formErrors[name] = msg
+				formErrors[name] = err
 			}
 			return formErrors
 		default:
@@ -94,3 +114,13 @@ func (app *application) parseForm(r *http.Request, dst any) error {
 
 	return nil
 }
+
+// Numeric param of a validation tag, as in the params of the API
+func intParam(param string) any {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		return param
+	}
+
+	return n
+}
@@ -1,4 +1,4 @@
-package main
+package web
 
 import (
 	"context"
@@ -8,6 +8,8 @@ import (
 	"net/http"
 
 	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/i18n"
 )
 
 type handlerWithError func(w http.ResponseWriter, r *http.Request) error
@@ -62,7 +64,8 @@ func (app *application) recovery(next http.Handler) http.Handler {
 	})
 }
 
-// Reads session authenticated user id key and checks if that user exists.
+// Reads session authenticated user id key and checks if that user exists
+// and is not locked.
 // If all systems check, then set authenticated context to the request.
 func (app *application) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -77,14 +80,17 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		exists, err := app.db.Users.Exists(r.Context(), id)
-		if err != nil {
+		user, err := app.db.Users.Get(r.Context(), id)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
 			app.serverError(w, "middleware authenticate", err)
 
 			return
 		}
-		if exists {
+		// Locked and disabled accounts are treated as signed out
+		if err == nil && !user.Locked && user.Active {
 			ctx := context.WithValue(r.Context(), isAuthenticatedContextKey, true)
+			// Signed in users see their own language
+			ctx = i18n.WithLocale(ctx, user.Locale)
 			r = r.WithContext(ctx)
 		}
 
@@ -92,6 +98,19 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// Translate pages into the language preferred by the browser. The
+// authenticate middleware overrides it with the language of the user.
+func (app *application) localize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+
+		locale := i18n.Match(r.Header.Get("Accept-Language"))
+		ctx := i18n.WithLocale(r.Context(), locale)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !app.isAuthenticated(r) {
@@ -106,6 +125,26 @@ func (app *application) requireAuthentication(next http.Handler) http.Handler {
 	})
 }
 
+// Redirects to the password confirmation page unless the user entered
+// their password recently. Must be used after requireAuthentication.
+func (app *application) requireRecentAuthentication(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.isRecentlyAuthenticated(r) {
+			// Only a page can be returned to after confirming
+			returnTo := "/"
+			if r.Method == http.MethodGet {
+				returnTo = r.URL.RequestURI()
+			}
+			app.sessionManager.Put(r.Context(), returnToSessionKey, returnTo)
+
+			http.Redirect(w, r, "/auth/confirm", http.StatusSeeOther)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) csrfFailureHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		app.logger.Error("csrf failure handler",
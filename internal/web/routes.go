@@ -1,10 +1,11 @@
-package main
+package web
 
 import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/justinas/nosurf"
+	"github.com/micahco/mono/internal/devmail"
 	"github.com/micahco/mono/internal/middleware"
 	"github.com/micahco/mono/ui"
 	"github.com/micahco/mono/ui/pages"
@@ -13,13 +14,20 @@ import (
 // App router
 func (app *application) routes() http.Handler {
 	r := chi.NewRouter()
+	r.Use(middleware.RealIP(app.config.TrustedProxies))
 	r.Use(app.recovery)
 	r.Use(middleware.SecureHeaders)
+	r.Use(app.localize)
 
 	// Static files
 	r.Handle("/static/*", app.handleStatic())
 	r.Get("/favicon.ico", app.handleFavicon)
 
+	// Email previews and captured mail
+	if app.config.Dev {
+		r.Mount(devmail.Path, devmail.New(app.mailer, app.sink))
+	}
+
 	r.Route("/", func(r chi.Router) {
 		r.Use(app.sessionManager.LoadAndSave)
 		r.Use(middleware.NoSurf(app.csrfFailureHandler()))
@@ -30,6 +38,9 @@ func (app *application) routes() http.Handler {
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/login", app.handle(app.handleAuthLoginPost))
 			r.Post("/logout", app.handle(app.handleAuthLogoutPost))
+			r.Post("/sso", app.handle(app.handleAuthSSOPost))
+			r.With(app.requireAuthentication).Get("/confirm", app.handle(app.handleAuthConfirmGet))
+			r.With(app.requireAuthentication).Post("/confirm", app.handle(app.handleAuthConfirmPost))
 			r.Post("/signup", app.handle(app.handleAuthSignupPost))
 			r.Get("/register", app.handle(app.handleAuthRegisterGet))
 			r.Post("/register", app.handle(app.handleAuthRegisterPost))
@@ -37,9 +48,40 @@ func (app *application) routes() http.Handler {
 			r.Post("/reset", app.handle(app.handleAuthResetPost))
 			r.Get("/reset/update", app.handle(app.handleAuthResetUpdateGet))
 			r.Post("/reset/update", app.handle(app.handleAuthResetUpdatePost))
+			r.Get("/sessions/revoke", app.handle(app.handleAuthSessionsRevokeGet))
+			r.Post("/sessions/revoke", app.handle(app.handleAuthSessionsRevokePost))
+			r.Get("/email/revert", app.handle(app.handleAuthEmailRevertGet))
+			r.Post("/email/revert", app.handle(app.handleAuthEmailRevertPost))
+		})
+
+		r.Route("/account", func(r chi.Router) {
+			r.Use(app.requireAuthentication)
+
+			r.Post("/locale", app.handle(app.handleAccountLocalePost))
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.requireRecentAuthentication)
+
+				r.Get("/email", app.handle(app.handleAccountEmailGet))
+				r.Post("/email", app.handle(app.handleAccountEmailPost))
+				r.Get("/email/update", app.handle(app.handleAccountEmailUpdateGet))
+				r.Post("/email/update", app.handle(app.handleAccountEmailUpdatePost))
+			})
 		})
 	})
 
+	// SAML single sign-on. Identity providers post responses cross-site,
+	// so these routes aren't protected from CSRF.
+	if app.samlEnabled() {
+		r.Route("/saml/{provider}", func(r chi.Router) {
+			r.Use(app.sessionManager.LoadAndSave)
+
+			r.Get("/metadata", app.handle(app.handleSAMLMetadataGet))
+			r.Get("/login", app.handle(app.handleSAMLLoginGet))
+			r.Post("/acs", app.handle(app.handleSAMLACSPost))
+		})
+	}
+
 	return r
 }
 
@@ -67,10 +109,10 @@ func (app *application) getIndex(w http.ResponseWriter, r *http.Request) error {
 			return err
 		}
 
-		component := pages.Dashboard(user)
+		component := pages.Dashboard(nosurf.Token(r), user)
 		return app.render(w, r, http.StatusOK, "Dashboard", component)
 	}
 
-	component := pages.Login(nosurf.Token(r), app.popFormErrors(r))
+	component := pages.Login(nosurf.Token(r), app.popFormErrors(r), app.samlEnabled())
 	return app.render(w, r, http.StatusOK, "Welcome", component)
 }
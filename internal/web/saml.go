@@ -0,0 +1,398 @@
+package web
+
+import (
+	stdcrypto "crypto"
+	"crypto/ecdsa"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/go-chi/chi/v5"
+	"github.com/micahco/mono/internal/crypto"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/fielderr"
+	"github.com/micahco/mono/internal/i18n"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+// Attributes identity providers commonly use for the email and name
+var (
+	samlEmailAttributes = []string{
+		"email",
+		"mail",
+		"urn:oid:0.9.2342.19200300.100.1.3",
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	}
+	samlDisplayNameAttributes = []string{
+		"displayName",
+		"urn:oid:2.16.840.1.113730.3.1.241",
+		"http://schemas.microsoft.com/identity/claims/displayname",
+	}
+)
+
+// Existing user with the asserted email who isn't linked to the provider
+var errSAMLUnlinkedUser = errors.New("saml: user not linked to provider")
+
+func (app *application) samlEnabled() bool {
+	return app.samlKeyPair != nil
+}
+
+// Service provider for the organization's identity provider. Each
+// provider gets its own entity ID and assertion consumer service URL.
+func (app *application) samlServiceProvider(p *data.SAMLProvider) (*saml.ServiceProvider, error) {
+	idpMetadata, err := samlsp.ParseMetadata([]byte(p.Metadata))
+	if err != nil {
+		return nil, err
+	}
+
+	metadataURL := app.baseURL.ResolveReference(&url.URL{Path: "/saml/" + p.Slug + "/metadata"})
+	acsURL := app.baseURL.ResolveReference(&url.URL{Path: "/saml/" + p.Slug + "/acs"})
+
+	key, ok := app.samlKeyPair.PrivateKey.(stdcrypto.Signer)
+	if !ok {
+		return nil, errors.New("saml: private key can't sign")
+	}
+
+	signatureMethod := dsig.RSASHA256SignatureMethod
+	if _, ok := app.samlKeyPair.PrivateKey.(*ecdsa.PrivateKey); ok {
+		signatureMethod = dsig.ECDSASHA256SignatureMethod
+	}
+
+	sp := &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		Key:               key,
+		Certificate:       app.samlKeyPair.Leaf,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.EmailAddressNameIDFormat,
+		SignatureMethod:   signatureMethod,
+	}
+
+	return sp, nil
+}
+
+func (app *application) getSAMLProvider(r *http.Request) (*data.SAMLProvider, *saml.ServiceProvider, error) {
+	p, err := app.db.SAML.GetProvider(r.Context(), chi.URLParam(r, "provider"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sp, err := app.samlServiceProvider(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return p, sp, nil
+}
+
+// Start single sign-on with the identity provider of the email's domain
+func (app *application) handleAuthSSOPost(w http.ResponseWriter, r *http.Request) error {
+	if app.isAuthenticated(r) {
+		return app.renderError(w, "already authenticated", http.StatusBadRequest)
+	}
+
+	var form struct {
+		Email string `form:"email" validate:"required,email"`
+	}
+
+	err := app.parseForm(r, &form)
+	if err != nil {
+		return err
+	}
+
+	_, domain, _ := strings.Cut(form.Email, "@")
+
+	p, err := app.db.SAML.GetProviderWithDomain(r.Context(), domain)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return FormErrors{"sso": formError(fielderr.CodeInvalid, "single sign-on is not configured for this domain", nil)}
+		}
+
+		return err
+	}
+
+	http.Redirect(w, r, "/saml/"+p.Slug+"/login", http.StatusSeeOther)
+
+	return nil
+}
+
+func (app *application) handleSAMLMetadataGet(w http.ResponseWriter, r *http.Request) error {
+	_, sp, err := app.getSAMLProvider(r)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return app.renderError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		}
+
+		return err
+	}
+
+	buf, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.Write(buf)
+
+	return nil
+}
+
+// SP-initiated login. Redirects to the identity provider with a signed
+// authentication request.
+func (app *application) handleSAMLLoginGet(w http.ResponseWriter, r *http.Request) error {
+	p, sp, err := app.getSAMLProvider(r)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return app.renderError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		}
+
+		return err
+	}
+
+	// Users confirming their identity before a sensitive operation sign in
+	// again at the provider and return to the page that required it.
+	var returnTo string
+	if app.sessionManager.Exists(r.Context(), authenticatedUserIDSessionKey) {
+		returnTo = app.sessionManager.PopString(r.Context(), returnToSessionKey)
+	}
+	if returnTo != "" {
+		forceAuthn := true
+		sp.ForceAuthn = &forceAuthn
+	}
+
+	idpURL := sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if idpURL == "" {
+		return fmt.Errorf("saml provider %s: no HTTP-Redirect binding", p.Slug)
+	}
+
+	req, err := sp.MakeAuthenticationRequest(idpURL, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return err
+	}
+
+	// The relay state identifies the request when the response is posted
+	// back, since the session cookie isn't sent on cross-site requests.
+	relayState, err := crypto.GeneratePlaintextToken()
+	if err != nil {
+		return err
+	}
+
+	expiry := time.Now().Add(data.SAMLRequestTTL)
+	err = app.db.SAML.NewRequest(r.Context(), crypto.TokenHash(relayState), expiry, p.ID, req.ID, returnTo)
+	if err != nil {
+		return err
+	}
+
+	redirectURL, err := req.Redirect(relayState, sp)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+
+	return nil
+}
+
+// Assertion consumer service. Validates the response posted by the
+// identity provider and logs in the user.
+func (app *application) handleSAMLACSPost(w http.ResponseWriter, r *http.Request) error {
+	p, sp, err := app.getSAMLProvider(r)
+	if err != nil {
+		if errors.Is(err, data.ErrRecordNotFound) {
+			return app.renderError(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		}
+
+		return err
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		return app.renderError(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	}
+
+	// Responses to our own requests must match the request ID. Unsolicited
+	// responses are only accepted from providers that allow them.
+	var possibleRequestIDs []string
+	returnTo := "/"
+	req, err := app.db.SAML.ConsumeRequest(r.Context(), crypto.TokenHash(r.PostForm.Get("RelayState")))
+	switch {
+	case err == nil && req.ProviderID == p.ID:
+		possibleRequestIDs = []string{req.RequestID}
+		if req.ReturnTo != "" {
+			returnTo = req.ReturnTo
+		}
+	case err == nil, errors.Is(err, data.ErrRecordNotFound):
+		if !p.AllowIDPInitiated {
+			return app.renderError(w, "invalid single sign-on response", http.StatusUnauthorized)
+		}
+		sp.AllowIDPInitiated = true
+	default:
+		return err
+	}
+
+	assertion, err := sp.ParseResponse(r, possibleRequestIDs)
+	if err != nil {
+		var invalidResponseErr *saml.InvalidResponseError
+		if errors.As(err, &invalidResponseErr) {
+			app.logger.Warn("saml: invalid response", "provider", p.Slug, "err", invalidResponseErr.PrivateErr)
+		}
+
+		return app.renderError(w, "invalid single sign-on response", http.StatusUnauthorized)
+	}
+
+	// Each assertion can only be used once while it is valid
+	expiry := time.Now().Add(saml.MaxIssueDelay + saml.MaxClockSkew)
+	if assertion.Conditions != nil {
+		expiry = assertion.Conditions.NotOnOrAfter.Add(saml.MaxClockSkew)
+	}
+	err = app.db.SAML.RememberAssertion(r.Context(), assertion.ID, expiry)
+	if err != nil {
+		if errors.Is(err, data.ErrReplayedAssertion) {
+			return app.renderError(w, "invalid single sign-on response", http.StatusUnauthorized)
+		}
+
+		return err
+	}
+
+	email := samlAttribute(assertion, samlEmailAttributes)
+	if email == "" && assertion.Subject != nil && assertion.Subject.NameID != nil {
+		email = assertion.Subject.NameID.Value
+	}
+
+	// Providers may only sign in users of their organization's domain
+	_, domain, _ := strings.Cut(email, "@")
+	if !strings.EqualFold(domain, p.Domain) {
+		return app.renderError(w, "email is not in the organization's domain", http.StatusForbidden)
+	}
+
+	user, err := app.samlProvision(r, p, email, samlAttribute(assertion, samlDisplayNameAttributes))
+	if err != nil {
+		if errors.Is(err, errSAMLUnlinkedUser) {
+			return app.renderError(w, "an account with this email already exists. sign in with your password", http.StatusForbidden)
+		}
+
+		return err
+	}
+
+	if !user.Active {
+		return app.renderError(w, "account disabled", http.StatusForbidden)
+	}
+	if user.Locked {
+		return app.renderError(w, "account locked. reset your password to unlock it", http.StatusForbidden)
+	}
+
+	// Also marks the user as recently authenticated
+	err = app.login(r, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = app.rememberDevice(r, user)
+	if err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, returnTo, http.StatusSeeOther)
+
+	return nil
+}
+
+// Sign in the user linked to the provider with the asserted email, or
+// create one. Existing users that aren't linked to the provider are only
+// linked if the provider allows it, otherwise returns errSAMLUnlinkedUser.
+func (app *application) samlProvision(r *http.Request, p *data.SAMLProvider, email, displayName string) (*data.User, error) {
+	var user *data.User
+	err := app.db.WithTx(r.Context(), func(tx *data.DB) error {
+		var err error
+		user, err = tx.Users.GetWithEmail(r.Context(), email)
+		switch {
+		case err == nil:
+			linked, err := tx.SAML.GetProviderWithUser(r.Context(), user.ID)
+			switch {
+			case err == nil && linked.ID == p.ID:
+			case err == nil, errors.Is(err, data.ErrRecordNotFound):
+				if !p.LinkByEmail {
+					return errSAMLUnlinkedUser
+				}
+
+				err = tx.SAML.LinkUser(r.Context(), user.ID, p.ID)
+				if err != nil {
+					return err
+				}
+			default:
+				return err
+			}
+		case errors.Is(err, data.ErrRecordNotFound):
+			// Provisioned users sign in with the identity provider until
+			// they reset their password.
+			password, err := crypto.GeneratePlaintextToken()
+			if err != nil {
+				return err
+			}
+
+			passwordHash, err := crypto.PasswordHash(password)
+			if err != nil {
+				return err
+			}
+
+			user, err = tx.Users.New(r.Context(), email, passwordHash)
+			if err != nil {
+				return err
+			}
+
+			err = tx.SAML.LinkUser(r.Context(), user.ID, p.ID)
+			if err != nil {
+				return err
+			}
+
+			// Keep the language of the first sign in
+			if locale := i18n.Locale(r.Context()); locale != user.Locale {
+				user.Locale = locale
+				err = tx.Users.Update(r.Context(), user)
+				if err != nil {
+					return err
+				}
+			}
+		default:
+			return err
+		}
+
+		if displayName == "" || user.DisplayName == displayName {
+			return nil
+		}
+
+		user.DisplayName = displayName
+
+		return tx.Users.Update(r.Context(), user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// First value of the first attribute present in the assertion
+func samlAttribute(assertion *saml.Assertion, names []string) string {
+	for _, name := range names {
+		for _, stmt := range assertion.AttributeStatements {
+			for _, attr := range stmt.Attributes {
+				if attr.Name != name && attr.FriendlyName != name {
+					continue
+				}
+				if len(attr.Values) > 0 && attr.Values[0].Value != "" {
+					return attr.Values[0].Value
+				}
+			}
+		}
+	}
+
+	return ""
+}
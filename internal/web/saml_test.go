@@ -0,0 +1,424 @@
+package web
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/gob"
+	"encoding/xml"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/crewjam/saml"
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid/v5"
+	"github.com/micahco/mono/internal/authn"
+	"github.com/micahco/mono/internal/data"
+	"github.com/micahco/mono/internal/data/memory"
+	"github.com/micahco/mono/internal/geoip"
+	"github.com/micahco/mono/internal/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Self-signed key pair for signing and encrypting SAML messages
+func newTestKeyPair(t *testing.T, commonName string) *tls.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}
+}
+
+// Identity provider that signs responses for the test server
+func newTestIDP(t *testing.T) *saml.IdentityProvider {
+	keyPair := newTestKeyPair(t, "idp.example.com")
+
+	return &saml.IdentityProvider{
+		Key:         keyPair.PrivateKey,
+		Certificate: keyPair.Leaf,
+		MetadataURL: url.URL{Scheme: "https", Host: "idp.example.com", Path: "/metadata"},
+		SSOURL:      url.URL{Scheme: "https", Host: "idp.example.com", Path: "/sso"},
+	}
+}
+
+type samlTestServer struct {
+	*httptest.Server
+	app *application
+}
+
+// Web server of the real routes with an in-memory database, which has a
+// provider for each of the identity providers
+func newSAMLTestServer(t *testing.T, providers map[*saml.IdentityProvider]*data.SAMLProvider) *samlTestServer {
+	db := memory.NewMemoryDB()
+	gob.Register(uuid.UUID{})
+	gob.Register(time.Time{})
+
+	locator, err := geoip.Open("")
+	require.NoError(t, err)
+
+	app := &application{
+		db:             *db.DB,
+		logger:         slog.New(slog.DiscardHandler),
+		mailer:         mailer.New(mailer.NewMemory(), &mail.Address{Address: "no-reply@example.com"}, nil),
+		locator:        locator,
+		authn:          &authn.Local{Users: db.Users},
+		sessionManager: scs.New(),
+		formDecoder:    form.NewDecoder(),
+		validate:       validator.New(),
+		samlKeyPair:    newTestKeyPair(t, "sp.example.com"),
+	}
+
+	for idp, p := range providers {
+		metadata, err := xml.Marshal(idp.Metadata())
+		require.NoError(t, err)
+		p.Metadata = string(metadata)
+		require.NoError(t, db.SAML.NewProvider(context.Background(), p))
+	}
+
+	// Service provider URLs are resolved against the server's URL
+	ts := httptest.NewServer(nil)
+	t.Cleanup(ts.Close)
+	app.baseURL, err = url.Parse(ts.URL)
+	require.NoError(t, err)
+	ts.Config.Handler = app.routes()
+
+	return &samlTestServer{ts, app}
+}
+
+func (ts *samlTestServer) serviceProvider(t *testing.T, slug string) *saml.ServiceProvider {
+	p, err := ts.app.db.SAML.GetProvider(context.Background(), slug)
+	require.NoError(t, err)
+	sp, err := ts.app.samlServiceProvider(p)
+	require.NoError(t, err)
+
+	return sp
+}
+
+// Client that doesn't follow redirects
+func (ts *samlTestServer) client() *http.Client {
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Start an SP-initiated login and return the signed response of the
+// identity provider for the email
+func (ts *samlTestServer) login(t *testing.T, idp *saml.IdentityProvider, slug, email string) url.Values {
+	return respond(t, ts.authnRequest(t, ts.client(), idp, slug), email)
+}
+
+// Start an SP-initiated login with the client and return the
+// authentication request received by the identity provider
+func (ts *samlTestServer) authnRequest(t *testing.T, c *http.Client, idp *saml.IdentityProvider, slug string) *saml.IdpAuthnRequest {
+	sp := ts.serviceProvider(t, slug)
+	idp.ServiceProviderProvider = testServiceProviders{sp.EntityID: sp.Metadata()}
+
+	res, err := c.Get(ts.URL + "/saml/" + slug + "/login")
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusFound, res.StatusCode)
+
+	r := httptest.NewRequest(http.MethodGet, res.Header.Get("Location"), nil)
+	req, err := saml.NewIdpAuthnRequest(idp, r)
+	require.NoError(t, err)
+	require.NoError(t, req.Validate())
+
+	return req
+}
+
+// Unsolicited response of the identity provider for the email
+func (ts *samlTestServer) idpInitiated(t *testing.T, idp *saml.IdentityProvider, slug, relayState, email string) url.Values {
+	spMetadata := ts.serviceProvider(t, slug).Metadata()
+
+	req := &saml.IdpAuthnRequest{
+		IDP:                     idp,
+		HTTPRequest:             httptest.NewRequest(http.MethodGet, "/", nil),
+		RelayState:              relayState,
+		ServiceProviderMetadata: spMetadata,
+		SPSSODescriptor:         &spMetadata.SPSSODescriptors[0],
+		Now:                     saml.TimeNow(),
+	}
+	for _, endpoint := range req.SPSSODescriptor.AssertionConsumerServices {
+		if endpoint.Binding == saml.HTTPPostBinding {
+			req.ACSEndpoint = &endpoint
+			break
+		}
+	}
+	require.NotNil(t, req.ACSEndpoint)
+
+	return respond(t, req, email)
+}
+
+func respond(t *testing.T, req *saml.IdpAuthnRequest, email string) url.Values {
+	session := &saml.Session{
+		ID:           "session",
+		NameID:       email,
+		NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		UserEmail:    email,
+	}
+	require.NoError(t, saml.DefaultAssertionMaker{}.MakeAssertion(req, session))
+
+	form, err := req.PostBinding()
+	require.NoError(t, err)
+
+	return url.Values{"SAMLResponse": {form.SAMLResponse}, "RelayState": {form.RelayState}}
+}
+
+func (ts *samlTestServer) postACS(t *testing.T, slug string, form url.Values) *http.Response {
+	res, err := ts.client().PostForm(ts.URL+"/saml/"+slug+"/acs", form)
+	require.NoError(t, err)
+	res.Body.Close()
+
+	return res
+}
+
+type testServiceProviders map[string]*saml.EntityDescriptor
+
+func (sps testServiceProviders) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	return sps[serviceProviderID], nil
+}
+
+func TestSAMLACS(t *testing.T) {
+	idp := newTestIDP(t)
+	partnerIDP := newTestIDP(t)
+	ts := newSAMLTestServer(t, map[*saml.IdentityProvider]*data.SAMLProvider{
+		idp:        {Slug: "acme", Name: "Acme", Domain: "acme.example"},
+		partnerIDP: {Slug: "partner", Name: "Partner", Domain: "partner.example", AllowIDPInitiated: true},
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		res, err := ts.client().Get(ts.URL + "/saml/acme/metadata")
+		require.NoError(t, err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/samlmetadata+xml", res.Header.Get("Content-Type"))
+
+		var metadata saml.EntityDescriptor
+		require.NoError(t, xml.NewDecoder(res.Body).Decode(&metadata))
+		assert.Equal(t, ts.URL+"/saml/acme/metadata", metadata.EntityID)
+
+		res, err = ts.client().Get(ts.URL + "/saml/unknown/metadata")
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("Login", func(t *testing.T) {
+		res := ts.postACS(t, "acme", ts.login(t, idp, "acme", "alice@acme.example"))
+		assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+		assert.Equal(t, "/", res.Header.Get("Location"))
+
+		_, err := ts.app.db.Users.GetWithEmail(context.Background(), "alice@acme.example")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Replay", func(t *testing.T) {
+		form := ts.login(t, idp, "acme", "alice@acme.example")
+		res := ts.postACS(t, "acme", form)
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+
+		// The relay state was consumed by the first response
+		res = ts.postACS(t, "acme", form)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		// Providers that accept unsolicited responses still reject an
+		// assertion that was already used
+		form = ts.idpInitiated(t, partnerIDP, "partner", "", "bob@partner.example")
+		res = ts.postACS(t, "partner", form)
+		require.Equal(t, http.StatusSeeOther, res.StatusCode)
+
+		res = ts.postACS(t, "partner", form)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("UnknownRelayState", func(t *testing.T) {
+		// Unsolicited responses to a provider that doesn't allow them
+		res := ts.postACS(t, "acme", ts.idpInitiated(t, idp, "acme", "unknown", "alice@acme.example"))
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		res = ts.postACS(t, "acme", ts.idpInitiated(t, idp, "acme", "", "alice@acme.example"))
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		// A response with the relay state of another provider's request
+		form := ts.login(t, partnerIDP, "partner", "bob@partner.example")
+		res = ts.postACS(t, "acme", ts.idpInitiated(t, idp, "acme", form.Get("RelayState"), "alice@acme.example"))
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		// Accepted by a provider that allows them
+		res = ts.postACS(t, "partner", ts.idpInitiated(t, partnerIDP, "partner", "unknown", "carol@partner.example"))
+		assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+	})
+
+	t.Run("WrongDomain", func(t *testing.T) {
+		res := ts.postACS(t, "acme", ts.login(t, idp, "acme", "mallory@partner.example"))
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		// Subdomains aren't the organization's domain
+		res = ts.postACS(t, "acme", ts.login(t, idp, "acme", "mallory@evil.acme.example"))
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+		_, err := ts.app.db.Users.GetWithEmail(context.Background(), "mallory@partner.example")
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("WrongSigner", func(t *testing.T) {
+		// Signed by the identity provider of another organization
+		res := ts.postACS(t, "acme", ts.login(t, partnerIDP, "acme", "alice@acme.example"))
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		form := ts.login(t, idp, "acme", "alice@acme.example")
+		form.Set("SAMLResponse", strings.ToUpper(form.Get("SAMLResponse")))
+		res := ts.postACS(t, "acme", form)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	})
+
+	t.Run("UnknownProvider", func(t *testing.T) {
+		res := ts.postACS(t, "unknown", url.Values{})
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
+
+func TestSAMLLinkByEmail(t *testing.T) {
+	ctx := context.Background()
+	idp := newTestIDP(t)
+	partnerIDP := newTestIDP(t)
+	ts := newSAMLTestServer(t, map[*saml.IdentityProvider]*data.SAMLProvider{
+		idp:        {Slug: "acme", Name: "Acme", Domain: "acme.example"},
+		partnerIDP: {Slug: "partner", Name: "Partner", Domain: "partner.example", LinkByEmail: true},
+	})
+
+	// Local users who signed up with a password before single sign-on
+	dave, err := ts.app.db.Users.New(ctx, "dave@acme.example", []byte("password"))
+	require.NoError(t, err)
+	erin, err := ts.app.db.Users.New(ctx, "erin@partner.example", []byte("password"))
+	require.NoError(t, err)
+
+	t.Run("Refused", func(t *testing.T) {
+		res := ts.postACS(t, "acme", ts.login(t, idp, "acme", "dave@acme.example"))
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+		assert.Empty(t, res.Cookies())
+
+		_, err := ts.app.db.SAML.GetProviderWithUser(ctx, dave.ID)
+		assert.ErrorIs(t, err, data.ErrRecordNotFound)
+	})
+
+	t.Run("Linked", func(t *testing.T) {
+		res := ts.postACS(t, "partner", ts.login(t, partnerIDP, "partner", "erin@partner.example"))
+		assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+
+		p, err := ts.app.db.SAML.GetProviderWithUser(ctx, erin.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "partner", p.Slug)
+	})
+
+	t.Run("Provisioned", func(t *testing.T) {
+		// Users created by the provider keep signing in with it
+		for range 2 {
+			res := ts.postACS(t, "acme", ts.login(t, idp, "acme", "frank@acme.example"))
+			assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+		}
+
+		frank, err := ts.app.db.Users.GetWithEmail(ctx, "frank@acme.example")
+		require.NoError(t, err)
+		p, err := ts.app.db.SAML.GetProviderWithUser(ctx, frank.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", p.Slug)
+	})
+}
+
+func TestSAMLReauthentication(t *testing.T) {
+	idp := newTestIDP(t)
+	ts := newSAMLTestServer(t, map[*saml.IdentityProvider]*data.SAMLProvider{
+		idp: {Slug: "acme", Name: "Acme", Domain: "acme.example"},
+	})
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	c := ts.client()
+	c.Jar = jar
+
+	get := func(t *testing.T, path string) *http.Response {
+		res, err := c.Get(ts.URL + path)
+		require.NoError(t, err)
+		res.Body.Close()
+
+		return res
+	}
+	postACS := func(t *testing.T, form url.Values) *http.Response {
+		res, err := c.PostForm(ts.URL+"/saml/acme/acs", form)
+		require.NoError(t, err)
+		res.Body.Close()
+
+		return res
+	}
+
+	req := ts.authnRequest(t, c, idp, "acme")
+	assert.Nil(t, req.Request.ForceAuthn)
+	res := postACS(t, respond(t, req, "alice@acme.example"))
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	assert.Equal(t, "/", res.Header.Get("Location"))
+
+	res = get(t, "/account/email")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Backdate the sign in past the window for sensitive operations
+	var token string
+	for _, cookie := range jar.Cookies(ts.app.baseURL) {
+		if cookie.Name == ts.app.sessionManager.Cookie.Name {
+			token = cookie.Value
+		}
+	}
+	ctx, err := ts.app.sessionManager.Load(context.Background(), token)
+	require.NoError(t, err)
+	ts.app.sessionManager.Put(ctx, authenticatedAtSessionKey, time.Now().Add(-data.FreshAuthenticationTTL))
+	_, _, err = ts.app.sessionManager.Commit(ctx)
+	require.NoError(t, err)
+
+	res = get(t, "/account/email")
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	require.Equal(t, "/auth/confirm", res.Header.Get("Location"))
+
+	// Users without a password confirm with their identity provider
+	res = get(t, "/auth/confirm")
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	require.Equal(t, "/saml/acme/login", res.Header.Get("Location"))
+
+	req = ts.authnRequest(t, c, idp, "acme")
+	require.NotNil(t, req.Request.ForceAuthn)
+	assert.True(t, *req.Request.ForceAuthn)
+
+	res = postACS(t, respond(t, req, "alice@acme.example"))
+	require.Equal(t, http.StatusSeeOther, res.StatusCode)
+	assert.Equal(t, "/account/email", res.Header.Get("Location"))
+
+	res = get(t, "/account/email")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
@@ -5,9 +5,11 @@ import (
 	"flag"
 	"log"
 	"os"
+	"strings"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/micahco/mono/migrations"
+	_ "modernc.org/sqlite"
 )
 
 func main() {
@@ -28,13 +30,20 @@ func main() {
 		log.Fatal("missing env: DATABASE_URL")
 	}
 
-	db, err := sql.Open("pgx", dsn)
+	// SQLite databases are files named by sqlite:path
+	driver, newMigrator := "pgx", migrations.NewMigrator
+	if path, ok := strings.CutPrefix(dsn, "sqlite:"); ok {
+		driver, newMigrator = "sqlite", migrations.NewSQLiteMigrator
+		dsn = strings.TrimPrefix(path, "//")
+	}
+
+	db, err := sql.Open(driver, dsn)
 	if err != nil {
-		log.Fatalf("pgx: %v", err)
+		log.Fatalf("%s: %v", driver, err)
 	}
 	defer db.Close()
 
-	m, err := migrations.NewMigrator(db)
+	m, err := newMigrator(db)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -1,8 +1,10 @@
 package migrations
 
 import (
+	"context"
 	"database/sql"
 	"embed"
+	"io/fs"
 
 	"github.com/pressly/goose/v3"
 )
@@ -10,28 +12,43 @@ import (
 //go:embed sql/*.sql
 var Files embed.FS
 
+// Migrations of the SQLite backend, which mirror the PostgreSQL schema
+//
+//go:embed sqlite/*.sql
+var SQLiteFiles embed.FS
+
 type Migrator struct {
-	db *sql.DB
+	provider *goose.Provider
 }
 
 func NewMigrator(db *sql.DB) (*Migrator, error) {
-	goose.SetBaseFS(Files)
+	return newMigrator(db, goose.DialectPostgres, Files, "sql")
+}
+
+func NewSQLiteMigrator(db *sql.DB) (*Migrator, error) {
+	return newMigrator(db, goose.DialectSQLite3, SQLiteFiles, "sqlite")
+}
 
-	if err := goose.SetDialect("postgres"); err != nil {
+func newMigrator(db *sql.DB, dialect goose.Dialect, files embed.FS, dir string) (*Migrator, error) {
+	fsys, err := fs.Sub(files, dir)
+	if err != nil {
 		return nil, err
 	}
 
-	if _, err := goose.EnsureDBVersion(db); err != nil {
+	provider, err := goose.NewProvider(dialect, db, fsys)
+	if err != nil {
 		return nil, err
 	}
 
-	return &Migrator{db}, nil
+	return &Migrator{provider}, nil
 }
 
 func (m *Migrator) Up() error {
-	return goose.Up(m.db, "sql")
+	_, err := m.provider.Up(context.Background())
+	return err
 }
 
 func (m *Migrator) Reset() error {
-	return goose.Reset(m.db, "sql")
+	_, err := m.provider.DownTo(context.Background(), 0)
+	return err
 }
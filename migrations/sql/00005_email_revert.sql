@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE user_
+    ADD COLUMN locked_ BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE verification_token_
+    ADD COLUMN user_id_ uuid REFERENCES user_ ON DELETE CASCADE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE verification_token_
+    DROP COLUMN IF EXISTS user_id_;
+
+ALTER TABLE user_
+    DROP COLUMN IF EXISTS locked_;
+-- +goose StatementEnd
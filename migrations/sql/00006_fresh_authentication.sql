@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE authentication_token_
+    ADD COLUMN authenticated_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE authentication_token_
+    DROP COLUMN IF EXISTS authenticated_at_;
+-- +goose StatementEnd
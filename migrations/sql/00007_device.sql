@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS device_ (
+    user_id_ uuid NOT NULL REFERENCES user_ ON DELETE CASCADE,
+    ip_ TEXT NOT NULL,
+    user_agent_ TEXT NOT NULL,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id_, ip_, user_agent_)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS device_;
+-- +goose StatementEnd
@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE user_
+    ADD COLUMN active_ BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN display_name_ TEXT NOT NULL DEFAULT '',
+    ADD COLUMN external_id_ TEXT UNIQUE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE user_
+    DROP COLUMN IF EXISTS external_id_,
+    DROP COLUMN IF EXISTS display_name_,
+    DROP COLUMN IF EXISTS active_;
+-- +goose StatementEnd
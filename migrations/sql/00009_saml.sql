@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS saml_provider_ (
+    id_ uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    slug_ TEXT UNIQUE NOT NULL,
+    name_ TEXT NOT NULL,
+    domain_ CITEXT UNIQUE NOT NULL,
+    metadata_ TEXT NOT NULL,
+    allow_idp_initiated_ BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS saml_request_ (
+    hash_ BYTEA PRIMARY KEY,
+    expiry_ TIMESTAMPTZ NOT NULL,
+    provider_id_ uuid NOT NULL REFERENCES saml_provider_ ON DELETE CASCADE,
+    request_id_ TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS saml_assertion_ (
+    id_ TEXT PRIMARY KEY,
+    expiry_ TIMESTAMPTZ NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS saml_assertion_;
+DROP TABLE IF EXISTS saml_request_;
+DROP TABLE IF EXISTS saml_provider_;
+-- +goose StatementEnd
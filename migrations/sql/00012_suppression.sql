@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS suppression_ (
+    email_ CITEXT PRIMARY KEY,
+    kind_ TEXT NOT NULL CHECK (kind_ IN ('hard', 'soft', 'complaint')),
+    diagnostic_ TEXT NOT NULL DEFAULT '',
+    soft_bounces_ INTEGER NOT NULL DEFAULT 0,
+    suppressed_ BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS suppression_;
+-- +goose StatementEnd
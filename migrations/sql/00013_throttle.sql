@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS throttle_ (
+    id_ BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    email_ CITEXT NOT NULL,
+    ip_ TEXT NOT NULL,
+    created_at_ TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS throttle_created_at_idx ON throttle_ (created_at_);
+CREATE INDEX IF NOT EXISTS throttle_email_idx ON throttle_ (email_, created_at_);
+CREATE INDEX IF NOT EXISTS throttle_ip_idx ON throttle_ (ip_, created_at_);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS throttle_;
+-- +goose StatementEnd
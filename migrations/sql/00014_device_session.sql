@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS device_session_ (
+    hash_ BYTEA PRIMARY KEY,
+    session_ TEXT NOT NULL,
+    expiry_ TIMESTAMPTZ NOT NULL,
+    user_id_ uuid NOT NULL REFERENCES user_ ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS device_session_expiry_idx ON device_session_ (expiry_);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS device_session_;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE saml_provider_
+    ADD COLUMN link_by_email_ BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS saml_user_ (
+    user_id_ uuid PRIMARY KEY REFERENCES user_ ON DELETE CASCADE,
+    provider_id_ uuid NOT NULL REFERENCES saml_provider_ ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS saml_user_;
+
+ALTER TABLE saml_provider_
+    DROP COLUMN IF EXISTS link_by_email_;
+-- +goose StatementEnd
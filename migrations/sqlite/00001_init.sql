@@ -0,0 +1,82 @@
+-- +goose Up
+-- +goose StatementBegin
+-- UUIDs are stored as text and timestamps as text in UTC.
+-- NOCASE stands in for CITEXT.
+CREATE TABLE IF NOT EXISTS user_ (
+    id_ TEXT PRIMARY KEY,
+    version_ INTEGER NOT NULL DEFAULT 1,
+    created_at_ DATETIME NOT NULL,
+    email_ TEXT COLLATE NOCASE UNIQUE NOT NULL,
+    password_hash_ BLOB NOT NULL,
+    locked_ BOOLEAN NOT NULL DEFAULT FALSE,
+    active_ BOOLEAN NOT NULL DEFAULT TRUE,
+    display_name_ TEXT NOT NULL DEFAULT '',
+    external_id_ TEXT UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS verification_token_ (
+    hash_ BLOB PRIMARY KEY,
+    expiry_ DATETIME NOT NULL,
+    scope_ TEXT NOT NULL,
+    email_ TEXT COLLATE NOCASE NOT NULL,
+    user_id_ TEXT REFERENCES user_ ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS authentication_token_ (
+    hash_ BLOB PRIMARY KEY,
+    expiry_ DATETIME NOT NULL,
+    user_id_ TEXT NOT NULL REFERENCES user_ ON DELETE CASCADE,
+    authenticated_at_ DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token TEXT PRIMARY KEY,
+    data BLOB NOT NULL,
+    expiry REAL NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON sessions (expiry);
+
+CREATE TABLE IF NOT EXISTS device_ (
+    user_id_ TEXT NOT NULL REFERENCES user_ ON DELETE CASCADE,
+    ip_ TEXT NOT NULL,
+    user_agent_ TEXT NOT NULL,
+    created_at_ DATETIME NOT NULL,
+    last_seen_at_ DATETIME NOT NULL,
+    PRIMARY KEY (user_id_, ip_, user_agent_)
+);
+
+CREATE TABLE IF NOT EXISTS saml_provider_ (
+    id_ TEXT PRIMARY KEY,
+    created_at_ DATETIME NOT NULL,
+    slug_ TEXT UNIQUE NOT NULL,
+    name_ TEXT NOT NULL,
+    domain_ TEXT COLLATE NOCASE UNIQUE NOT NULL,
+    metadata_ TEXT NOT NULL,
+    allow_idp_initiated_ BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE IF NOT EXISTS saml_request_ (
+    hash_ BLOB PRIMARY KEY,
+    expiry_ DATETIME NOT NULL,
+    provider_id_ TEXT NOT NULL REFERENCES saml_provider_ ON DELETE CASCADE,
+    request_id_ TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS saml_assertion_ (
+    id_ TEXT PRIMARY KEY,
+    expiry_ DATETIME NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS saml_assertion_;
+DROP TABLE IF EXISTS saml_request_;
+DROP TABLE IF EXISTS saml_provider_;
+DROP TABLE IF EXISTS device_;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS authentication_token_;
+DROP TABLE IF EXISTS verification_token_;
+DROP TABLE IF EXISTS user_;
+-- +goose StatementEnd
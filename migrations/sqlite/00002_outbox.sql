@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS outbox_ (
+    id_ TEXT PRIMARY KEY,
+    created_at_ DATETIME NOT NULL,
+    sender_ TEXT NOT NULL,
+    recipient_ TEXT NOT NULL,
+    subject_ TEXT NOT NULL,
+    body_ TEXT NOT NULL,
+    status_ TEXT NOT NULL DEFAULT 'pending' CHECK (status_ IN ('pending', 'dead')),
+    attempts_ INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at_ DATETIME NOT NULL,
+    last_error_ TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS outbox_next_attempt_at_idx ON outbox_ (next_attempt_at_) WHERE status_ = 'pending';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox_;
+-- +goose StatementEnd
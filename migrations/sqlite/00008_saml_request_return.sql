@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE saml_request_
+    ADD COLUMN return_to_ TEXT NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE saml_request_
+    DROP COLUMN return_to_;
+-- +goose StatementEnd
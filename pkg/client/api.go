@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+type Health struct {
+	Status     string            `json:"status"`
+	SystemInfo map[string]string `json:"system_info"`
+}
+
+type User struct {
+	CreatedAt   time.Time `json:"created_at"`
+	Email       string    `json:"email"`
+	Locked      bool      `json:"locked"`
+	Active      bool      `json:"active"`
+	DisplayName string    `json:"display_name"`
+	Locale      string    `json:"locale"`
+}
+
+type AuthenticationToken struct {
+	Expiry          time.Time `json:"expiry"`
+	AuthenticatedAt time.Time `json:"authenticated_at"`
+}
+
+// User to create with the token of a registration email
+type NewUser struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+	// Language of the user, or that of the request if empty
+	Locale string `json:"locale,omitempty"`
+}
+
+// Fields of the user to change. Nil fields are unchanged. Changing the
+// email needs the token of an email change email.
+type UserUpdate struct {
+	Email    *string `json:"email,omitempty"`
+	Password *string `json:"password,omitempty"`
+	Token    *string `json:"token,omitempty"`
+	Locale   *string `json:"locale,omitempty"`
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+type userResponse struct {
+	User *User `json:"user"`
+}
+
+type emailInput struct {
+	Email string `json:"email"`
+}
+
+func (c *Client) Healthcheck(ctx context.Context) (*Health, error) {
+	var health Health
+	err := c.do(ctx, request{method: http.MethodGet, path: "/v1/healthcheck", idempotent: true}, &health)
+	if err != nil {
+		return nil, err
+	}
+
+	return &health, nil
+}
+
+// OpenAPI document of the API
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var spec json.RawMessage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/v1/openapi.json", idempotent: true}, &spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return spec, nil
+}
+
+// Exchange the credentials for an authentication token, which the client
+// uses from then on
+func (c *Client) Login(ctx context.Context, email, password string) (string, error) {
+	input := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{email, password}
+
+	var res struct {
+		AuthenticationToken string `json:"authentication_token"`
+	}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/v1/tokens/authentication", body: input}, &res)
+	if err != nil {
+		return "", err
+	}
+
+	c.SetToken(res.AuthenticationToken)
+
+	return res.AuthenticationToken, nil
+}
+
+// Confirm the password of the user to allow sensitive changes with the
+// token of the client
+func (c *Client) Reauthenticate(ctx context.Context, password string) (*AuthenticationToken, error) {
+	input := struct {
+		Password string `json:"password"`
+	}{password}
+
+	var res struct {
+		AuthenticationToken *AuthenticationToken `json:"authentication_token"`
+	}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/v1/tokens/authentication", body: input, auth: true, idempotent: true}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.AuthenticationToken, nil
+}
+
+// Sign out the session of a new device email
+func (c *Client) RevokeSession(ctx context.Context, session string) (string, error) {
+	input := struct {
+		Session string `json:"session"`
+	}{session}
+
+	return c.message(ctx, request{method: http.MethodPost, path: "/v1/tokens/authentication/revoke", body: input})
+}
+
+// Mail a registration token to the email address
+func (c *Client) RequestRegistration(ctx context.Context, email string) (string, error) {
+	return c.message(ctx, request{method: http.MethodPost, path: "/v1/tokens/verification/registration", body: emailInput{email}})
+}
+
+// Mail a password reset token to the email address
+func (c *Client) RequestPasswordReset(ctx context.Context, email string) (string, error) {
+	return c.message(ctx, request{method: http.MethodPost, path: "/v1/tokens/verification/password-reset", body: emailInput{email}})
+}
+
+// Mail a token to the email address that changes the email of the user
+// to it. The token must have been confirmed recently with Reauthenticate.
+func (c *Client) RequestEmailChange(ctx context.Context, email string) (string, error) {
+	return c.message(ctx, request{method: http.MethodPost, path: "/v1/tokens/verification/email-change", body: emailInput{email}, auth: true})
+}
+
+func (c *Client) Register(ctx context.Context, user NewUser) (*User, error) {
+	return c.user(ctx, request{method: http.MethodPost, path: "/v1/users", body: user})
+}
+
+// Reset the password with the token of a password reset email
+func (c *Client) ResetPassword(ctx context.Context, password, token string) (string, error) {
+	input := struct {
+		Password string `json:"password"`
+		Token    string `json:"token"`
+	}{password, token}
+
+	return c.message(ctx, request{method: http.MethodPut, path: "/v1/users/password", body: input})
+}
+
+// Restore the email address with the token of an email changed email
+func (c *Client) RevertEmail(ctx context.Context, token string) (string, error) {
+	input := struct {
+		Token string `json:"token"`
+	}{token}
+
+	return c.message(ctx, request{method: http.MethodPut, path: "/v1/users/email/revert", body: input})
+}
+
+// Authenticated user
+func (c *Client) Me(ctx context.Context) (*User, error) {
+	return c.user(ctx, request{method: http.MethodGet, path: "/v1/users/me", auth: true, idempotent: true})
+}
+
+// Update the authenticated user. The token must have been confirmed
+// recently with Reauthenticate.
+func (c *Client) UpdateMe(ctx context.Context, update UserUpdate) (*User, error) {
+	// Tokens can only be used once
+	idempotent := update.Token == nil
+
+	return c.user(ctx, request{method: http.MethodPut, path: "/v1/users/me", body: update, auth: true, idempotent: idempotent})
+}
+
+func (c *Client) message(ctx context.Context, req request) (string, error) {
+	var res messageResponse
+	err := c.do(ctx, req, &res)
+	if err != nil {
+		return "", err
+	}
+
+	return res.Message, nil
+}
+
+func (c *Client) user(ctx context.Context, req request) (*User, error) {
+	var res userResponse
+	err := c.do(ctx, req, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.User, nil
+}
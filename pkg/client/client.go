@@ -0,0 +1,294 @@
+// Package client is a typed client of the mono API. It keeps the
+// authentication token of the user, signs in again when the token is
+// rejected if it was given credentials, and retries idempotent requests
+// that fail for reasons that may pass.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRetries = 3
+	DefaultBackoff = 250 * time.Millisecond
+	// Longest wait between attempts, including those the API asks for
+	maxBackoff = 30 * time.Second
+)
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	userAgent  string
+	retries    int
+	backoff    time.Duration
+
+	mu    sync.Mutex
+	token string
+	// Credentials to sign in with when the token is missing or rejected
+	email, password string
+}
+
+type Option func(*Client)
+
+// Send requests with the HTTP client instead of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// Authenticate requests with the token
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// Sign in with the credentials before the first authenticated request and
+// whenever the token is rejected
+func WithCredentials(email, password string) Option {
+	return func(c *Client) {
+		c.email = email
+		c.password = password
+	}
+}
+
+// Retry idempotent requests up to retries times, waiting backoff before
+// the first retry and twice as long before each one after it
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// Client of the API at the base URL, e.g. "https://api.example.com"
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("client: base URL must be http or https: %q", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  "mono-client",
+		retries:    DefaultRetries,
+		backoff:    DefaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Authentication token of the client, if it has one
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.token
+}
+
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = token
+}
+
+type request struct {
+	method string
+	path   string
+	body   any
+	// Whether the request needs an authentication token
+	auth bool
+	// Whether sending the request again has no other effect than the
+	// first time, so that it can be retried
+	idempotent bool
+}
+
+// Send the request and decode the response into dst, if it isn't nil
+func (c *Client) do(ctx context.Context, req request, dst any) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		body, err = json.Marshal(req.body)
+		if err != nil {
+			return err
+		}
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		var token string
+		if req.auth {
+			var err error
+			token, err = c.authenticationToken(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		res, err := c.send(ctx, req, body, token)
+		if err != nil {
+			if req.idempotent && attempt < c.retries && ctx.Err() == nil {
+				if err := c.wait(ctx, attempt, 0); err != nil {
+					return err
+				}
+				continue
+			}
+			return err
+		}
+
+		if res.StatusCode < 300 {
+			defer res.Body.Close()
+
+			if dst == nil {
+				return nil
+			}
+			return json.NewDecoder(res.Body).Decode(dst)
+		}
+
+		apiErr := parseError(res)
+		res.Body.Close()
+
+		switch {
+		// The token expired or was revoked, so sign in again, once
+		case req.auth && res.StatusCode == http.StatusUnauthorized && !refreshed && c.canSignIn():
+			refreshed = true
+			c.clearToken(token)
+			attempt--
+			continue
+
+		case req.idempotent && retryable(res.StatusCode) && attempt < c.retries:
+			if err := c.wait(ctx, attempt, retryAfter(res)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		return apiErr
+	}
+}
+
+func (c *Client) send(ctx context.Context, req request, body []byte, token string) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL.String()+req.path, r)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Accept", "application/json, application/problem+json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return c.httpClient.Do(httpReq)
+}
+
+// Token of the client, signing in first if it has none
+func (c *Client) authenticationToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token, email, password := c.token, c.email, c.password
+	c.mu.Unlock()
+
+	if token != "" || email == "" {
+		return token, nil
+	}
+
+	return c.Login(ctx, email, password)
+}
+
+func (c *Client) canSignIn() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.email != ""
+}
+
+// Forget the token, unless it was replaced since it was sent
+func (c *Client) clearToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == token {
+		c.token = ""
+	}
+}
+
+// Statuses of failures that may pass
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Wait that the response asks for, in seconds
+func retryAfter(res *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// Wait before the retry of the attempt, with jitter, or for as long as
+// the API asked for
+func (c *Client) wait(ctx context.Context, attempt int, after time.Duration) error {
+	d := after
+	if d == 0 {
+		d = c.backoff << attempt
+		if d > 0 {
+			d = d/2 + rand.N(d/2+1)
+		}
+	}
+	d = min(d, maxBackoff)
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Types of problems that the API responds with
+const (
+	TypeValidation   = "urn:mono:problem:validation"
+	TypeInvalidBody  = "urn:mono:problem:invalid-body"
+	TypeUnauthorized = "urn:mono:problem:unauthorized"
+	TypeExpiredToken = "urn:mono:problem:expired-token"
+	TypeForbidden    = "urn:mono:problem:forbidden"
+	TypeNotFound     = "urn:mono:problem:not-found"
+	TypeConflict     = "urn:mono:problem:conflict"
+	TypeRateLimit    = "urn:mono:problem:rate-limit"
+)
+
+// Errors that an *Error matches with errors.Is, by its type
+var (
+	ErrValidation   = errors.New("client: invalid request")
+	ErrInvalidBody  = errors.New("client: invalid request body")
+	ErrUnauthorized = errors.New("client: unauthorized")
+	ErrExpiredToken = errors.New("client: expired token")
+	ErrForbidden    = errors.New("client: forbidden")
+	ErrNotFound     = errors.New("client: not found")
+	ErrConflict     = errors.New("client: conflict")
+	ErrRateLimited  = errors.New("client: rate limited")
+)
+
+var typeErrors = map[string]error{
+	TypeValidation:   ErrValidation,
+	TypeInvalidBody:  ErrInvalidBody,
+	TypeUnauthorized: ErrUnauthorized,
+	TypeExpiredToken: ErrExpiredToken,
+	TypeForbidden:    ErrForbidden,
+	TypeNotFound:     ErrNotFound,
+	TypeConflict:     ErrConflict,
+	TypeRateLimit:    ErrRateLimited,
+}
+
+// Types of the legacy error bodies of servers that don't send problem
+// details, which only have a status
+var legacyTypes = map[int]string{
+	http.StatusBadRequest:          TypeInvalidBody,
+	http.StatusUnauthorized:        TypeUnauthorized,
+	http.StatusForbidden:           TypeForbidden,
+	http.StatusNotFound:            TypeNotFound,
+	http.StatusConflict:            TypeConflict,
+	http.StatusUnprocessableEntity: TypeValidation,
+	http.StatusTooManyRequests:     TypeRateLimit,
+}
+
+// Error response of the API
+type Error struct {
+	StatusCode int
+	// Type of the problem, or "about:blank" if the status describes it
+	Type      string
+	Title     string
+	Detail    string
+	Instance  string
+	RequestID string
+	// Errors of each field of the request
+	Fields map[string][]FieldError
+}
+
+// Why a field of the request is invalid
+type FieldError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Params  map[string]any `json:"params,omitempty"`
+}
+
+func (e *Error) Error() string {
+	msg := e.Detail
+	if msg == "" {
+		msg = e.Title
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+
+	if len(e.Fields) > 0 {
+		fields := make([]string, 0, len(e.Fields))
+		for field := range e.Fields {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		msg += " (" + strings.Join(fields, ", ") + ")"
+	}
+
+	return fmt.Sprintf("client: %d %s", e.StatusCode, msg)
+}
+
+func (e *Error) Is(target error) bool {
+	return typeErrors[e.Type] == target
+}
+
+// Decode the problem details of the response, or its legacy
+// {"error": ...} body
+func parseError(res *http.Response) *Error {
+	e := &Error{
+		StatusCode: res.StatusCode,
+		Type:       "about:blank",
+		RequestID:  res.Header.Get("X-Request-Id"),
+	}
+
+	b, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return e
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/problem+json":
+		var p struct {
+			Type      string                  `json:"type"`
+			Title     string                  `json:"title"`
+			Detail    string                  `json:"detail"`
+			Instance  string                  `json:"instance"`
+			RequestID string                  `json:"request_id"`
+			Fields    map[string][]FieldError `json:"fields"`
+		}
+		if json.Unmarshal(b, &p) != nil {
+			return e
+		}
+
+		if p.Type != "" {
+			e.Type = p.Type
+		}
+		e.Title = p.Title
+		e.Detail = p.Detail
+		e.Instance = p.Instance
+		e.Fields = p.Fields
+		if p.RequestID != "" {
+			e.RequestID = p.RequestID
+		}
+	case "application/json":
+		var legacy struct {
+			Error     string                  `json:"error"`
+			RequestID string                  `json:"request_id"`
+			Fields    map[string][]FieldError `json:"fields"`
+		}
+		if json.Unmarshal(b, &legacy) != nil {
+			return e
+		}
+
+		if typ, ok := legacyTypes[res.StatusCode]; ok {
+			e.Type = typ
+		}
+		e.Title = http.StatusText(res.StatusCode)
+		e.Detail = legacy.Error
+		e.Fields = legacy.Fields
+		if legacy.RequestID != "" {
+			e.RequestID = legacy.RequestID
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseError(t *testing.T) {
+	newResponse := func(status int, contentType, body string) *http.Response {
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {contentType}, "X-Request-Id": {"header-id"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	}
+
+	t.Run("problem", func(t *testing.T) {
+		err := parseError(newResponse(http.StatusUnauthorized, "application/problem+json", `{
+			"type": "urn:mono:problem:expired-token",
+			"title": "Expired token",
+			"status": 401,
+			"detail": "expired token",
+			"instance": "/v1/users",
+			"request_id": "body-id"
+		}`))
+
+		assert.ErrorIs(t, err, ErrExpiredToken)
+		assert.NotErrorIs(t, err, ErrUnauthorized)
+		assert.Equal(t, "body-id", err.RequestID)
+		assert.Equal(t, "/v1/users", err.Instance)
+		assert.Equal(t, "client: 401 expired token", err.Error())
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		err := parseError(newResponse(http.StatusUnprocessableEntity, "application/json", `{
+			"error": "email: must be a valid email address.",
+			"fields": {"email": [{"code": "invalid_email", "message": "must be a valid email address"}]}
+		}`))
+
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Equal(t, "header-id", err.RequestID)
+		assert.Equal(t, "invalid_email", err.Fields["email"][0].Code)
+		assert.Equal(t, "client: 422 email: must be a valid email address. (email)", err.Error())
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		err := parseError(newResponse(http.StatusBadGateway, "text/html", `<html></html>`))
+
+		assert.Equal(t, "about:blank", err.Type)
+		assert.Equal(t, "client: 502 Bad Gateway", err.Error())
+	})
+}
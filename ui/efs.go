@@ -6,3 +6,6 @@ import (
 
 //go:embed "static"
 var StaticFiles embed.FS
+
+//go:embed "docs"
+var DocsFiles embed.FS